@@ -8,14 +8,15 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 
 	"github.com/commonsyllabi/explorer/api/auth"
 	"github.com/commonsyllabi/explorer/api/config"
 	"github.com/commonsyllabi/explorer/api/handlers"
+	"github.com/commonsyllabi/explorer/api/jobs"
 	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/throttle"
 )
 
 var conf config.Config
@@ -29,7 +30,12 @@ func StartServer(port string, c config.Config) {
 		panic(err)
 	}
 
-	router := SetupRouter()
+	router := SetupRouter(auth.JWTAuthenticator{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	jobs.Start(ctx)
+
 	s := &http.Server{
 		Addr:         ":" + port,
 		Handler:      router,
@@ -55,9 +61,34 @@ func StartServer(port string, c config.Config) {
 	s.Shutdown(context.Background())
 }
 
-// SetupRouter registers all middleware, templates, logging route groups and settings
-func SetupRouter() *echo.Echo {
+// SetupRouter registers all middleware, templates, logging route groups and settings.
+// The authenticator resolves the identity of the user of each request, before any policy is enforced.
+func SetupRouter(authenticator auth.Authenticator) *echo.Echo {
+	if err := auth.Init(conf.JWT); err != nil {
+		panic(err)
+	}
+
+	if err := auth.InitOIDC(conf.OIDC); err != nil {
+		panic(err)
+	}
+
+	if conf.RateLimit.Enabled {
+		store, err := throttle.NewStore(conf.RateLimit.Store)
+		if err != nil {
+			panic(err)
+		}
+		auth.InitThrottle(store)
+	} else {
+		auth.InitThrottle(nil)
+	}
+
+	extractor, err := throttle.IPExtractor(conf.RateLimit.TrustedProxies)
+	if err != nil {
+		panic(err)
+	}
+
 	r := echo.New()
+	r.IPExtractor = extractor
 
 	r.Use(middleware.CORS())
 	r.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
@@ -65,7 +96,7 @@ func SetupRouter() *echo.Echo {
 	}))
 	r.Use(middleware.Recover())
 	r.Use(middleware.BodyLimit("16M"))
-	r.Use(injectConfig)
+	r.Use(injectConfig(authenticator))
 
 	if os.Getenv("API_MODE") != "release" {
 		r.Static("/static", conf.UploadsDir)
@@ -73,72 +104,149 @@ func SetupRouter() *echo.Echo {
 
 	r.GET("/ping", handlePing)
 
-	r.POST("/login", auth.Login)
-	r.GET("/admin", auth.Admin)
+	r.POST("/login", auth.Login, auth.Throttle("login"), auth.Authorize("session.create"))
 
 	a := r.Group("/auth")
 	{
-		a.POST("/confirm", auth.Confirm)
-		a.POST("/request-recover", auth.RequestRecover)
-		a.POST("/check-recover", auth.Recover)
+		a.POST("/confirm", auth.Confirm, auth.Authorize("account.recover"))
+		a.POST("/request-recover", auth.RequestRecover, auth.Throttle("recover"), auth.Authorize("account.recover"))
+		a.POST("/check-recover", auth.Recover, auth.Authorize("account.recover"))
+
+		a.POST("/refresh", auth.Refresh, auth.Authorize("session.create"))
+		a.POST("/logout", auth.Logout, auth.Authorize("session.create"))
+		a.POST("/logout-all", auth.LogoutAll, auth.Authorize("session.revoke"))
+
+		a.GET("/oidc/:provider/login", auth.OIDCLogin, auth.Authorize("session.create"))
+		a.GET("/oidc/:provider/callback", auth.OIDCCallback, auth.Authorize("session.create"))
+
+		a.POST("/2fa/verify", auth.VerifyTwoFactor, auth.Throttle("login"), auth.Authorize("session.create"))
+		a.POST("/2fa/enroll", auth.EnrollTwoFactor, auth.Authorize("two_factor.enroll"))
+		a.POST("/2fa/activate", auth.ActivateTwoFactor, auth.Authorize("two_factor.enroll"))
+		a.POST("/2fa/disable", auth.DisableTwoFactor, auth.Authorize("two_factor.disable"))
+
+		a.GET("/tokens", auth.GetAPITokens, auth.Authorize("api_token.list"))
+		a.POST("/tokens", auth.CreateAPIToken, auth.Authorize("api_token.create"))
+		a.DELETE("/tokens/:id", auth.RevokeAPIToken, auth.Authorize("api_token.revoke"))
+	}
+
+	// -- moderators can unlist content, admins can also manage accounts
+	admin := r.Group("/admin")
+	{
+		admin.GET("", handlers.GetAdminOverview, auth.Authorize("admin.overview"))
+
+		admin.GET("/users", handlers.GetAllUsers, auth.Authorize("user.list"))
+		admin.PATCH("/users/:id", handlers.AdminUpdateUser, auth.Authorize("user.manage"))
+		admin.DELETE("/users/:id", handlers.AdminDeleteUser, auth.Authorize("user.manage"))
+
+		admin.GET("/syllabi", handlers.GetAdminSyllabi, auth.Authorize("admin.overview"))
+		admin.GET("/collections", handlers.GetAdminCollections, auth.Authorize("admin.overview"))
+		admin.POST("/syllabi/:id/unlist", handlers.UnlistSyllabus, auth.Authorize("syllabus.unlist"))
+		admin.POST("/collections/:id/unlist", handlers.UnlistCollection, auth.Authorize("collection.unlist"))
 	}
 
 	syllabi := r.Group("/syllabi")
 	{
-		syllabi.GET("/", handlers.GetSyllabi)
-		syllabi.GET("/:id", handlers.GetSyllabus)
+		syllabi.GET("/", handlers.GetSyllabi, auth.Authorize("syllabus.read"))
+		syllabi.GET("/:id", handlers.GetSyllabus, auth.Authorize("syllabus.read"))
+		syllabi.GET("/:id/similar", handlers.GetSimilarSyllabi, auth.Authorize("syllabus.read"))
+		syllabi.GET("/:id/derivatives", handlers.GetSyllabusDerivatives, auth.Authorize("syllabus.read"))
+
+		syllabi.POST("/", handlers.CreateSyllabus, auth.Authorize("syllabus.create"))
+		syllabi.POST("/:id/fork", handlers.ForkSyllabus, auth.Authorize("syllabus.fork"))
+		syllabi.PATCH("/:id", handlers.UpdateSyllabus, auth.Authorize("syllabus.update"))
+		syllabi.DELETE("/:id", handlers.DeleteSyllabus, auth.Authorize("syllabus.delete"))
+
+		syllabi.GET("/:id/revisions", handlers.GetSyllabusRevisions, auth.Authorize("syllabus.read"))
+		syllabi.GET("/:id/revisions/diff", handlers.DiffSyllabusRevisions, auth.Authorize("syllabus.read"))
+		syllabi.GET("/:id/revisions/:number", handlers.GetSyllabusRevision, auth.Authorize("syllabus.read"))
+		syllabi.POST("/:id/revisions/:number/restore", handlers.RestoreSyllabusRevision, auth.Authorize("syllabus.update"))
+
+		syllabi.POST("/:id/institutions", handlers.AddSyllabusInstitution, auth.Authorize("syllabus.institutions.update"))
+		syllabi.PATCH("/:id/institutions/:inst_id", handlers.EditSyllabusInstitution, auth.Authorize("syllabus.institutions.update"))
+		syllabi.DELETE("/:id/institutions/:inst_id", handlers.RemoveSyllabusInstitution, auth.Authorize("syllabus.institutions.update"))
 
-		syllabi.POST("/", handlers.CreateSyllabus)
-		syllabi.PATCH("/:id", handlers.UpdateSyllabus)
-		syllabi.DELETE("/:id", handlers.DeleteSyllabus)
+		syllabi.POST("/:id/attachments", handlers.AddSyllabusAttachment, auth.Authorize("syllabus.attachments.update"))
+		syllabi.DELETE("/:id/attachments/:att_id", handlers.RemoveSyllabusAttachment, auth.Authorize("syllabus.attachments.update"))
 
-		syllabi.POST("/:id/institutions", handlers.AddSyllabusInstitution)
-		syllabi.PATCH("/:id/institutions/:inst_id", handlers.EditSyllabusInstitution)
-		syllabi.DELETE("/:id/institutions/:inst_id", handlers.RemoveSyllabusInstitution)
+		syllabi.POST("/:id/collaborators", handlers.AddSyllabusCollaborator, auth.Authorize("syllabus.collaborators.update"))
+		syllabi.DELETE("/:id/collaborators/:user_id", handlers.RemoveSyllabusCollaborator, auth.Authorize("syllabus.collaborators.update"))
 
-		syllabi.POST("/:id/attachments", handlers.AddSyllabusAttachment)
-		syllabi.DELETE("/:id/attachments/:att_id", handlers.RemoveSyllabusAttachment)
+		syllabi.GET("/:id/sessions", handlers.GetSessions, auth.Authorize("syllabus.read"))
+		syllabi.POST("/:id/sessions", handlers.CreateSession, auth.Authorize("syllabus.sessions.update"))
+		syllabi.PATCH("/:id/sessions/:session_id", handlers.UpdateSession, auth.Authorize("syllabus.sessions.update"))
+		syllabi.DELETE("/:id/sessions/:session_id", handlers.DeleteSession, auth.Authorize("syllabus.sessions.update"))
 
-		syllabi.POST("/parse", handlers.ParseSyllabusFile)
+		syllabi.GET("/:id/assessments", handlers.GetAssessments, auth.Authorize("syllabus.read"))
+		syllabi.PUT("/:id/assessments", handlers.UpdateAssessments, auth.Authorize("syllabus.assessments.update"))
+
+		syllabi.POST("/parse", handlers.ParseSyllabusFile, auth.Authorize("syllabus.parse"))
 	}
 
 	users := r.Group("/users")
 	{
-		users.GET("/", handlers.GetAllUsers)
-		users.GET("/:id", handlers.GetUser)
-		users.POST("/", handlers.CreateUser)
+		users.GET("/:id", handlers.GetUser, auth.Authorize("user.read"))
+		users.POST("/", handlers.CreateUser, auth.Throttle("signup"), auth.Authorize("user.create"))
 
-		users.PATCH("/:id", handlers.UpdateUser)
-		users.DELETE("/:id", handlers.DeleteUser)
+		users.PATCH("/:id", handlers.UpdateUser, auth.Authorize("user.update"))
+		users.DELETE("/:id", handlers.DeleteUser, auth.Authorize("user.delete"))
 
-		users.POST("/:id/institutions", handlers.AddUserInstitution)
-		users.PATCH("/:id/institutions/:inst_id", handlers.EditUserInstitution)
-		users.DELETE("/:id/institutions/:inst_id", handlers.RemoveUserInstitution)
+		users.POST("/:id/institutions", handlers.AddUserInstitution, auth.Authorize("user.institutions.update"))
+		users.PATCH("/:id/institutions/:inst_id", handlers.EditUserInstitution, auth.Authorize("user.institutions.update"))
+		users.DELETE("/:id/institutions/:inst_id", handlers.RemoveUserInstitution, auth.Authorize("user.institutions.update"))
 	}
 
 	attachments := r.Group("/attachments")
 	{
-		attachments.GET("/", handlers.GetAllAttachments)
-		attachments.GET("/:id", handlers.GetAttachment)
+		attachments.GET("/", handlers.GetAllAttachments, auth.Authorize("attachment.read"))
+		attachments.GET("/:id", handlers.GetAttachment, auth.Authorize("attachment.read"))
 
-		attachments.POST("/", handlers.CreateAttachment)
-		attachments.PATCH("/:id", handlers.UpdateAttachment)
-		attachments.DELETE("/:id", handlers.DeleteAttachment)
+		attachments.POST("/", handlers.CreateAttachment, auth.Authorize("attachment.create"))
+		attachments.PATCH("/:id", handlers.UpdateAttachment, auth.Authorize("attachment.update"))
+		attachments.DELETE("/:id", handlers.DeleteAttachment, auth.Authorize("attachment.delete"))
 	}
 
 	collections := r.Group("/collections")
 	{
-		collections.GET("/", handlers.GetAllCollections)
-		collections.GET("/:id", handlers.GetCollection)
+		collections.GET("/", handlers.GetAllCollections, auth.Authorize("collection.read"))
+		collections.GET("/:id", handlers.GetCollection, auth.Authorize("collection.read"))
+
+		collections.POST("/", handlers.CreateCollection, auth.Authorize("collection.create"))
+		collections.PATCH("/:id", handlers.UpdateCollection, auth.Authorize("collection.update"))
+		collections.DELETE("/:id", handlers.DeleteCollection, auth.Authorize("collection.delete"))
 
-		collections.POST("/", handlers.CreateCollection)
-		collections.PATCH("/:id", handlers.UpdateCollection)
-		collections.DELETE("/:id", handlers.DeleteCollection)
+		collections.GET("/:id/syllabi", handlers.GetCollectionSyllabi, auth.Authorize("collection.read"))
+		collections.GET("/:id/syllabi/:syll_id", handlers.GetCollectionSyllabus, auth.Authorize("collection.read"))
+		collections.POST("/:id/syllabi", handlers.AddCollectionSyllabus, auth.Authorize("collection.syllabi.update"))
+		collections.DELETE("/:id/syllabi/:syll_id", handlers.RemoveCollectionSyllabus, auth.Authorize("collection.syllabi.update"))
+	}
 
-		collections.GET("/:id/syllabi", handlers.GetCollectionSyllabi)
-		collections.GET("/:id/syllabi/:syll_id", handlers.GetCollectionSyllabus)
-		collections.POST("/:id/syllabi", handlers.AddCollectionSyllabus)
-		collections.DELETE("/:id/syllabi/:syll_id", handlers.RemoveCollectionSyllabus)
+	readings := r.Group("/readings")
+	{
+		readings.GET("/", handlers.GetReadings, auth.Authorize("reading.read"))
+		readings.GET("/:id", handlers.GetReading, auth.Authorize("reading.read"))
+		readings.GET("/:id/syllabi", handlers.GetReadingSyllabi, auth.Authorize("reading.read"))
+	}
+
+	r.GET("/autocomplete/:kind", handlers.GetSuggestions, auth.Authorize("autocomplete.read"))
+
+	r.GET("/assessments/mix", handlers.GetAssessmentMix, auth.Authorize("assessment.read"))
+
+	// -- digests link to unsubscribe, which mail clients may also do with a POST, as a one-click unsubscribe
+	searches := r.Group("/searches")
+	{
+		searches.GET("/", handlers.GetSavedSearches, auth.Authorize("saved_search.list"))
+		searches.POST("/", handlers.CreateSavedSearch, auth.Authorize("saved_search.create"))
+		searches.PATCH("/:id", handlers.UpdateSavedSearch, auth.Authorize("saved_search.update"))
+		searches.DELETE("/:id", handlers.DeleteSavedSearch, auth.Authorize("saved_search.delete"))
+
+		searches.GET("/unsubscribe", handlers.UnsubscribeSavedSearch, auth.Authorize("saved_search.unsubscribe"))
+		searches.POST("/unsubscribe", handlers.UnsubscribeSavedSearch, auth.Authorize("saved_search.unsubscribe"))
+	}
+
+	taxonomy := r.Group("/taxonomy")
+	{
+		taxonomy.GET("/fields", handlers.GetFieldsTaxonomy, auth.Authorize("taxonomy.read"))
+		taxonomy.GET("/licenses", handlers.GetLicenses, auth.Authorize("taxonomy.read"))
 	}
 
 	r.GET("/", handleNotFound)
@@ -147,21 +255,27 @@ func SetupRouter() *echo.Echo {
 	return r
 }
 
-func injectConfig(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		id, err := auth.Authenticate(c)
-		if err != nil {
-			zero.Warn(err.Error())
-			id = uuid.Nil
+func injectConfig(authenticator auth.Authenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			identity, err := authenticator.Authenticate(c)
+			if err != nil {
+				zero.Warn(err.Error())
+				identity = auth.Anonymous
+			}
+			c.Set("user_uuid", identity.UUID)
+			c.Set("user_role", identity.Role)
+			if identity.Scopes != nil {
+				c.Set("user_scopes", identity.Scopes)
+			}
+
+			c.Set("config", conf)
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			return nil
 		}
-		c.Set("user_uuid", id)
-
-		c.Set("config", conf)
-		if err := next(c); err != nil {
-			c.Error(err)
-		}
-
-		return nil
 	}
 }
 
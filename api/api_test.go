@@ -1,11 +1,16 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/commonsyllabi/explorer/api/auth"
 	"github.com/commonsyllabi/explorer/api/config"
 	"github.com/commonsyllabi/explorer/api/models"
 	"github.com/google/uuid"
@@ -13,6 +18,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var router *echo.Echo
@@ -22,6 +28,8 @@ var (
 	collectionID uuid.UUID
 	attachmentID uuid.UUID
 	userID       uuid.UUID
+	otherUserID  uuid.UUID
+	adminID      uuid.UUID
 )
 
 func setup(t *testing.T) func(t *testing.T) {
@@ -30,6 +38,8 @@ func setup(t *testing.T) func(t *testing.T) {
 	collectionID = uuid.MustParse("b9e4c3ed-ac4f-4e44-bb43-5123b7b6d7a7")
 	attachmentID = uuid.MustParse("c55f0baf-12b8-4bdb-b5e6-2280bff8ab21")
 	userID = uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c8a8")
+	otherUserID = uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c8a9")
+	adminID = uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c8a0")
 
 	router = mustSetupRouter()
 	mustInitDB()
@@ -76,8 +86,36 @@ func TestLoadConfig(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestLoadConfigKeepsDefaults(t *testing.T) {
+	path := "test_config.yml"
+	require.Nil(t, os.WriteFile(path, []byte("uploads_dir: /tmp/explorer/test\nrate_limit:\n  store: postgres\n"), 0644))
+	defer os.Remove(path)
+
+	var c config.Config
+	require.Nil(t, c.LoadConf(path))
+	assert.Equal(t, "/tmp/explorer/test", c.UploadsDir)
+	assert.Equal(t, "postgres", c.RateLimit.Store)
+	assert.True(t, c.RateLimit.Enabled)
+	assert.Equal(t, 72*time.Hour, c.JWT.Grace)
+	assert.Equal(t, 15*time.Minute, c.JWT.AccessTTL)
+}
+
+func TestParseRetiredKeys(t *testing.T) {
+	keys, err := config.ParseRetiredKeys("2022-01:old:secret:2022-02-01, 2021-12:older:2022-01-01")
+	require.Nil(t, err)
+	require.Equal(t, 2, len(keys))
+	assert.Equal(t, "2022-01", keys[0].KeyID)
+	assert.Equal(t, "old:secret", keys[0].Secret)
+	assert.Equal(t, 2022, keys[0].RetiredAt.Year())
+	assert.Equal(t, "older", keys[1].Secret)
+
+	for _, raw := range []string{"2022-01", "2022-01:secret", "2022-01::2022-02-01", ":secret:2022-02-01", "2022-01:secret:february"} {
+		_, err := config.ParseRetiredKeys(raw)
+		assert.NotNil(t, err, raw)
+	}
+}
+
 func TestRoutes(t *testing.T) {
-	os.Setenv("API_MODE", "release")
 	t.Run("Test delete collection unauthorized", func(t *testing.T) {
 		path := "/collections/" + collectionID.String()
 		req := httptest.NewRequest(http.MethodDelete, path, nil)
@@ -117,7 +155,145 @@ func TestRoutes(t *testing.T) {
 
 		assert.Equal(t, http.StatusUnauthorized, res.Code)
 	})
-	os.Setenv("API_MODE", "test")
+}
+
+func TestCrossUserAccess(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	unlistedSyllabusID := "46de6a2b-aacb-4c24-b1e1-3495821f8469"
+	otherUnlistedSyllabusID := "46de6a2b-aacb-4c24-b1e1-3495821f8466"
+	unlistedCollectionID := "b9e4666d-ac4f-4e44-bb43-5123b7b6d7a9"
+	otherUnlistedCollectionID := "b9e4c3ed-ac4f-66e6-bb43-5123b7b6d7a7"
+
+	t.Run("Test owner can see unlisted syllabus", func(t *testing.T) {
+		res := mustRequest(http.MethodGet, "/syllabi/"+unlistedSyllabusID, "justyna", nil)
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("Test other user cannot see unlisted syllabus", func(t *testing.T) {
+		res := mustRequest(http.MethodGet, "/syllabi/"+unlistedSyllabusID, "pierre", nil)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+
+		res = mustRequest(http.MethodGet, "/syllabi/"+otherUnlistedSyllabusID, "justyna", nil)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("Test anonymous cannot see unlisted syllabus", func(t *testing.T) {
+		res := mustRequest(http.MethodGet, "/syllabi/"+unlistedSyllabusID, "", nil)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("Test other user does not list unlisted syllabus", func(t *testing.T) {
+		var owned, other struct {
+			Syllabi []models.Syllabus `json:"syllabi"`
+		}
+
+		res := mustRequest(http.MethodGet, "/syllabi/?page_size=100", "justyna", nil)
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &owned))
+		assert.True(t, containsSyllabus(owned.Syllabi, unlistedSyllabusID))
+
+		res = mustRequest(http.MethodGet, "/syllabi/?page_size=100", "pierre", nil)
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &other))
+		assert.False(t, containsSyllabus(other.Syllabi, unlistedSyllabusID))
+	})
+
+	t.Run("Test other user cannot change unlisted syllabus", func(t *testing.T) {
+		form := url.Values{"title": {"Not my syllabus"}}
+		res := mustRequest(http.MethodPatch, "/syllabi/"+unlistedSyllabusID, "pierre", form)
+		assert.Equal(t, http.StatusForbidden, res.Code)
+
+		res = mustRequest(http.MethodPatch, "/syllabi/"+otherUnlistedSyllabusID, "justyna", form)
+		assert.Equal(t, http.StatusForbidden, res.Code)
+
+		res = mustRequest(http.MethodDelete, "/syllabi/"+unlistedSyllabusID, "pierre", nil)
+		assert.Equal(t, http.StatusForbidden, res.Code)
+
+		res = mustRequest(http.MethodPatch, "/syllabi/"+unlistedSyllabusID, "", form)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("Test owner can see unlisted collection", func(t *testing.T) {
+		res := mustRequest(http.MethodGet, "/collections/"+unlistedCollectionID, "justyna", nil)
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("Test other user cannot see unlisted collection", func(t *testing.T) {
+		res := mustRequest(http.MethodGet, "/collections/"+unlistedCollectionID, "pierre", nil)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+
+		res = mustRequest(http.MethodGet, "/collections/"+otherUnlistedCollectionID, "justyna", nil)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+
+		res = mustRequest(http.MethodGet, "/collections/"+unlistedCollectionID, "", nil)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("Test other user does not list unlisted collection", func(t *testing.T) {
+		var colls struct {
+			Collections []models.Collection `json:"collections"`
+		}
+		res := mustRequest(http.MethodGet, "/collections/", "pierre", nil)
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &colls))
+
+		for _, c := range colls.Collections {
+			assert.NotEqual(t, unlistedCollectionID, c.UUID.String())
+		}
+	})
+
+	t.Run("Test other user cannot change unlisted collection", func(t *testing.T) {
+		form := url.Values{"name": {"Not my collection"}}
+		res := mustRequest(http.MethodPatch, "/collections/"+unlistedCollectionID, "pierre", form)
+		assert.Equal(t, http.StatusForbidden, res.Code)
+
+		res = mustRequest(http.MethodPatch, "/collections/"+otherUnlistedCollectionID, "justyna", form)
+		assert.Equal(t, http.StatusForbidden, res.Code)
+
+		res = mustRequest(http.MethodDelete, "/collections/"+unlistedCollectionID, "pierre", nil)
+		assert.Equal(t, http.StatusForbidden, res.Code)
+
+		res = mustRequest(http.MethodPost, "/collections/"+unlistedCollectionID+"/syllabi", "pierre", url.Values{"syllabus_id": {otherUnlistedSyllabusID}})
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	})
+
+	t.Run("Test owner can change unlisted collection", func(t *testing.T) {
+		form := url.Values{"name": {"Still private stuff"}}
+		res := mustRequest(http.MethodPatch, "/collections/"+unlistedCollectionID, "justyna", form)
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("Test admin can delete unlisted content of others", func(t *testing.T) {
+		res := mustRequest(http.MethodDelete, "/collections/"+otherUnlistedCollectionID, "admin", nil)
+		assert.Equal(t, http.StatusOK, res.Code)
+
+		res = mustRequest(http.MethodDelete, "/syllabi/"+otherUnlistedSyllabusID, "admin", nil)
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+}
+
+// mustRequest sends a form to the router as the user identified by token, or anonymously when the token is empty
+func mustRequest(method string, path string, token string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func containsSyllabus(syllabi []models.Syllabus, id string) bool {
+	for _, s := range syllabi {
+		if s.UUID.String() == id {
+			return true
+		}
+	}
+	return false
 }
 
 func mustSetupRouter() *echo.Echo {
@@ -125,7 +301,11 @@ func mustSetupRouter() *echo.Echo {
 	conf.TemplatesDir = "../api/templates"
 	conf.FixturesDir = "../api/models/fixtures"
 
-	router := SetupRouter()
+	router := SetupRouter(auth.StaticAuthenticator{
+		"justyna": {UUID: userID, Role: models.RoleUser},
+		"pierre":  {UUID: otherUserID, Role: models.RoleUser},
+		"admin":   {UUID: adminID, Role: models.RoleAdmin},
+	})
 	return router
 }
 
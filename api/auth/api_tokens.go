@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// authenticateAPIToken resolves the identity of a request made with an API token, limited to the scopes of the token
+func authenticateAPIToken(secret string) (Identity, error) {
+	user, token, err := models.AuthenticateAPIToken(secret)
+	if err != nil {
+		return Anonymous, err
+	}
+
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+
+	return Identity{UUID: user.UUID, Role: role, Scopes: token.Scopes}, nil
+}
+
+// GetAPITokens lists the API tokens of the current user, without their secrets
+func GetAPITokens(c echo.Context) error {
+	user_uuid, _ := c.Get("user_uuid").(uuid.UUID)
+
+	tokens, err := models.GetAPITokens(user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error getting your API tokens.")
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// CreateAPIToken creates a named API token for the current user, with the scopes[] it is allowed and an optional expires_at, in RFC 3339.
+// The token is only ever returned in this response.
+func CreateAPIToken(c echo.Context) error {
+	user_uuid, _ := c.Get("user_uuid").(uuid.UUID)
+
+	form, err := c.FormParams()
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, "Parameters can't be empty")
+	}
+
+	var expires_at *time.Time
+	if raw := strings.TrimSpace(c.FormValue("expires_at")); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			zero.Error(err.Error())
+			return c.String(http.StatusBadRequest, "Not a valid expiry date.")
+		}
+		expires_at = &t
+	}
+
+	secret, token, err := models.CreateAPIToken(user_uuid, c.FormValue("name"), form["scopes[]"], expires_at)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidAPIToken) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusInternalServerError, "There was an error creating your API token.")
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"token":     secret,
+		"api_token": token,
+	})
+}
+
+// RevokeAPIToken deletes an API token of the current user
+func RevokeAPIToken(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, "Not a valid ID.")
+	}
+
+	err = models.RevokeAPIToken(id)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrAPITokenInvalid) {
+			return c.String(http.StatusNotFound, "We could not find the requested API token.")
+		}
+		return c.String(http.StatusInternalServerError, "There was an error revoking your API token.")
+	}
+
+	return c.String(http.StatusOK, "The API token has been revoked.")
+}
@@ -0,0 +1,104 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type apiTokenResponse struct {
+	Token    string          `json:"token"`
+	APIToken models.APIToken `json:"api_token"`
+}
+
+func TestAPITokens(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	syllID := "46de6a2b-aacb-4c24-b1e1-3495821f846a"
+	session := mustLogin(t, "jus@pop.com")
+
+	var readToken, writeToken apiTokenResponse
+
+	t.Run("Testing create API token", func(t *testing.T) {
+		res := postForm("/auth/tokens", session.Token, url.Values{"name": {"catalog sync"}, "scopes[]": {models.ScopeRead}})
+		require.Equal(t, http.StatusCreated, res.Code)
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &readToken))
+
+		assert.True(t, strings.HasPrefix(readToken.Token, models.APITokenPrefix))
+		assert.Equal(t, "catalog sync", readToken.APIToken.Name)
+		assert.Nil(t, readToken.APIToken.ExpiresAt)
+		assert.NotContains(t, res.Body.String(), "hash")
+
+		expiry := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+		res = postForm("/auth/tokens", session.Token, url.Values{"name": {"writer"}, "scopes[]": {models.ScopeRead, models.ScopeWriteSyllabi}, "expires_at": {expiry}})
+		require.Equal(t, http.StatusCreated, res.Code)
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &writeToken))
+		assert.NotNil(t, writeToken.APIToken.ExpiresAt)
+	})
+
+	t.Run("Testing create API token with invalid parameters", func(t *testing.T) {
+		res := postForm("/auth/tokens", session.Token, url.Values{"name": {"bad"}, "scopes[]": {"write:everything"}})
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+
+		res = postForm("/auth/tokens", session.Token, url.Values{"name": {"bad"}})
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+
+		res = postForm("/auth/tokens", session.Token, url.Values{"name": {"bad"}, "scopes[]": {models.ScopeRead}, "expires_at": {"2001-01-01T00:00:00Z"}})
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+
+	t.Run("Testing API token within its scopes", func(t *testing.T) {
+		res := sendForm(http.MethodGet, "/syllabi/"+syllID, readToken.Token, nil)
+		assert.Equal(t, http.StatusOK, res.Code)
+
+		res = patchForm("/syllabi/"+syllID, writeToken.Token, url.Values{"title": {"Synced from the catalog"}})
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("Testing API token outside of its scopes", func(t *testing.T) {
+		res := patchForm("/syllabi/"+syllID, readToken.Token, url.Values{"title": {"Not allowed"}})
+		assert.Equal(t, http.StatusForbidden, res.Code)
+
+		res = postForm("/collections/", writeToken.Token, url.Values{"name": {"Not allowed"}})
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	})
+
+	t.Run("Testing API token can't manage tokens", func(t *testing.T) {
+		res := postForm("/auth/tokens", writeToken.Token, url.Values{"name": {"escalate"}, "scopes[]": {models.ScopeWriteCollections}})
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	})
+
+	t.Run("Testing list API tokens records last use", func(t *testing.T) {
+		res := sendForm(http.MethodGet, "/auth/tokens", session.Token, nil)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var tokens []models.APIToken
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &tokens))
+		require.Len(t, tokens, 2)
+		for _, tok := range tokens {
+			assert.NotNil(t, tok.LastUsedAt, "%s should have been used", tok.Name)
+		}
+	})
+
+	t.Run("Testing revoke API token of another user", func(t *testing.T) {
+		other := mustLogin(t, "pierre.depaz@gmail.com")
+		res := sendForm(http.MethodDelete, "/auth/tokens/"+readToken.APIToken.UUID.String(), other.Token, nil)
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	})
+
+	t.Run("Testing revoke API token", func(t *testing.T) {
+		res := sendForm(http.MethodDelete, "/auth/tokens/"+readToken.APIToken.UUID.String(), session.Token, nil)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		res = sendForm(http.MethodPost, "/syllabi/", readToken.Token, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+}
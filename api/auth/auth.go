@@ -11,6 +11,7 @@ import (
 
 	zero "github.com/commonsyllabi/explorer/api/logger"
 	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/commonsyllabi/explorer/api/throttle"
 	"github.com/commonsyllabi/explorer/mailer"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -21,58 +22,117 @@ import (
 )
 
 type JWTCustomClaims struct {
-	Name  string `json:"name"`
-	UUID  string `json:"uuid"`
-	Email string `json:"email"`
+	Name           string `json:"name"`
+	UUID           string `json:"uuid"`
+	Email          string `json:"email"`
+	Role           string `json:"role"`
+	SessionVersion int    `json:"sv"`
+	Purpose        string `json:"purpose,omitempty"`
 	jwt.StandardClaims
 }
 
-func Authenticate(c echo.Context) (uuid.UUID, error) {
-	if os.Getenv("API_MODE") == "test" {
-		return uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c8a8"), nil
-	}
+// Identity is the authenticated user of a request, along with the role granted to them when their token was issued.
+// Requests made with an API token are limited to its scopes, while sessions have no scopes and are not limited.
+type Identity struct {
+	UUID   uuid.UUID
+	Role   string
+	Scopes []string
+}
 
-	t := c.QueryParam("token")
-	if t != "" {
-		token, err := uuid.Parse(t)
-		if err != nil {
-			return uuid.Nil, err
-		}
+// Anonymous is the identity of requests without credentials
+var Anonymous = Identity{UUID: uuid.Nil, Role: ""}
 
-		if token != uuid.Nil && token.String() == os.Getenv("ADMIN_KEY") {
-			return token, nil
-		}
-	}
+// Authenticator resolves the identity of the user making a request. Requests without credentials are Anonymous,
+// while requests with credentials which cannot be verified return an error.
+type Authenticator interface {
+	Authenticate(c echo.Context) (Identity, error)
+}
 
-	authHeader := c.Request().Header["Authorization"]
-	if len(authHeader) == 0 {
-		return uuid.Nil, nil
+// JWTAuthenticator authenticates requests bearing an access token issued by Login or Refresh, or an API token
+type JWTAuthenticator struct{}
+
+func (JWTAuthenticator) Authenticate(c echo.Context) (Identity, error) {
+	tokenString, found, err := bearerToken(c)
+	if !found || err != nil {
+		return Anonymous, err
 	}
-	raw := c.Request().Header["Authorization"][0]
-	frags := strings.Split(raw, " ")
-	if len(frags) == 1 {
-		return uuid.Nil, errors.New("no token on Authorization header")
+
+	if strings.HasPrefix(tokenString, models.APITokenPrefix) {
+		return authenticateAPIToken(tokenString)
 	}
 
-	tokenString := frags[1]
-	token, err := jwt.ParseWithClaims(tokenString, &JWTCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected JWT signing method")
-		}
-		return []byte("cosyl"), nil
-	})
+	kr, err := getKeyring()
+	if err != nil {
+		return Anonymous, err
+	}
 
+	token, err := kr.Parse(tokenString, &JWTCustomClaims{})
 	if err != nil {
-		return uuid.Nil, err
+		return Anonymous, err
 	}
 
 	if !token.Valid {
-		return uuid.Nil, fmt.Errorf("unauthorized user - %v", token)
+		return Anonymous, fmt.Errorf("unauthorized user - %v", token)
 	}
 
 	claims := token.Claims.(*JWTCustomClaims)
+	if claims.Purpose != "" {
+		return Anonymous, fmt.Errorf("a %s token can't be used as an access token", claims.Purpose)
+	}
+
+	id, err := uuid.Parse(claims.UUID)
+	if err != nil {
+		return Anonymous, err
+	}
+
+	// -- tokens issued before a password change, a role change or a revocation carry an outdated session version
+	version, err := models.GetSessionVersion(id)
+	if err != nil {
+		return Anonymous, fmt.Errorf("could not find the session of user %s: %v", id, err)
+	}
+
+	if claims.SessionVersion < version {
+		return Anonymous, fmt.Errorf("revoked token for user %s", id)
+	}
+
+	role := claims.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+
+	return Identity{UUID: id, Role: role}, nil
+}
+
+// StaticAuthenticator resolves bearer tokens from a fixed set of identities, so that tests can act as any user without logging in
+type StaticAuthenticator map[string]Identity
+
+func (s StaticAuthenticator) Authenticate(c echo.Context) (Identity, error) {
+	tokenString, found, err := bearerToken(c)
+	if !found || err != nil {
+		return Anonymous, err
+	}
+
+	identity, found := s[tokenString]
+	if !found {
+		return Anonymous, errors.New("unknown token")
+	}
 
-	return uuid.MustParse(claims.UUID), nil
+	return identity, nil
+}
+
+// bearerToken returns the token of the Authorization header, and whether the request had one at all
+func bearerToken(c echo.Context) (string, bool, error) {
+	authHeader := c.Request().Header["Authorization"]
+	if len(authHeader) == 0 {
+		return "", false, nil
+	}
+
+	frags := strings.Split(authHeader[0], " ")
+	if len(frags) == 1 {
+		return "", true, errors.New("no token on Authorization header")
+	}
+
+	return frags[1], true, nil
 }
 
 func Login(c echo.Context) error {
@@ -82,44 +142,146 @@ func Login(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "Parameters can't be empty")
 	}
 
+	key := loginKey(email.Address)
+	if locked, err := checkLockout(c, key); locked {
+		return err
+	}
+
 	user, err := models.GetUserByEmail(email.Address, uuid.Nil)
-	if err != nil || user.Status == models.UserPending {
+	if err != nil || user.Status != models.UserConfirmed {
 		if err != nil {
 			zero.Error(err.Error())
 		} else {
-			zero.Error("User not found")
+			zero.Errorf("User %s is %s", user.UUID, user.Status)
 		}
+		failLogin(key)
 		return c.String(http.StatusUnauthorized, "Authentication failed")
 	}
 
 	err = bcrypt.CompareHashAndPassword(user.Password, []byte(password))
 	if err != nil {
 		zero.Error(err.Error())
+		failLogin(key)
 		return c.String(http.StatusUnauthorized, "Authentication failed")
 	}
 
+	succeedLogin(key)
+	return completeLogin(c, user)
+}
+
+// issueTokens returns a short-lived access token, along with a refresh token to renew it
+func issueTokens(c echo.Context, user models.User) error {
+	session, err := newSession(user)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "Authentication failed")
+	}
+
+	return c.JSON(http.StatusOK, session)
+}
+
+func newSession(user models.User) (echo.Map, error) {
+	t, expires, err := signAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, _, err := models.CreateRefreshToken(user.UUID, refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return echo.Map{
+		"user":          user,
+		"token":         t,
+		"expires_at":    expires,
+		"refresh_token": refresh,
+	}, nil
+}
+
+func signAccessToken(user models.User) (string, time.Time, error) {
+	expires := time.Now().Add(accessTTL)
 	claims := &JWTCustomClaims{
-		user.Name,
-		user.UUID.String(),
-		user.Email,
-		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 72).Unix(),
+		Name:           user.Name,
+		UUID:           user.UUID.String(),
+		Email:          user.Email,
+		Role:           user.Role,
+		SessionVersion: user.SessionVersion,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expires.Unix(),
+			IssuedAt:  time.Now().Unix(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, err := token.SignedString([]byte("cosyl"))
+	kr, err := getKeyring()
+	if err != nil {
+		return "", expires, err
+	}
+
+	t, err := kr.Sign(claims)
+	return t, expires, err
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token. The exchanged refresh token can't be used again.
+func Refresh(c echo.Context) error {
+	refresh := c.FormValue("refresh_token")
+	if strings.Trim(refresh, " ") == "" {
+		return c.String(http.StatusBadRequest, "Parameters can't be empty")
+	}
+
+	user, next, err := models.RotateRefreshToken(refresh, refreshTTL)
 	if err != nil {
 		zero.Error(err.Error())
-		return c.String(http.StatusInternalServerError, "Authentication failed")
+		if errors.Is(err, models.ErrRefreshTokenInvalid) || errors.Is(err, models.ErrRefreshTokenReused) {
+			return c.String(http.StatusUnauthorized, "Your session has expired, please log in again.")
+		}
+		return c.String(http.StatusInternalServerError, "There was an error refreshing your session.")
+	}
+
+	t, expires, err := signAccessToken(user)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error refreshing your session.")
 	}
 
 	return c.JSON(http.StatusOK, echo.Map{
-		"user":  user,
-		"token": t,
+		"token":         t,
+		"expires_at":    expires,
+		"refresh_token": next,
 	})
 }
 
+// Logout revokes the given refresh token. The current access token remains valid until it expires.
+func Logout(c echo.Context) error {
+	refresh := c.FormValue("refresh_token")
+	if strings.Trim(refresh, " ") == "" {
+		return c.String(http.StatusBadRequest, "Parameters can't be empty")
+	}
+
+	err := models.RevokeRefreshToken(refresh)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrRefreshTokenInvalid) {
+			return c.String(http.StatusNotFound, "The session could not be found.")
+		}
+		return c.String(http.StatusInternalServerError, "There was an error logging out.")
+	}
+
+	return c.String(http.StatusOK, "logged out")
+}
+
+// LogoutAll revokes all refresh and access tokens of the authenticated user, on every device
+func LogoutAll(c echo.Context) error {
+	user_uuid, _ := c.Get("user_uuid").(uuid.UUID)
+	err := models.RevokeSessions(user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error logging out.")
+	}
+
+	return c.String(http.StatusOK, "logged out everywhere")
+}
+
 type Token struct {
 	Token string `json:"token" form:"token"`
 }
@@ -127,18 +289,19 @@ type Token struct {
 func Confirm(c echo.Context) error {
 	t := new(Token)
 	c.Bind(t)
-	token, err := uuid.Parse(t.Token)
-
-	if err != nil {
-		zero.Errorf(err.Error())
+	if strings.Trim(t.Token, " ") == "" {
 		return c.String(http.StatusBadRequest, "The token format is incorrect")
 	}
 
-	var user models.User
-	user, err = models.GetTokenUser(token)
+	user, err := models.ConfirmUser(t.Token)
 	if err != nil {
-		// no regular user found, check deleted tokens
-		user, err = models.GetDeletedTokenUser(token)
+		if !errors.Is(err, models.ErrTokenInvalid) {
+			zero.Errorf(err.Error())
+			return c.String(http.StatusInternalServerError, "There was an error confirming the user account.")
+		}
+
+		// no redeemable token found, check if the account was already confirmed with it
+		user, err = models.GetRedeemedTokenUser(t.Token, models.TokenConfirmation)
 		if err != nil {
 			zero.Errorf(err.Error())
 			return c.String(http.StatusNotFound, "The confirmation token could not be found.")
@@ -147,24 +310,11 @@ func Confirm(c echo.Context) error {
 		if user.Status == models.UserConfirmed {
 			return c.JSON(http.StatusOK, user)
 		} else {
-			zero.Error("the token is deleted and the user not confirmed")
+			zero.Error("the token is used and the user not confirmed")
 			return c.String(http.StatusNotFound, "The confirmation token could not be found.")
 		}
 	}
 
-	user.Status = models.UserConfirmed
-	user, err = models.UpdateUser(user.UUID, user.UUID, &user)
-	if err != nil {
-		zero.Errorf(err.Error())
-		return c.String(http.StatusNotFound, "The user account was not found.")
-	}
-
-	err = models.DeleteToken(token)
-	if err != nil {
-		zero.Errorf(err.Error())
-		return c.String(http.StatusInternalServerError, "There was an internal problem. Please try again later.")
-	}
-
 	return c.JSON(http.StatusOK, user)
 }
 
@@ -175,6 +325,16 @@ func RequestRecover(c echo.Context) error {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
 
+	if limiter != nil {
+		counter, err := limiter.Hit("recover:account:"+strings.ToLower(email.Address), RECOVER_LIMIT.Window)
+		if err != nil {
+			zero.Errorf("error counting recovery requests: %v", err)
+		} else if counter.Count > RECOVER_LIMIT.Requests {
+			zero.Warnf("too many recovery requests for %s", email.Address)
+			return throttle.TooManyRequests(c, counter.ResetAt)
+		}
+	}
+
 	user, err := models.GetUserByEmail(email.Address, uuid.Nil)
 	if err != nil {
 		zero.Errorf("could not find user")
@@ -186,7 +346,7 @@ func RequestRecover(c echo.Context) error {
 		return c.String(http.StatusNotFound, "user has nil uuid")
 	}
 
-	token, err := models.CreateToken(user.UUID)
+	token, _, err := models.CreateToken(user.UUID, models.TokenRecovery)
 	if err != nil {
 		zero.Errorf("could not create token")
 		return c.String(http.StatusNotFound, err.Error())
@@ -204,7 +364,7 @@ func RequestRecover(c echo.Context) error {
 		body := mailer.ConfirmationPayload{
 			Name:  user.Name,
 			Host:  host,
-			Token: token.UUID.String(),
+			Token: token,
 		}
 
 		err := mailer.SendMail(email.Address, "Account recovery", "account_recovery", body)
@@ -217,15 +377,15 @@ func RequestRecover(c echo.Context) error {
 	return c.String(http.StatusOK, "recovery email sent!")
 }
 
-// Recover takes a token and checks if it exists in the token table, and a password to update the associated user password
+// Recover takes a recovery token and checks that it can be redeemed, and a password to update the associated user password
 func Recover(c echo.Context) error {
-	token, err := uuid.Parse(c.QueryParam("token"))
-	if err != nil {
-		zero.Errorf("token param not found %s", err)
+	token := c.QueryParam("token")
+	if strings.Trim(token, " ") == "" {
+		zero.Errorf("token param not found")
 		return c.String(http.StatusNotFound, "token not found")
 	}
 
-	user, err := models.GetTokenUser(token)
+	_, err := models.GetTokenUser(token, models.TokenRecovery)
 	if err != nil {
 		zero.Errorf("user not found %s", err)
 		return c.String(http.StatusNotFound, "user not found")
@@ -251,53 +411,16 @@ func Recover(c echo.Context) error {
 		zero.Errorf("error hashing password: %v", err)
 		return c.JSON(http.StatusInternalServerError, gin.H{"error updating user": err.Error()})
 	}
-	user.Password = hashed
-	updated, err := models.UpdateUser(user.UUID, user.UUID, &user)
-	if err != nil {
-		zero.Errorf("couldn't update password %s", err)
-		return c.String(http.StatusBadRequest, "couldn't update password")
-	}
 
-	err = models.DeleteToken(token)
+	// -- the token is burnt, the password set and the sessions revoked together
+	updated, err := models.SetUserPassword(token, hashed)
 	if err != nil {
-		zero.Errorf(err.Error())
-		return c.String(http.StatusInternalServerError, err.Error())
+		zero.Errorf("couldn't update password %s", err)
+		if errors.Is(err, models.ErrTokenInvalid) {
+			return c.String(http.StatusNotFound, "token not found")
+		}
+		return c.String(http.StatusInternalServerError, "couldn't update password")
 	}
 
 	return c.JSON(http.StatusPartialContent, updated)
 }
-
-func Admin(c echo.Context) error {
-	uuid, err := Authenticate(c)
-	if err != nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
-	params := make(map[string]any, 0)
-	params["page"] = 0
-	params["fields"] = "%"
-	params["keywords"] = "%"
-	params["languages"] = "%"
-	params["levels"] = "%"
-	params["tags"] = "%"
-
-	syll, err := models.GetSyllabi(params, uuid)
-	if err != nil {
-		zero.Error(err.Error())
-		return c.String(http.StatusInternalServerError, err.Error())
-	}
-
-	coll, err := models.GetAllCollections(uuid)
-	if err != nil {
-		zero.Error(err.Error())
-		return c.String(http.StatusInternalServerError, err.Error())
-	}
-
-	users, err := models.GetAllUsers()
-	if err != nil {
-		zero.Error(err.Error())
-		return c.String(http.StatusInternalServerError, err.Error())
-	}
-
-	return c.JSON(http.StatusOK, echo.Map{"syllabi": syll, "collections": coll, "users": users})
-}
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/commonsyllabi/explorer/api"
+	"github.com/commonsyllabi/explorer/api/auth"
 	"github.com/commonsyllabi/explorer/api/config"
 	"github.com/commonsyllabi/explorer/api/models"
 	"github.com/google/uuid"
@@ -67,13 +68,201 @@ func TestAuth(t *testing.T) {
 	})
 
 	t.Run("Testing authorized access", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req := httptest.NewRequest(http.MethodGet, "/admin?page_size=2", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token["token"]))
+
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var overview struct {
+			Syllabi []models.Syllabus          `json:"syllabi"`
+			Meta    map[string]models.PageMeta `json:"meta"`
+		}
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &overview))
+		assert.Len(t, overview.Syllabi, 2)
+		assert.NotEmpty(t, overview.Meta["syllabi"].NextCursor)
+	})
+
+	t.Run("Testing forbidden access", func(t *testing.T) {
+		var session echo.Map
+		data := url.Values{}
+		data.Add("email", "jus@pop.com")
+		data.Add("password", "12345678")
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer([]byte(data.Encode())))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &session))
+
+		req = httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", session["token"]))
+		res = httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	})
+
+	t.Run("Testing admin list users", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token["token"]))
+
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.NotContains(t, res.Body.String(), "password")
+	})
+
+	t.Run("Testing admin update user role", func(t *testing.T) {
+		body := bytes.NewBuffer([]byte(`{"role": "moderator"}`))
+		req := httptest.NewRequest(http.MethodPatch, "/admin/users/e7b74bcd-c864-41ee-b5a7-d3031f76c8a9", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token["token"]))
+
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var user models.User
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &user))
+		assert.Equal(t, models.RoleModerator, user.Role)
+	})
+
+	t.Run("Testing admin update user invalid role", func(t *testing.T) {
+		body := bytes.NewBuffer([]byte(`{"role": "superuser"}`))
+		req := httptest.NewRequest(http.MethodPatch, "/admin/users/e7b74bcd-c864-41ee-b5a7-d3031f76c8a9", body)
+		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token["token"]))
 
 		res := httptest.NewRecorder()
 		router.ServeHTTP(res, req)
 
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+
+	t.Run("Testing unauthenticated admin access", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", "Bearer not-a-token")
+
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	var refreshed echo.Map
+	t.Run("Testing refresh session", func(t *testing.T) {
+		data := url.Values{}
+		data.Add("refresh_token", fmt.Sprintf("%s", token["refresh_token"]))
+		body := bytes.NewBuffer([]byte(data.Encode()))
+
+		req := httptest.NewRequest(http.MethodPost, "/auth/refresh", body)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code)
+
+		err := json.Unmarshal(res.Body.Bytes(), &refreshed)
+		require.Nil(t, err)
+		assert.NotEmpty(t, refreshed["token"])
+		assert.NotEqual(t, token["refresh_token"], refreshed["refresh_token"])
+	})
+
+	t.Run("Testing refresh session with used token", func(t *testing.T) {
+		data := url.Values{}
+		data.Add("refresh_token", fmt.Sprintf("%s", token["refresh_token"]))
+		body := bytes.NewBuffer([]byte(data.Encode()))
+
+		req := httptest.NewRequest(http.MethodPost, "/auth/refresh", body)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("Testing refresh session after reuse", func(t *testing.T) {
+		data := url.Values{}
+		data.Add("refresh_token", fmt.Sprintf("%s", refreshed["refresh_token"]))
+		body := bytes.NewBuffer([]byte(data.Encode()))
+
+		req := httptest.NewRequest(http.MethodPost, "/auth/refresh", body)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("Testing logout", func(t *testing.T) {
+		var session echo.Map
+		data := url.Values{}
+		data.Add("email", "pat@shiu.com")
+		data.Add("password", "12345678")
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer([]byte(data.Encode())))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &session))
+
+		data = url.Values{}
+		data.Add("refresh_token", fmt.Sprintf("%s", session["refresh_token"]))
+		req = httptest.NewRequest(http.MethodPost, "/auth/logout", bytes.NewBuffer([]byte(data.Encode())))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res = httptest.NewRecorder()
+		router.ServeHTTP(res, req)
 		assert.Equal(t, http.StatusOK, res.Code)
+
+		req = httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBuffer([]byte(data.Encode())))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res = httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("Testing logout with empty token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+
+	t.Run("Testing confirm user account with recovery token", func(t *testing.T) {
+		obj := map[string]string{
+			"token": tokenRecoveryID.String(),
+		}
+		body, _ := json.Marshal(obj)
+		req := httptest.NewRequest(http.MethodPost, "/auth/confirm", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("Testing recover password with confirmation token", func(t *testing.T) {
+		path := fmt.Sprintf("/auth/check-recover?token=%s", tokenConfirmID)
+		data := url.Values{}
+		data.Add("password", "135791113")
+		body := bytes.NewBuffer([]byte(data.Encode()))
+		req := httptest.NewRequest(http.MethodPost, path, body)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
 	})
 
 	t.Run("Testing confirm user account", func(t *testing.T) {
@@ -92,6 +281,10 @@ func TestAuth(t *testing.T) {
 		require.Nil(t, err)
 		assert.NotZero(t, user.UUID)
 		assert.Equal(t, "confirmed", user.Status)
+
+		stored, err := models.GetUser(userConfirmID, userConfirmID)
+		require.Nil(t, err)
+		assert.Equal(t, models.UserConfirmed, stored.Status)
 	})
 
 	t.Run("Testing request recovery token", func(t *testing.T) {
@@ -119,6 +312,31 @@ func TestAuth(t *testing.T) {
 		router.ServeHTTP(res, req)
 
 		assert.Equal(t, http.StatusPartialContent, res.Code)
+
+		data = url.Values{}
+		data.Add("email", "john-pending@doe.com")
+		data.Add("password", "135791113")
+		req = httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer([]byte(data.Encode())))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		res = httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("Testing reuse recovery token", func(t *testing.T) {
+		path := fmt.Sprintf("/auth/check-recover?token=%s", tokenRecoveryID)
+		data := url.Values{}
+		data.Add("password", "2468101214")
+		body := bytes.NewBuffer([]byte(data.Encode()))
+		req := httptest.NewRequest(http.MethodPost, path, body)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
 	})
 
 }
@@ -129,7 +347,7 @@ func mustSetupRouter() *echo.Echo {
 	conf.TemplatesDir = "../api/templates"
 	conf.FixturesDir = "../api/models/fixtures"
 
-	router := api.SetupRouter()
+	router := api.SetupRouter(auth.JWTAuthenticator{})
 	return router
 }
 
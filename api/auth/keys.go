@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/config"
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/golang-jwt/jwt"
+)
+
+// SigningKey is an HMAC secret identified by the kid header of the tokens it signs
+type SigningKey struct {
+	ID        string
+	Secret    []byte
+	RetiredAt time.Time
+}
+
+// Keyring signs tokens with its current key, and verifies them with either the current key,
+// or a retired key which is still within its grace period
+type Keyring struct {
+	current SigningKey
+	retired map[string]SigningKey
+	grace   time.Duration
+}
+
+var (
+	keys   *Keyring
+	keysMu sync.RWMutex
+
+	accessTTL  = 15 * time.Minute
+	refreshTTL = 30 * 24 * time.Hour
+)
+
+// NewKeyring creates a keyring from the JWT configuration. A missing secret is an error in release mode;
+// in every other mode, a random secret is generated so that tokens only live as long as the process.
+func NewKeyring(conf config.JWTConfig) (*Keyring, error) {
+	secret := []byte(conf.Secret)
+	if len(secret) == 0 {
+		if os.Getenv("API_MODE") == "release" {
+			return nil, errors.New("missing JWT secret, set JWT_SECRET or jwt.secret in the configuration")
+		}
+
+		zero.Warn("no JWT secret configured, generating an ephemeral one")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+
+	id := conf.KeyID
+	if id == "" {
+		id = "default"
+	}
+
+	kr := &Keyring{
+		current: SigningKey{ID: id, Secret: secret},
+		retired: make(map[string]SigningKey),
+		grace:   conf.Grace,
+	}
+
+	for _, k := range conf.RetiredKeys {
+		if k.KeyID == id {
+			return nil, fmt.Errorf("retired key %s has the same ID as the current key", k.KeyID)
+		}
+
+		kr.retired[k.KeyID] = SigningKey{ID: k.KeyID, Secret: []byte(k.Secret), RetiredAt: k.RetiredAt}
+	}
+
+	return kr, nil
+}
+
+// Init sets the keyring and the token lifetimes used by Login and Authenticate
+func Init(conf config.JWTConfig) error {
+	kr, err := NewKeyring(conf)
+	if err != nil {
+		return err
+	}
+
+	keysMu.Lock()
+	keys = kr
+	if conf.AccessTTL > 0 {
+		accessTTL = conf.AccessTTL
+	}
+	if conf.RefreshTTL > 0 {
+		refreshTTL = conf.RefreshTTL
+	}
+	keysMu.Unlock()
+
+	return nil
+}
+
+func getKeyring() (*Keyring, error) {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+
+	if keys == nil {
+		return nil, errors.New("the JWT keyring has not been initialized")
+	}
+	return keys, nil
+}
+
+// Sign returns the token signed with the current key, with its ID set as the kid header
+func (kr *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = kr.current.ID
+	return token.SignedString(kr.current.Secret)
+}
+
+// Parse verifies the token against the key designated by its kid header, and decodes it into claims
+func (kr *Keyring) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, kr.keyFunc)
+}
+
+func (kr *Keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, errors.New("unexpected JWT signing method")
+	}
+
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, errors.New("missing kid header on JWT")
+	}
+
+	if kid == kr.current.ID {
+		return kr.current.Secret, nil
+	}
+
+	key, found := kr.retired[kid]
+	if !found {
+		return nil, fmt.Errorf("unknown JWT signing key: %s", kid)
+	}
+
+	if time.Now().After(key.RetiredAt.Add(kr.grace)) {
+		return nil, fmt.Errorf("expired JWT signing key: %s", kid)
+	}
+
+	return key.Secret, nil
+}
@@ -0,0 +1,119 @@
+package auth_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/auth"
+	"github.com/commonsyllabi/explorer/api/config"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyring(t *testing.T) {
+	claims := func() *auth.JWTCustomClaims {
+		return &auth.JWTCustomClaims{
+			Name: "Justyna Poplawska",
+			UUID: "e7b74bcd-c864-41ee-b5a7-d3031f76c8a8",
+			StandardClaims: jwt.StandardClaims{
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			},
+		}
+	}
+
+	previous, err := auth.NewKeyring(config.JWTConfig{KeyID: "2022-01", Secret: "previous-secret"})
+	require.Nil(t, err)
+
+	ancient, err := auth.NewKeyring(config.JWTConfig{KeyID: "2021-01", Secret: "ancient-secret"})
+	require.Nil(t, err)
+
+	current, err := auth.NewKeyring(config.JWTConfig{
+		KeyID:  "2022-02",
+		Secret: "current-secret",
+		Grace:  72 * time.Hour,
+		RetiredKeys: []config.RetiredKey{
+			{KeyID: "2022-01", Secret: "previous-secret", RetiredAt: time.Now().Add(-24 * time.Hour)},
+			{KeyID: "2021-01", Secret: "ancient-secret", RetiredAt: time.Now().Add(-30 * 24 * time.Hour)},
+		},
+	})
+	require.Nil(t, err)
+
+	t.Run("Test sign with current key", func(t *testing.T) {
+		signed, err := current.Sign(claims())
+		require.Nil(t, err)
+
+		parsed := &auth.JWTCustomClaims{}
+		token, err := current.Parse(signed, parsed)
+		require.Nil(t, err)
+		assert.True(t, token.Valid)
+		assert.Equal(t, "2022-02", token.Header["kid"])
+		assert.Equal(t, "e7b74bcd-c864-41ee-b5a7-d3031f76c8a8", parsed.UUID)
+	})
+
+	t.Run("Test verify with recently retired key", func(t *testing.T) {
+		signed, err := previous.Sign(claims())
+		require.Nil(t, err)
+
+		token, err := current.Parse(signed, &auth.JWTCustomClaims{})
+		require.Nil(t, err)
+		assert.True(t, token.Valid)
+	})
+
+	t.Run("Test reject expired retired key", func(t *testing.T) {
+		signed, err := ancient.Sign(claims())
+		require.Nil(t, err)
+
+		_, err = current.Parse(signed, &auth.JWTCustomClaims{})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test reject unknown key", func(t *testing.T) {
+		unknown, err := auth.NewKeyring(config.JWTConfig{KeyID: "rogue", Secret: "current-secret"})
+		require.Nil(t, err)
+
+		signed, err := unknown.Sign(claims())
+		require.Nil(t, err)
+
+		_, err = current.Parse(signed, &auth.JWTCustomClaims{})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test reject wrong secret", func(t *testing.T) {
+		forged, err := auth.NewKeyring(config.JWTConfig{KeyID: "2022-02", Secret: "cosyl"})
+		require.Nil(t, err)
+
+		signed, err := forged.Sign(claims())
+		require.Nil(t, err)
+
+		_, err = current.Parse(signed, &auth.JWTCustomClaims{})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test reject missing kid", func(t *testing.T) {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims()).SignedString([]byte("current-secret"))
+		require.Nil(t, err)
+
+		_, err = current.Parse(signed, &auth.JWTCustomClaims{})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test missing secret in release mode", func(t *testing.T) {
+		mode := os.Getenv("API_MODE")
+		os.Setenv("API_MODE", "release")
+		defer os.Setenv("API_MODE", mode)
+
+		_, err := auth.NewKeyring(config.JWTConfig{KeyID: "2022-02"})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test retired key sharing the current ID", func(t *testing.T) {
+		_, err := auth.NewKeyring(config.JWTConfig{
+			KeyID:       "2022-02",
+			Secret:      "current-secret",
+			RetiredKeys: []config.RetiredKey{{KeyID: "2022-02", Secret: "previous-secret"}},
+		})
+		assert.NotNil(t, err)
+	})
+}
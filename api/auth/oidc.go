@@ -0,0 +1,454 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/config"
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+// oidcStateTTL is how long users have to log in with their identity provider once they have been redirected to it
+const oidcStateTTL = 10 * time.Minute
+
+// oidcStateCookie holds the hash of the state of a login, binding it to the browser which started it
+const oidcStateCookie = "oidc_state"
+
+// oidcProvider is a configured identity provider, along with its endpoints and signing keys once they have been discovered
+type oidcProvider struct {
+	conf   config.OIDCProvider
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *oidcMetadata
+	keys     map[string]*rsa.PublicKey
+}
+
+// oidcMetadata is the part of the discovery document of a provider that the authorization code flow needs
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+var (
+	providers   = make(map[string]*oidcProvider)
+	providersMu sync.RWMutex
+)
+
+// InitOIDC sets the identity providers users can log in with. Their endpoints are only discovered on the first login,
+// so that an unavailable provider does not prevent the API from starting.
+func InitOIDC(confs []config.OIDCProvider) error {
+	configured := make(map[string]*oidcProvider)
+	for _, conf := range confs {
+		if conf.Name == "" || conf.Issuer == "" || conf.ClientID == "" || conf.RedirectURL == "" {
+			return fmt.Errorf("the OIDC provider %q needs a name, an issuer, a client ID and a redirect URL", conf.Name)
+		}
+
+		if _, found := configured[conf.Name]; found {
+			return fmt.Errorf("the OIDC provider %s is declared twice", conf.Name)
+		}
+
+		configured[conf.Name] = &oidcProvider{
+			conf:   conf,
+			client: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
+	providersMu.Lock()
+	providers = configured
+	providersMu.Unlock()
+
+	return nil
+}
+
+func getProvider(name string) (*oidcProvider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	p, found := providers[name]
+	return p, found
+}
+
+// OIDCLogin redirects the user to the authorization endpoint of the provider, with a PKCE challenge
+func OIDCLogin(c echo.Context) error {
+	p, found := getProvider(c.Param("provider"))
+	if !found {
+		return c.String(http.StatusNotFound, "Unknown identity provider.")
+	}
+
+	meta, err := p.discover()
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadGateway, "The identity provider is unavailable.")
+	}
+
+	verifier, err := randomString()
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error starting the login.")
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error starting the login.")
+	}
+
+	state, err := models.CreateOIDCState(p.conf.Name, nonce, verifier, oidcStateTTL)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error starting the login.")
+	}
+
+	// -- without it, a login started by someone else could be completed in the browser of the user, logging them into the wrong account
+	c.SetCookie(&http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    hashState(state),
+		Path:     "/",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		Secure:   c.Scheme() == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	challenge := sha256.Sum256([]byte(verifier))
+	scopes := append([]string{"openid", "email", "profile"}, p.conf.Scopes...)
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.conf.ClientID)
+	q.Set("redirect_uri", p.conf.RedirectURL)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return c.Redirect(http.StatusFound, meta.AuthorizationEndpoint+sep+q.Encode())
+}
+
+// OIDCCallback completes the login once the provider has redirected the user back. The authorization code is exchanged for an ID token,
+// whose verified email is used to log in to the linked account, to link an existing account or to create a new one.
+func OIDCCallback(c echo.Context) error {
+	p, found := getProvider(c.Param("provider"))
+	if !found {
+		return c.String(http.StatusNotFound, "Unknown identity provider.")
+	}
+
+	if e := c.QueryParam("error"); e != "" {
+		zero.Warnf("login with %s failed: %s %s", p.conf.Name, e, c.QueryParam("error_description"))
+		return c.String(http.StatusUnauthorized, "Authentication failed")
+	}
+
+	code, state := c.QueryParam("code"), c.QueryParam("state")
+	if code == "" || state == "" {
+		return c.String(http.StatusBadRequest, "Parameters can't be empty")
+	}
+
+	cookie, err := c.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(hashState(state))) != 1 {
+		zero.Warnf("login with %s was not started by the same browser", p.conf.Name)
+		return c.String(http.StatusUnauthorized, "The login request is invalid or has expired, please try again.")
+	}
+
+	c.SetCookie(&http.Cookie{Name: oidcStateCookie, Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+
+	pending, err := models.RedeemOIDCState(state, p.conf.Name)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusUnauthorized, "The login request is invalid or has expired, please try again.")
+	}
+
+	raw, err := p.exchange(code, pending.Verifier)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusUnauthorized, "Authentication failed")
+	}
+
+	claims, err := p.verify(raw, pending.Nonce)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusUnauthorized, "Authentication failed")
+	}
+
+	profile, err := p.profile(claims)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusForbidden, "Your identity provider has not verified your email address.")
+	}
+
+	user, err := models.LoginOIDCUser(profile)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrAccountSuspended) {
+			return c.String(http.StatusForbidden, "Your account is suspended.")
+		}
+		return c.String(http.StatusInternalServerError, "There was an error logging you in.")
+	}
+
+	return completeLogin(c, user)
+}
+
+// discover fetches the discovery document of the provider, and checks that it belongs to the configured issuer
+func (p *oidcProvider) discover() (*oidcMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var meta oidcMetadata
+	err := p.getJSON(strings.TrimSuffix(p.conf.Issuer, "/")+"/.well-known/openid-configuration", &meta)
+	if err != nil {
+		return nil, fmt.Errorf("could not discover the OIDC provider %s: %v", p.conf.Name, err)
+	}
+
+	if strings.TrimSuffix(meta.Issuer, "/") != strings.TrimSuffix(p.conf.Issuer, "/") {
+		return nil, fmt.Errorf("the OIDC provider %s announces the issuer %s instead of %s", p.conf.Name, meta.Issuer, p.conf.Issuer)
+	}
+
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("the discovery document of the OIDC provider %s is incomplete", p.conf.Name)
+	}
+
+	p.metadata = &meta
+	return p.metadata, nil
+}
+
+// exchange redeems the authorization code along with the PKCE verifier, and returns the raw ID token
+func (p *oidcProvider) exchange(code string, verifier string) (string, error) {
+	meta, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.conf.RedirectURL)
+	form.Set("client_id", p.conf.ClientID)
+	form.Set("code_verifier", verifier)
+	if p.conf.ClientSecret != "" {
+		form.Set("client_secret", p.conf.ClientSecret)
+	}
+
+	res, err := p.client.PostForm(meta.TokenEndpoint, form)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return "", fmt.Errorf("the OIDC provider %s refused the authorization code: %d %s", p.conf.Name, res.StatusCode, body)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&tokens)
+	if err != nil {
+		return "", err
+	}
+
+	if tokens.IDToken == "" {
+		return "", fmt.Errorf("the OIDC provider %s did not return an ID token", p.conf.Name)
+	}
+
+	return tokens.IDToken, nil
+}
+
+// verify checks the signature of the ID token against the keys of the provider, then its issuer, audience, expiry and nonce
+func (p *oidcProvider) verify(raw string, nonce string) (jwt.MapClaims, error) {
+	meta, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.New("unexpected ID token signing method")
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return p.key(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyIssuer(meta.Issuer, true) {
+		return nil, fmt.Errorf("unexpected ID token issuer: %v", claims["iss"])
+	}
+
+	if !claims.VerifyAudience(p.conf.ClientID, true) {
+		return nil, fmt.Errorf("unexpected ID token audience: %v", claims["aud"])
+	}
+
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, errors.New("the ID token has expired")
+	}
+
+	if n, _ := claims["nonce"].(string); n == "" || n != nonce {
+		return nil, errors.New("the ID token nonce does not match the login request")
+	}
+
+	return claims, nil
+}
+
+// key returns the signing key with the given ID. The keys are fetched again when an unknown one is requested,
+// since providers rotate them.
+func (p *oidcProvider) key(kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if k, found := p.lookupKey(kid); found {
+		return k, nil
+	}
+
+	var set jwks
+	err := p.getJSON(p.metadata.JWKSURI, &set)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the keys of the OIDC provider %s: %v", p.conf.Name, err)
+	}
+
+	p.keys = make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+
+		p.keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	if k, found := p.lookupKey(kid); found {
+		return k, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q for the OIDC provider %s", kid, p.conf.Name)
+}
+
+// lookupKey finds a cached key. Tokens without a key ID are accepted when the provider has a single key.
+func (p *oidcProvider) lookupKey(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+
+	k, found := p.keys[kid]
+	return k, found
+}
+
+// profile extracts the user from the ID token claims. Only verified email addresses are accepted, since accounts are linked by email.
+func (p *oidcProvider) profile(claims jwt.MapClaims) (models.OIDCProfile, error) {
+	profile := models.OIDCProfile{Provider: p.conf.Name}
+
+	profile.Subject, _ = claims["sub"].(string)
+	profile.Email, _ = claims["email"].(string)
+	profile.Name, _ = claims["name"].(string)
+
+	if profile.Subject == "" || profile.Email == "" {
+		return profile, errors.New("the ID token has no subject or no email")
+	}
+
+	verified := false
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = v == "true"
+	}
+	if !verified {
+		return profile, fmt.Errorf("the email %s is not verified by %s", profile.Email, p.conf.Name)
+	}
+
+	if p.conf.InstitutionClaim != "" {
+		switch v := claims[p.conf.InstitutionClaim].(type) {
+		case string:
+			profile.Institutions = append(profile.Institutions, v)
+		case []interface{}:
+			for _, i := range v {
+				if s, ok := i.(string); ok {
+					profile.Institutions = append(profile.Institutions, s)
+				}
+			}
+		}
+	}
+
+	return profile, nil
+}
+
+func (p *oidcProvider) getJSON(url string, v interface{}) error {
+	res, err := p.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", res.StatusCode, url)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// randomString returns a url-safe random string, used for PKCE verifiers and nonces
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
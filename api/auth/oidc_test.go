@@ -0,0 +1,327 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/auth"
+	"github.com/commonsyllabi/explorer/api/config"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockIdP is a local OpenID provider. It signs the claims set before each login, and checks the PKCE verifier when redeeming codes.
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	claims jwt.MapClaims
+	nonce  string
+	issued int
+	codes  map[string]authorization
+}
+
+type authorization struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	idp := &mockIdP{key: key, codes: make(map[string]authorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "mock",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "explorer" {
+			http.Error(w, "invalid_request", http.StatusBadRequest)
+			return
+		}
+
+		idp.mu.Lock()
+		idp.issued++
+		code := fmt.Sprintf("code-%d", idp.issued)
+		nonce := q.Get("nonce")
+		if idp.nonce != "" {
+			nonce = idp.nonce
+		}
+		idp.codes[code] = authorization{challenge: q.Get("code_challenge"), nonce: nonce, claims: idp.claims}
+		idp.mu.Unlock()
+
+		redirect := fmt.Sprintf("%s?code=%s&state=%s", q.Get("redirect_uri"), code, url.QueryEscape(q.Get("state")))
+		http.Redirect(w, r, redirect, http.StatusFound)
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		authz, found := idp.codes[r.FormValue("code")]
+		delete(idp.codes, r.FormValue("code"))
+		idp.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !found || base64.RawURLEncoding.EncodeToString(sum[:]) != authz.challenge {
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":   idp.server.URL,
+			"aud":   "explorer",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": authz.nonce,
+		}
+		for k, v := range authz.claims {
+			claims[k] = v
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "mock"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "opaque", "id_token": signed})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	return idp
+}
+
+// login goes through the whole authorization code flow, and returns the response of the callback
+func (idp *mockIdP) login(t *testing.T, claims jwt.MapClaims) *httptest.ResponseRecorder {
+	idp.mu.Lock()
+	idp.claims = claims
+	idp.mu.Unlock()
+
+	code, state, cookie := idp.authorize(t)
+	return callback(code, state, cookie)
+}
+
+// authorize starts a login on the API, and follows it to the provider until it redirects back with a code.
+// It also returns the state cookie set by the API in the browser.
+func (idp *mockIdP) authorize(t *testing.T) (string, string, *http.Cookie) {
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/campus/login", nil)
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	require.Equal(t, http.StatusFound, res.Code)
+
+	cookies := res.Result().Cookies()
+	require.Equal(t, 1, len(cookies))
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(res.Header().Get("Location"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	back, err := url.Parse(resp.Header.Get("Location"))
+	require.Nil(t, err)
+	return back.Query().Get("code"), back.Query().Get("state"), cookies[0]
+}
+
+func callback(code string, state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	q := url.Values{"code": {code}, "state": {state}}
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/campus/callback?"+q.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func TestOIDC(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	idp := newMockIdP(t)
+	defer idp.server.Close()
+
+	err := auth.InitOIDC([]config.OIDCProvider{{
+		Name:             "campus",
+		Issuer:           idp.server.URL,
+		ClientID:         "explorer",
+		ClientSecret:     "secret",
+		RedirectURL:      "http://localhost:3000/auth/callback",
+		InstitutionClaim: "institution",
+	}})
+	require.Nil(t, err)
+	defer auth.InitOIDC(nil)
+
+	t.Run("Test login redirects with a PKCE challenge", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/auth/oidc/campus/login", nil)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		require.Equal(t, http.StatusFound, res.Code)
+
+		location, err := url.Parse(res.Header().Get("Location"))
+		require.Nil(t, err)
+		assert.Equal(t, "/authorize", location.Path)
+		assert.Equal(t, "S256", location.Query().Get("code_challenge_method"))
+		assert.NotEmpty(t, location.Query().Get("code_challenge"))
+		assert.NotEmpty(t, location.Query().Get("state"))
+		assert.NotEmpty(t, location.Query().Get("nonce"))
+		assert.Contains(t, location.Query().Get("scope"), "openid")
+
+		cookies := res.Result().Cookies()
+		require.Equal(t, 1, len(cookies))
+		assert.True(t, cookies[0].HttpOnly)
+		assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+		assert.NotEqual(t, location.Query().Get("state"), cookies[0].Value)
+	})
+
+	t.Run("Test login with unknown provider", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/auth/oidc/elsewhere/login", nil)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("Test login creates a new user", func(t *testing.T) {
+		claims := jwt.MapClaims{
+			"sub":            "campus-001",
+			"email":          "ada@campus.edu",
+			"email_verified": true,
+			"name":           "Ada Lovelace",
+			"institution":    []string{"Campus University"},
+		}
+		res := idp.login(t, claims)
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+
+		var session struct {
+			User  models.User `json:"user"`
+			Token string      `json:"token"`
+		}
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &session))
+		assert.NotEmpty(t, session.Token)
+		assert.Equal(t, "ada@campus.edu", session.User.Email)
+		assert.Equal(t, models.UserConfirmed, session.User.Status)
+		require.Equal(t, 1, len(session.User.Institutions))
+		assert.Equal(t, "Campus University", session.User.Institutions[0].Name)
+
+		res = idp.login(t, claims)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var again struct {
+			User models.User `json:"user"`
+		}
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &again))
+		assert.Equal(t, session.User.UUID, again.User.UUID)
+		assert.Equal(t, 1, len(again.User.Institutions))
+	})
+
+	t.Run("Test login links an existing user by email", func(t *testing.T) {
+		res := idp.login(t, jwt.MapClaims{"sub": "campus-002", "email": "jus@pop.com", "email_verified": true})
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var session struct {
+			User models.User `json:"user"`
+		}
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &session))
+		assert.Equal(t, "e7b74bcd-c864-41ee-b5a7-d3031f76c8a8", session.User.UUID.String())
+	})
+
+	t.Run("Test login confirms a pending user", func(t *testing.T) {
+		res := idp.login(t, jwt.MapClaims{"sub": "campus-003", "email": "john-pending@doe.com", "email_verified": true})
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var session struct {
+			User models.User `json:"user"`
+		}
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &session))
+		assert.Equal(t, models.UserConfirmed, session.User.Status)
+	})
+
+	t.Run("Test login with unverified email", func(t *testing.T) {
+		res := idp.login(t, jwt.MapClaims{"sub": "campus-004", "email": "pat@shiu.com", "email_verified": false})
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	})
+
+	t.Run("Test login with mismatched nonce", func(t *testing.T) {
+		idp.mu.Lock()
+		idp.nonce = "replayed"
+		idp.mu.Unlock()
+		defer func() {
+			idp.mu.Lock()
+			idp.nonce = ""
+			idp.mu.Unlock()
+		}()
+
+		res := idp.login(t, jwt.MapClaims{"sub": "campus-001", "email": "ada@campus.edu", "email_verified": true})
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("Test callback with reused state", func(t *testing.T) {
+		idp.mu.Lock()
+		idp.claims = jwt.MapClaims{"sub": "campus-001", "email": "ada@campus.edu", "email_verified": true}
+		idp.mu.Unlock()
+
+		code, state, cookie := idp.authorize(t)
+		res := callback(code, state, cookie)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		res = callback(code, state, cookie)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("Test callback with unknown state", func(t *testing.T) {
+		code, _, cookie := idp.authorize(t)
+		res := callback(code, "forged", cookie)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("Test callback in another browser than the login", func(t *testing.T) {
+		idp.mu.Lock()
+		idp.claims = jwt.MapClaims{"sub": "campus-001", "email": "ada@campus.edu", "email_verified": true}
+		idp.mu.Unlock()
+
+		code, state, _ := idp.authorize(t)
+		res := callback(code, state, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+		_, _, other := idp.authorize(t)
+		res = callback(code, state, other)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Relation is what ties the user of a request to the resource they act upon
+type Relation int
+
+const (
+	Anyone Relation = iota
+	Member
+	Collaborator
+	Owner
+	Moderator
+	Admin
+)
+
+// Rule declares which relations grant an action. Resource and Param locate the resource the action targets,
+// Param being looked up in the route parameters first, then in the query parameters.
+// Actions which do not target an existing resource leave both empty.
+// Scope is the scope an API token needs for the action, and actions without one can only be done with a session.
+type Rule struct {
+	Resource string
+	Param    string
+	Scope    string
+	Allow    []Relation
+}
+
+// RESOURCES looks up who has access to each kind of resource
+var RESOURCES = map[string]func(uuid.UUID) (models.Access, error){
+	"syllabus":     models.GetSyllabusAccess,
+	"collection":   models.GetCollectionAccess,
+	"attachment":   models.GetAttachmentAccess,
+	"user":         models.GetUserAccess,
+	"api_token":    models.GetAPITokenAccess,
+	"saved_search": models.GetSavedSearchAccess,
+}
+
+// POLICIES declares who may do what, for every action of the API
+var POLICIES = map[string]Rule{
+	"syllabus.read":                 {Scope: models.ScopeRead, Allow: []Relation{Anyone}},
+	"syllabus.create":               {Scope: models.ScopeWriteSyllabi, Allow: []Relation{Member}},
+	"syllabus.parse":                {Scope: models.ScopeWriteSyllabi, Allow: []Relation{Member}},
+	"syllabus.fork":                 {Scope: models.ScopeWriteSyllabi, Allow: []Relation{Member}},
+	"syllabus.update":               {Resource: "syllabus", Param: "id", Scope: models.ScopeWriteSyllabi, Allow: []Relation{Owner, Collaborator, Admin}},
+	"syllabus.attachments.update":   {Resource: "syllabus", Param: "id", Scope: models.ScopeWriteSyllabi, Allow: []Relation{Owner, Collaborator, Admin}},
+	"syllabus.institutions.update":  {Resource: "syllabus", Param: "id", Scope: models.ScopeWriteSyllabi, Allow: []Relation{Owner, Collaborator, Admin}},
+	"syllabus.sessions.update":      {Resource: "syllabus", Param: "id", Scope: models.ScopeWriteSyllabi, Allow: []Relation{Owner, Collaborator, Admin}},
+	"syllabus.assessments.update":   {Resource: "syllabus", Param: "id", Scope: models.ScopeWriteSyllabi, Allow: []Relation{Owner, Collaborator, Admin}},
+	"syllabus.collaborators.update": {Resource: "syllabus", Param: "id", Scope: models.ScopeWriteSyllabi, Allow: []Relation{Owner, Admin}},
+	"syllabus.delete":               {Resource: "syllabus", Param: "id", Scope: models.ScopeWriteSyllabi, Allow: []Relation{Owner, Admin}},
+	"syllabus.unlist":               {Resource: "syllabus", Param: "id", Allow: []Relation{Moderator}},
+
+	"collection.read":           {Scope: models.ScopeRead, Allow: []Relation{Anyone}},
+	"collection.create":         {Scope: models.ScopeWriteCollections, Allow: []Relation{Member}},
+	"collection.update":         {Resource: "collection", Param: "id", Scope: models.ScopeWriteCollections, Allow: []Relation{Owner, Admin}},
+	"collection.syllabi.update": {Resource: "collection", Param: "id", Scope: models.ScopeWriteCollections, Allow: []Relation{Owner, Admin}},
+	"collection.delete":         {Resource: "collection", Param: "id", Scope: models.ScopeWriteCollections, Allow: []Relation{Owner, Admin}},
+	"collection.unlist":         {Resource: "collection", Param: "id", Allow: []Relation{Moderator}},
+
+	"attachment.read":   {Scope: models.ScopeRead, Allow: []Relation{Anyone}},
+	"attachment.create": {Resource: "syllabus", Param: "syllabus_id", Scope: models.ScopeWriteSyllabi, Allow: []Relation{Owner, Collaborator, Admin}},
+	"attachment.update": {Resource: "attachment", Param: "id", Scope: models.ScopeWriteSyllabi, Allow: []Relation{Owner, Collaborator, Admin}},
+	"attachment.delete": {Resource: "attachment", Param: "id", Scope: models.ScopeWriteSyllabi, Allow: []Relation{Owner, Collaborator, Admin}},
+
+	"user.read":                {Scope: models.ScopeRead, Allow: []Relation{Anyone}},
+	"user.create":              {Allow: []Relation{Anyone}},
+	"user.update":              {Resource: "user", Param: "id", Allow: []Relation{Owner, Admin}},
+	"user.institutions.update": {Resource: "user", Param: "id", Allow: []Relation{Owner, Admin}},
+	"user.delete":              {Resource: "user", Param: "id", Allow: []Relation{Owner, Admin}},
+	"user.list":                {Allow: []Relation{Admin}},
+	"user.manage":              {Resource: "user", Param: "id", Allow: []Relation{Admin}},
+
+	"taxonomy.read":     {Scope: models.ScopeRead, Allow: []Relation{Anyone}},
+	"reading.read":      {Scope: models.ScopeRead, Allow: []Relation{Anyone}},
+	"autocomplete.read": {Scope: models.ScopeRead, Allow: []Relation{Anyone}},
+	"assessment.read":   {Scope: models.ScopeRead, Allow: []Relation{Anyone}},
+
+	"session.create":  {Allow: []Relation{Anyone}},
+	"session.revoke":  {Allow: []Relation{Member}},
+	"account.recover": {Allow: []Relation{Anyone}},
+	"admin.overview":  {Allow: []Relation{Admin}},
+
+	// -- users required to enroll do so before they have a session, with the challenge given at login
+	"two_factor.enroll":  {Allow: []Relation{Anyone}},
+	"two_factor.disable": {Allow: []Relation{Member}},
+
+	"api_token.list":   {Allow: []Relation{Member}},
+	"api_token.create": {Allow: []Relation{Member}},
+	"api_token.revoke": {Resource: "api_token", Param: "id", Allow: []Relation{Owner, Admin}},
+
+	// -- digests can be turned off from the link they come with, without logging in
+	"saved_search.list":        {Allow: []Relation{Member}},
+	"saved_search.create":      {Allow: []Relation{Member}},
+	"saved_search.update":      {Resource: "saved_search", Param: "id", Allow: []Relation{Owner, Admin}},
+	"saved_search.delete":      {Resource: "saved_search", Param: "id", Allow: []Relation{Owner, Admin}},
+	"saved_search.unsubscribe": {Allow: []Relation{Anyone}},
+}
+
+// Authorize enforces the policy of the action on every request of the route. It expects the identity of the user
+// to have been set on the context beforehand, and answers 401 to anonymous users and 403 to authenticated ones when denied.
+// A policy for an unknown action or resource is a programming error, and panics when the router is set up.
+func Authorize(action string) echo.MiddlewareFunc {
+	rule, found := POLICIES[action]
+	if !found {
+		panic(fmt.Sprintf("no policy declared for action %s", action))
+	}
+
+	lookup, found := RESOURCES[rule.Resource]
+	if rule.Resource != "" && !found {
+		panic(fmt.Sprintf("no access lookup declared for resource %s of action %s", rule.Resource, action))
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user_uuid, _ := c.Get("user_uuid").(uuid.UUID)
+			user_role, _ := c.Get("user_role").(string)
+
+			if user_uuid == uuid.Nil && !allowsAnyone(rule) {
+				return c.String(http.StatusUnauthorized, "unauthorized")
+			}
+
+			if scopes, limited := c.Get("user_scopes").([]string); limited && !hasScope(scopes, rule.Scope) {
+				zero.Warnf("API token of user %s lacks the scope of %s on %s", user_uuid, action, c.Request().URL.Path)
+				return c.String(http.StatusForbidden, "This API token does not allow this action.")
+			}
+
+			var access models.Access
+			if rule.Resource != "" {
+				raw := c.Param(rule.Param)
+				if raw == "" {
+					raw = c.QueryParam(rule.Param)
+				}
+
+				id, err := uuid.Parse(raw)
+				if err != nil {
+					zero.Error(err.Error())
+					return c.String(http.StatusBadRequest, "Not a valid ID.")
+				}
+
+				access, err = lookup(id)
+				if err != nil {
+					zero.Error(err.Error())
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return c.String(http.StatusNotFound, fmt.Sprintf("We could not find the requested %s.", rule.Resource))
+					}
+					return c.String(http.StatusInternalServerError, "There was an error checking your permissions.")
+				}
+			}
+
+			for _, r := range rule.Allow {
+				if granted(r, user_uuid, user_role, access) {
+					return next(c)
+				}
+			}
+
+			zero.Warnf("user %s was denied %s on %s", user_uuid, action, c.Request().URL.Path)
+			return c.String(http.StatusForbidden, "forbidden")
+		}
+	}
+}
+
+// hasScope checks whether the scope is one of the scopes, the empty scope never being granted
+func hasScope(scopes []string, scope string) bool {
+	if scope == "" {
+		return false
+	}
+
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func allowsAnyone(rule Rule) bool {
+	for _, r := range rule.Allow {
+		if r == Anyone {
+			return true
+		}
+	}
+	return false
+}
+
+func granted(r Relation, user_uuid uuid.UUID, user_role string, access models.Access) bool {
+	switch r {
+	case Anyone:
+		return true
+	case Member:
+		return user_uuid != uuid.Nil
+	case Collaborator:
+		return access.IsCollaborator(user_uuid)
+	case Owner:
+		return access.IsOwner(user_uuid)
+	case Moderator:
+		return user_uuid != uuid.Nil && models.HasRole(user_role, models.RoleModerator)
+	case Admin:
+		return user_uuid != uuid.Nil && models.HasRole(user_role, models.RoleAdmin)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,277 @@
+package auth_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	anonymous    = "anonymous"
+	stranger     = "stranger"
+	collaborator = "collaborator"
+	moderator    = "moderator"
+	owner        = "owner"
+	admin        = "admin"
+)
+
+// -- actors are ordered so that the ones denied run before the allowed ones can delete the resource
+var actors = []string{anonymous, stranger, collaborator, moderator, owner, admin}
+
+var credentials = map[string]string{
+	stranger:     "pierre.depaz@gmail.com",
+	collaborator: "test@delete.com",
+	moderator:    "mail@commonsyllabi.org",
+	owner:        "jus@pop.com",
+	admin:        "pat@shiu.com",
+}
+
+var (
+	everyone = []string{anonymous, stranger, collaborator, moderator, owner, admin}
+	members  = []string{stranger, collaborator, moderator, owner, admin}
+	editors  = []string{collaborator, owner, admin}
+	owners   = []string{owner, admin}
+	mods     = []string{moderator, admin}
+	admins   = []string{admin}
+)
+
+type permission struct {
+	method  string
+	route   string
+	path    string
+	form    url.Values
+	allowed []string
+}
+
+func TestPolicies(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	syllID := "46de6a2b-aacb-4c24-b1e1-3495821f846a"
+	syllDeleteID := "46de6a2b-aacb-4c24-b1e1-3495821f8469"
+	collID := "b9e4c3ed-ac4f-4e44-bb43-5123b7b6d7a9"
+	collDeleteID := "b9e4666d-ac4f-4e44-bb43-5123b7b6d7a9"
+	attID := "c55f0baf-12b8-4bdb-b5e6-2280bff8ab21"
+	attDeleteID := "c55f0baf-12b8-4bdb-b5e6-2280bff8ab30"
+	ownerID := "e7b74bcd-c864-41ee-b5a7-d3031f76c8a8"
+	pendingID := "e7b74bcd-c864-41ee-b5a7-d3031f76c800"
+	instID := uuid.New().String()
+
+	_, err := models.AddCollaboratorToSyllabus(uuid.MustParse(syllID), uuid.MustParse("e7b74bcd-c975-41ee-b5a7-d3031f76c8a0"))
+	require.Nil(t, err)
+
+	_, apiToken, err := models.CreateAPIToken(uuid.MustParse(ownerID), "sync", []string{models.ScopeRead}, nil)
+	require.Nil(t, err)
+
+	search, err := models.CreateSavedSearch(uuid.MustParse(ownerID), "Media theory", "tags=media", models.DigestWeekly)
+	require.Nil(t, err)
+
+	matrix := []permission{
+		{http.MethodGet, "/ping", "/ping", nil, everyone},
+		{http.MethodGet, "/", "/", nil, everyone},
+		{http.MethodPost, "/", "/", nil, everyone},
+		{http.MethodGet, "/static", "/static", nil, everyone},
+		{http.MethodGet, "/static/*", "/static/none", nil, everyone},
+
+		{http.MethodPost, "/login", "/login", nil, everyone},
+		{http.MethodPost, "/auth/confirm", "/auth/confirm", nil, everyone},
+		{http.MethodPost, "/auth/request-recover", "/auth/request-recover", nil, everyone},
+		{http.MethodPost, "/auth/check-recover", "/auth/check-recover", nil, everyone},
+		{http.MethodPost, "/auth/refresh", "/auth/refresh", nil, everyone},
+		{http.MethodPost, "/auth/logout", "/auth/logout", nil, everyone},
+		{http.MethodPost, "/auth/logout-all", "/auth/logout-all", nil, members},
+		{http.MethodGet, "/auth/oidc/:provider/login", "/auth/oidc/unknown/login", nil, everyone},
+		{http.MethodGet, "/auth/oidc/:provider/callback", "/auth/oidc/unknown/callback", nil, everyone},
+		{http.MethodPost, "/auth/2fa/verify", "/auth/2fa/verify", nil, everyone},
+		// -- anonymous users can only enroll with an enrollment challenge
+		{http.MethodPost, "/auth/2fa/enroll", "/auth/2fa/enroll", nil, members},
+		{http.MethodPost, "/auth/2fa/activate", "/auth/2fa/activate", nil, members},
+		{http.MethodPost, "/auth/2fa/disable", "/auth/2fa/disable", nil, members},
+		{http.MethodGet, "/auth/tokens", "/auth/tokens", nil, members},
+		{http.MethodPost, "/auth/tokens", "/auth/tokens", nil, members},
+		{http.MethodDelete, "/auth/tokens/:id", "/auth/tokens/" + apiToken.UUID.String(), nil, owners},
+
+		{http.MethodGet, "/admin", "/admin", nil, admins},
+		{http.MethodGet, "/admin/users", "/admin/users", nil, admins},
+		{http.MethodPatch, "/admin/users/:id", "/admin/users/" + pendingID, url.Values{"role": {models.RoleUser}}, admins},
+		{http.MethodGet, "/admin/syllabi", "/admin/syllabi", nil, admins},
+		{http.MethodGet, "/admin/collections", "/admin/collections", nil, admins},
+		{http.MethodPost, "/admin/syllabi/:id/unlist", "/admin/syllabi/" + syllDeleteID + "/unlist", nil, mods},
+		{http.MethodPost, "/admin/collections/:id/unlist", "/admin/collections/" + collDeleteID + "/unlist", nil, mods},
+
+		{http.MethodGet, "/syllabi/", "/syllabi/", nil, everyone},
+		{http.MethodGet, "/syllabi/:id", "/syllabi/" + syllID, nil, everyone},
+		{http.MethodGet, "/syllabi/:id/similar", "/syllabi/" + syllID + "/similar", nil, everyone},
+		{http.MethodGet, "/syllabi/:id/derivatives", "/syllabi/" + syllID + "/derivatives", nil, everyone},
+		{http.MethodPost, "/syllabi/", "/syllabi/", nil, members},
+		{http.MethodPost, "/syllabi/:id/fork", "/syllabi/" + syllID + "/fork", nil, members},
+		{http.MethodPatch, "/syllabi/:id", "/syllabi/" + syllID, nil, editors},
+		{http.MethodGet, "/syllabi/:id/revisions", "/syllabi/" + syllID + "/revisions", nil, everyone},
+		{http.MethodGet, "/syllabi/:id/revisions/diff", "/syllabi/" + syllID + "/revisions/diff?from=1", nil, everyone},
+		{http.MethodGet, "/syllabi/:id/revisions/:number", "/syllabi/" + syllID + "/revisions/1", nil, everyone},
+		{http.MethodPost, "/syllabi/:id/revisions/:number/restore", "/syllabi/" + syllID + "/revisions/1/restore", nil, editors},
+		{http.MethodPost, "/syllabi/:id/institutions", "/syllabi/" + syllID + "/institutions", nil, editors},
+		{http.MethodPatch, "/syllabi/:id/institutions/:inst_id", "/syllabi/" + syllID + "/institutions/" + instID, nil, editors},
+		{http.MethodDelete, "/syllabi/:id/institutions/:inst_id", "/syllabi/" + syllID + "/institutions/" + instID, nil, editors},
+		{http.MethodPost, "/syllabi/:id/attachments", "/syllabi/" + syllID + "/attachments", url.Values{"att_id": {attID}}, editors},
+		{http.MethodDelete, "/syllabi/:id/attachments/:att_id", "/syllabi/" + syllID + "/attachments/" + attID, nil, editors},
+		{http.MethodPost, "/syllabi/:id/collaborators", "/syllabi/" + syllID + "/collaborators", url.Values{"user_id": {pendingID}}, owners},
+		{http.MethodDelete, "/syllabi/:id/collaborators/:user_id", "/syllabi/" + syllID + "/collaborators/" + pendingID, nil, owners},
+		{http.MethodGet, "/syllabi/:id/sessions", "/syllabi/" + syllID + "/sessions", nil, everyone},
+		{http.MethodPost, "/syllabi/:id/sessions", "/syllabi/" + syllID + "/sessions", url.Values{"title": {"Introduction"}}, editors},
+		{http.MethodPatch, "/syllabi/:id/sessions/:session_id", "/syllabi/" + syllID + "/sessions/" + uuid.New().String(), nil, editors},
+		{http.MethodDelete, "/syllabi/:id/sessions/:session_id", "/syllabi/" + syllID + "/sessions/" + uuid.New().String(), nil, editors},
+		{http.MethodGet, "/syllabi/:id/assessments", "/syllabi/" + syllID + "/assessments", nil, everyone},
+		{http.MethodPut, "/syllabi/:id/assessments", "/syllabi/" + syllID + "/assessments", url.Values{"type[]": {"exam"}, "weight[]": {"100"}}, editors},
+		{http.MethodPost, "/syllabi/parse", "/syllabi/parse", nil, members},
+
+		{http.MethodGet, "/users/:id", "/users/" + ownerID, nil, everyone},
+		{http.MethodPost, "/users/", "/users/", nil, everyone},
+		{http.MethodPatch, "/users/:id", "/users/" + ownerID, nil, owners},
+		{http.MethodPost, "/users/:id/institutions", "/users/" + ownerID + "/institutions", nil, owners},
+		{http.MethodPatch, "/users/:id/institutions/:inst_id", "/users/" + ownerID + "/institutions/" + instID, nil, owners},
+		{http.MethodDelete, "/users/:id/institutions/:inst_id", "/users/" + ownerID + "/institutions/" + instID, nil, owners},
+
+		{http.MethodGet, "/attachments/", "/attachments/", nil, everyone},
+		{http.MethodGet, "/attachments/:id", "/attachments/" + attID, nil, everyone},
+		{http.MethodPost, "/attachments/", "/attachments/?syllabus_id=" + syllID, nil, editors},
+		{http.MethodPatch, "/attachments/:id", "/attachments/" + attID, nil, editors},
+
+		{http.MethodGet, "/collections/", "/collections/", nil, everyone},
+		{http.MethodGet, "/collections/:id", "/collections/" + collID, nil, everyone},
+		{http.MethodGet, "/collections/:id/syllabi", "/collections/" + collID + "/syllabi", nil, everyone},
+		{http.MethodGet, "/collections/:id/syllabi/:syll_id", "/collections/" + collID + "/syllabi/" + syllID, nil, everyone},
+		{http.MethodPost, "/collections/", "/collections/", nil, members},
+		{http.MethodPatch, "/collections/:id", "/collections/" + collID, nil, owners},
+		{http.MethodPost, "/collections/:id/syllabi", "/collections/" + collID + "/syllabi", url.Values{"syllabus_id": {syllID}}, owners},
+		{http.MethodDelete, "/collections/:id/syllabi/:syll_id", "/collections/" + collID + "/syllabi/" + syllID, nil, owners},
+
+		{http.MethodGet, "/taxonomy/fields", "/taxonomy/fields", nil, everyone},
+		{http.MethodGet, "/taxonomy/licenses", "/taxonomy/licenses", nil, everyone},
+
+		{http.MethodGet, "/readings/", "/readings/", nil, everyone},
+		{http.MethodGet, "/readings/:id", "/readings/" + uuid.New().String(), nil, everyone},
+		{http.MethodGet, "/readings/:id/syllabi", "/readings/" + uuid.New().String() + "/syllabi", nil, everyone},
+
+		{http.MethodGet, "/autocomplete/:kind", "/autocomplete/tags?q=de", nil, everyone},
+
+		{http.MethodGet, "/assessments/mix", "/assessments/mix?level=narrow", nil, everyone},
+
+		{http.MethodGet, "/searches/", "/searches/", nil, members},
+		{http.MethodPost, "/searches/", "/searches/", nil, members},
+		{http.MethodPatch, "/searches/:id", "/searches/" + search.UUID.String(), nil, owners},
+		{http.MethodGet, "/searches/unsubscribe", "/searches/unsubscribe?token=unknown", nil, everyone},
+		{http.MethodPost, "/searches/unsubscribe", "/searches/unsubscribe?token=unknown", nil, everyone},
+
+		// -- destructive actions come last, the account of the owner being deleted at the very end
+		{http.MethodDelete, "/attachments/:id", "/attachments/" + attDeleteID, nil, editors},
+		{http.MethodDelete, "/syllabi/:id", "/syllabi/" + syllDeleteID, nil, owners},
+		{http.MethodDelete, "/collections/:id", "/collections/" + collDeleteID, nil, owners},
+		{http.MethodDelete, "/searches/:id", "/searches/" + search.UUID.String(), nil, owners},
+		{http.MethodDelete, "/admin/users/:id", "/admin/users/" + pendingID, nil, admins},
+		{http.MethodDelete, "/users/:id", "/users/" + ownerID, nil, owners},
+	}
+
+	t.Run("Testing every route has a policy", func(t *testing.T) {
+		declared := make(map[string]bool)
+		for _, p := range matrix {
+			declared[p.method+" "+p.route] = true
+		}
+
+		for _, r := range router.Routes() {
+			assert.True(t, declared[r.Method+" "+r.Path], "missing permissions for %s %s", r.Method, r.Path)
+		}
+	})
+
+	t.Run("Testing adding an attachment of a syllabus the user cannot edit", func(t *testing.T) {
+		tokens := mustLoginActors(t)
+		strangerAttID := "c55f0baf-12b8-4adb-b5e6-2280bee8ab20"
+
+		for _, actor := range []string{collaborator, owner, admin} {
+			body := bytes.NewBuffer([]byte(url.Values{"att_id": {strangerAttID}}.Encode()))
+			req := httptest.NewRequest(http.MethodPost, "/syllabi/"+syllID+"/attachments", body)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[actor]))
+
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+
+			if actor == admin {
+				assert.Equal(t, http.StatusOK, res.Code, "%s should be allowed", actor)
+			} else {
+				assert.Equal(t, http.StatusForbidden, res.Code, "%s should be forbidden", actor)
+			}
+		}
+	})
+
+	for _, p := range matrix {
+		t.Run(fmt.Sprintf("Testing permissions on %s %s", p.method, p.route), func(t *testing.T) {
+			// -- sessions are opened again for each route, since some of them revoke or delete accounts
+			tokens := mustLoginActors(t)
+
+			for _, actor := range actors {
+				body := bytes.NewBuffer([]byte(p.form.Encode()))
+				req := httptest.NewRequest(p.method, p.path, body)
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				if actor != anonymous {
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[actor]))
+				}
+
+				res := httptest.NewRecorder()
+				router.ServeHTTP(res, req)
+
+				switch {
+				case contains(p.allowed, actor):
+					assert.NotContains(t, []int{http.StatusUnauthorized, http.StatusForbidden}, res.Code, "%s should be allowed", actor)
+				case actor == anonymous:
+					assert.Equal(t, http.StatusUnauthorized, res.Code, "%s should be unauthorized", actor)
+				default:
+					assert.Equal(t, http.StatusForbidden, res.Code, "%s should be forbidden", actor)
+				}
+			}
+		})
+	}
+}
+
+// mustLoginActors returns an access token for each actor but the anonymous one
+func mustLoginActors(t *testing.T) map[string]string {
+	tokens := make(map[string]string)
+
+	for actor, email := range credentials {
+		data := url.Values{}
+		data.Add("email", email)
+		data.Add("password", "12345678")
+
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer([]byte(data.Encode())))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code, "%s could not log in", actor)
+
+		var session echo.Map
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &session))
+		tokens[actor] = fmt.Sprintf("%s", session["token"])
+	}
+
+	return tokens
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/throttle"
+	"github.com/labstack/echo/v4"
+)
+
+// LIMITS are the number of requests a single IP can make to each group of authentication endpoints
+var LIMITS = map[string]throttle.Rule{
+	"login":   {Requests: 30, Window: 15 * time.Minute},
+	"recover": {Requests: 10, Window: time.Hour},
+	"signup":  {Requests: 10, Window: time.Hour},
+}
+
+// RECOVER_LIMIT is the number of recovery emails that can be requested for a single account
+var RECOVER_LIMIT = throttle.Rule{Requests: 3, Window: time.Hour}
+
+// LOCKOUT slows down, then locks, logging into an account after repeated failures, whether of the password or of the second factor
+var LOCKOUT = throttle.Lockout{
+	Window:     15 * time.Minute,
+	DelayAfter: 3,
+	LockAfter:  10,
+	MaxDelay:   time.Minute,
+}
+
+var limiter throttle.Store
+
+// InitThrottle sets the store counting requests and failed logins. A nil store turns off all limits.
+func InitThrottle(store throttle.Store) {
+	limiter = store
+	LOCKOUT.Store = store
+}
+
+// Throttle applies the IP limit of the given group to a route
+func Throttle(name string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if limiter == nil {
+			return next
+		}
+		return throttle.Limit(limiter, name, LIMITS[name])(next)
+	}
+}
+
+func loginKey(email string) string {
+	return "login:account:" + strings.ToLower(email)
+}
+
+func twoFactorKey(user_uuid string) string {
+	return "two_factor:account:" + user_uuid
+}
+
+// checkLockout answers with a 429 if the account can't be logged into yet, and returns whether it did
+func checkLockout(c echo.Context, key string) (bool, error) {
+	if limiter == nil {
+		return false, nil
+	}
+
+	ok, until, err := LOCKOUT.Check(key)
+	if err != nil {
+		zero.Errorf("error checking lockout of %s: %v", key, err)
+	}
+	if ok {
+		return false, nil
+	}
+
+	zero.Warnf("too many failed logins for %s", key)
+	return true, throttle.TooManyRequests(c, until)
+}
+
+func failLogin(key string) {
+	if limiter == nil {
+		return
+	}
+
+	if err := LOCKOUT.Fail(key); err != nil {
+		zero.Errorf("error counting failed login for %s: %v", key, err)
+	}
+}
+
+func succeedLogin(key string) {
+	if limiter == nil {
+		return
+	}
+
+	if err := LOCKOUT.Succeed(key); err != nil {
+		zero.Errorf("error resetting failed logins for %s: %v", key, err)
+	}
+}
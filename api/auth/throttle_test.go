@@ -0,0 +1,91 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/auth"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/commonsyllabi/explorer/api/throttle"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottle(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	auth.InitThrottle(throttle.NewMemoryStore())
+	defer auth.InitThrottle(nil)
+
+	login := func(password string) int {
+		return postForm("/login", "", url.Values{"email": {"pat@shiu.com"}, "password": {password}}).Code
+	}
+
+	t.Run("Testing failed logins are delayed", func(t *testing.T) {
+		for i := 0; i < auth.LOCKOUT.DelayAfter; i++ {
+			require.Equal(t, http.StatusUnauthorized, login("wrong-password"))
+		}
+
+		res := postForm("/login", "", url.Values{"email": {"pat@shiu.com"}, "password": {"12345678"}})
+		assert.Equal(t, http.StatusTooManyRequests, res.Code)
+		assert.Equal(t, "1", res.Header().Get("Retry-After"))
+	})
+
+	t.Run("Testing delayed login succeeds after waiting", func(t *testing.T) {
+		time.Sleep(time.Second)
+		assert.Equal(t, http.StatusOK, login("12345678"))
+	})
+
+	t.Run("Testing successful login resets failures", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, login("wrong-password"))
+		assert.Equal(t, http.StatusOK, login("12345678"))
+	})
+
+	t.Run("Testing unknown accounts are delayed", func(t *testing.T) {
+		form := url.Values{"email": {"nobody@test.com"}, "password": {"12345678"}}
+		for i := 0; i < auth.LOCKOUT.DelayAfter; i++ {
+			require.Equal(t, http.StatusUnauthorized, postForm("/login", "", form).Code)
+		}
+
+		assert.Equal(t, http.StatusTooManyRequests, postForm("/login", "", form).Code)
+	})
+
+	t.Run("Testing wrong codes to disable two-factor are delayed", func(t *testing.T) {
+		user_uuid := uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c8a9")
+		secret, err := auth.GenerateTOTPSecret()
+		require.Nil(t, err)
+		require.Nil(t, models.SetTOTPSecret(user_uuid, secret))
+		recovery, err := models.EnableTwoFactor(user_uuid, 0)
+		require.Nil(t, err)
+
+		challenge := mustLoginChallenge(t, "pierre.depaz@gmail.com")
+		res := postForm("/auth/2fa/verify", "", url.Values{"challenge": {challenge.Challenge}, "code": {recovery[0]}})
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var session sessionResponse
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &session))
+
+		for i := 0; i < auth.LOCKOUT.DelayAfter; i++ {
+			require.Equal(t, http.StatusUnauthorized, postForm("/auth/2fa/disable", session.Token, url.Values{"code": {"not-a-code"}}).Code)
+		}
+
+		res = postForm("/auth/2fa/disable", session.Token, url.Values{"code": {recovery[1]}})
+		assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	})
+
+	t.Run("Testing recovery requests are limited per account", func(t *testing.T) {
+		form := url.Values{"email": {"auth-pending@test.com"}}
+		for i := 0; i < auth.RECOVER_LIMIT.Requests; i++ {
+			require.Equal(t, http.StatusOK, postForm("/auth/request-recover", "", form).Code)
+		}
+
+		res := postForm("/auth/request-recover", "", form)
+		assert.Equal(t, http.StatusTooManyRequests, res.Code)
+		assert.NotEmpty(t, res.Header().Get("Retry-After"))
+	})
+}
@@ -0,0 +1,74 @@
+package auth_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTP(t *testing.T) {
+	// -- the SHA1 secret of the RFC 6238 test vectors
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	t.Run("Test RFC 6238 vectors", func(t *testing.T) {
+		vectors := map[int64]string{
+			59:          "287082",
+			1111111109:  "081804",
+			1111111111:  "050471",
+			1234567890:  "005924",
+			2000000000:  "279037",
+			20000000000: "353130",
+		}
+
+		for ts, expected := range vectors {
+			code, err := auth.GenerateTOTPCode(secret, time.Unix(ts, 0))
+			require.Nil(t, err)
+			assert.Equal(t, expected, code, "at %d", ts)
+		}
+	})
+
+	t.Run("Test validate code with clock drift", func(t *testing.T) {
+		now := time.Unix(1111111111, 0)
+		previous, err := auth.GenerateTOTPCode(secret, now.Add(-30*time.Second))
+		require.Nil(t, err)
+
+		step, ok := auth.ValidateTOTPCode(secret, previous, now)
+		assert.True(t, ok)
+		assert.Equal(t, now.Unix()/30-1, step)
+	})
+
+	t.Run("Test reject stale code", func(t *testing.T) {
+		now := time.Unix(1111111111, 0)
+		stale, err := auth.GenerateTOTPCode(secret, now.Add(-2*time.Minute))
+		require.Nil(t, err)
+
+		_, ok := auth.ValidateTOTPCode(secret, stale, now)
+		assert.False(t, ok)
+	})
+
+	t.Run("Test reject malformed code", func(t *testing.T) {
+		_, ok := auth.ValidateTOTPCode(secret, "12345", time.Now())
+		assert.False(t, ok)
+
+		_, ok = auth.ValidateTOTPCode(secret, "abcdef", time.Now())
+		assert.False(t, ok)
+	})
+
+	t.Run("Test provisioning URI", func(t *testing.T) {
+		generated, err := auth.GenerateTOTPSecret()
+		require.Nil(t, err)
+
+		uri, err := url.Parse(auth.TOTPProvisioningURI(generated, "jus@pop.com"))
+		require.Nil(t, err)
+		assert.Equal(t, "otpauth", uri.Scheme)
+		assert.Equal(t, "totp", uri.Host)
+		assert.Equal(t, "/Cosyll:jus@pop.com", uri.Path)
+		assert.Equal(t, generated, uri.Query().Get("secret"))
+		assert.Equal(t, "Cosyll", uri.Query().Get("issuer"))
+	})
+}
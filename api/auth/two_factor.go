@@ -0,0 +1,276 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// PurposeTwoFactor is the purpose of challenges answered with a code of the second factor
+	PurposeTwoFactor = "two_factor"
+	// PurposeEnrollment is the purpose of challenges given to users who must set up a second factor before logging in
+	PurposeEnrollment = "enrollment"
+)
+
+// challengeTTL is how long users have to complete the second step of a login
+const challengeTTL = 5 * time.Minute
+
+var errChallengeInvalid = errors.New("the challenge is invalid or has expired")
+
+// completeLogin is the last step of every login once the user has proven who they are. Users with a second factor get a challenge
+// to answer with a code, and users required to have one get a challenge to set it up. Everyone else gets a session.
+func completeLogin(c echo.Context, user models.User) error {
+	purpose := ""
+	if user.TwoFactorEnabled {
+		purpose = PurposeTwoFactor
+	} else if user.TwoFactorRequired {
+		purpose = PurposeEnrollment
+	}
+
+	if purpose == "" {
+		return issueTokens(c, user)
+	}
+
+	challenge, expires, err := signChallenge(user, purpose)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "Authentication failed")
+	}
+
+	return c.JSON(http.StatusAccepted, echo.Map{
+		"challenge":  challenge,
+		"purpose":    purpose,
+		"expires_at": expires,
+	})
+}
+
+// signChallenge returns a short-lived token which can only be exchanged for a session through the second step of the login
+func signChallenge(user models.User, purpose string) (string, time.Time, error) {
+	expires := time.Now().Add(challengeTTL)
+	claims := &JWTCustomClaims{
+		UUID:           user.UUID.String(),
+		SessionVersion: user.SessionVersion,
+		Purpose:        purpose,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expires.Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+	}
+
+	kr, err := getKeyring()
+	if err != nil {
+		return "", expires, err
+	}
+
+	t, err := kr.Sign(claims)
+	return t, expires, err
+}
+
+// parseChallenge verifies a challenge of the given purpose, and returns its user
+func parseChallenge(challenge string, purpose string) (models.User, error) {
+	var user models.User
+	kr, err := getKeyring()
+	if err != nil {
+		return user, err
+	}
+
+	claims := &JWTCustomClaims{}
+	token, err := kr.Parse(challenge, claims)
+	if err != nil || !token.Valid || claims.Purpose != purpose {
+		return user, errChallengeInvalid
+	}
+
+	id, err := uuid.Parse(claims.UUID)
+	if err != nil {
+		return user, errChallengeInvalid
+	}
+
+	user, err = models.GetUser(id, id)
+	if err != nil {
+		return user, err
+	}
+
+	if user.Status != models.UserConfirmed || claims.SessionVersion < user.SessionVersion {
+		return user, errChallengeInvalid
+	}
+
+	return user, nil
+}
+
+// VerifyTwoFactor is the second step of the login, exchanging a challenge and a code of the authenticator app, or a recovery code, for a session
+func VerifyTwoFactor(c echo.Context) error {
+	challenge, code := c.FormValue("challenge"), c.FormValue("code")
+	if strings.TrimSpace(challenge) == "" || strings.TrimSpace(code) == "" {
+		return c.String(http.StatusBadRequest, "Parameters can't be empty")
+	}
+
+	user, err := parseChallenge(challenge, PurposeTwoFactor)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusUnauthorized, "Your login has expired, please log in again.")
+	}
+
+	key := twoFactorKey(user.UUID.String())
+	if locked, err := checkLockout(c, key); locked {
+		return err
+	}
+
+	err = checkSecondFactor(user, code)
+	if err != nil {
+		zero.Error(err.Error())
+		failLogin(key)
+		return c.String(http.StatusUnauthorized, "Authentication failed")
+	}
+
+	succeedLogin(key)
+	return issueTokens(c, user)
+}
+
+// EnrollTwoFactor generates a new secret for the user, to be registered in an authenticator app with the provisioning URI.
+// The second factor is only enabled by ActivateTwoFactor. Users who must enroll before logging in authenticate with their enrollment challenge.
+func EnrollTwoFactor(c echo.Context) error {
+	user, err := enrollingUser(c)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if user.TwoFactorEnabled {
+		return c.String(http.StatusConflict, "Two-factor authentication is already enabled.")
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error setting up two-factor authentication.")
+	}
+
+	err = models.SetTOTPSecret(user.UUID, secret)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error setting up two-factor authentication.")
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"secret": secret,
+		"uri":    TOTPProvisioningURI(secret, user.Email),
+	})
+}
+
+// ActivateTwoFactor enables the second factor once the user has shown a valid code. It answers with the recovery codes, which are never shown again,
+// and with a new session since all the existing ones are revoked.
+func ActivateTwoFactor(c echo.Context) error {
+	user, err := enrollingUser(c)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if user.TwoFactorEnabled {
+		return c.String(http.StatusConflict, "Two-factor authentication is already enabled.")
+	}
+
+	if user.TOTPSecret == "" {
+		return c.String(http.StatusBadRequest, "Two-factor authentication has not been set up.")
+	}
+
+	step, ok := ValidateTOTPCode(user.TOTPSecret, c.FormValue("code"), time.Now())
+	if !ok {
+		return c.String(http.StatusBadRequest, "The code is not valid.")
+	}
+
+	codes, err := models.EnableTwoFactor(user.UUID, step)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error enabling two-factor authentication.")
+	}
+
+	user, err = models.GetUser(user.UUID, user.UUID)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error enabling two-factor authentication.")
+	}
+
+	session, err := newSession(user)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error enabling two-factor authentication.")
+	}
+	session["recovery_codes"] = codes
+
+	return c.JSON(http.StatusOK, session)
+}
+
+// DisableTwoFactor removes the second factor of the authenticated user, who must show a valid code to do so.
+// Users required by an administrator to have a second factor can't remove it.
+func DisableTwoFactor(c echo.Context) error {
+	user_uuid, _ := c.Get("user_uuid").(uuid.UUID)
+	user, err := models.GetUser(user_uuid, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "The user account was not found.")
+	}
+
+	if !user.TwoFactorEnabled {
+		return c.String(http.StatusBadRequest, "Two-factor authentication is not enabled.")
+	}
+
+	if user.TwoFactorRequired {
+		return c.String(http.StatusForbidden, "Two-factor authentication is required for your account.")
+	}
+
+	// -- the code is guessed against the same lockout as at login, so that a stolen session can't brute force it
+	key := twoFactorKey(user.UUID.String())
+	if locked, err := checkLockout(c, key); locked {
+		return err
+	}
+
+	err = checkSecondFactor(user, c.FormValue("code"))
+	if err != nil {
+		zero.Error(err.Error())
+		failLogin(key)
+		return c.String(http.StatusUnauthorized, "The code is not valid.")
+	}
+
+	succeedLogin(key)
+	err = models.DisableTwoFactor(user.UUID)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error disabling two-factor authentication.")
+	}
+
+	return c.String(http.StatusOK, "two-factor authentication disabled")
+}
+
+// checkSecondFactor accepts either a code of the authenticator app, which can't be used twice, or an unused recovery code
+func checkSecondFactor(user models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if step, ok := ValidateTOTPCode(user.TOTPSecret, code, time.Now()); ok {
+		return models.UseTOTPStep(user.UUID, step)
+	}
+
+	if len(code) == totpDigits {
+		return fmt.Errorf("invalid authentication code for user %s", user.UUID)
+	}
+
+	return models.RedeemRecoveryCode(user.UUID, code)
+}
+
+// enrollingUser is either the authenticated user, or the user of an enrollment challenge
+func enrollingUser(c echo.Context) (models.User, error) {
+	user_uuid, _ := c.Get("user_uuid").(uuid.UUID)
+	if user_uuid != uuid.Nil {
+		return models.GetUser(user_uuid, user_uuid)
+	}
+
+	return parseChallenge(c.FormValue("challenge"), PurposeEnrollment)
+}
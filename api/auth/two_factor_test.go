@@ -0,0 +1,201 @@
+package auth_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/auth"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type challengeResponse struct {
+	Challenge string `json:"challenge"`
+	Purpose   string `json:"purpose"`
+}
+
+type sessionResponse struct {
+	User          models.User `json:"user"`
+	Token         string      `json:"token"`
+	RecoveryCodes []string    `json:"recovery_codes"`
+}
+
+func TestTwoFactor(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	var secret string
+	var recovery []string
+
+	t.Run("Testing enroll", func(t *testing.T) {
+		session := mustLogin(t, "jus@pop.com")
+
+		res := postForm("/auth/2fa/enroll", session.Token, nil)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var enrollment struct {
+			Secret string `json:"secret"`
+			URI    string `json:"uri"`
+		}
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &enrollment))
+		assert.NotEmpty(t, enrollment.Secret)
+		assert.Contains(t, enrollment.URI, "otpauth://totp/")
+		secret = enrollment.Secret
+
+		res = postForm("/auth/2fa/activate", session.Token, url.Values{"code": {"000000"}})
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+
+		code, err := auth.GenerateTOTPCode(secret, time.Now())
+		require.Nil(t, err)
+		res = postForm("/auth/2fa/activate", session.Token, url.Values{"code": {code}})
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var activated sessionResponse
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &activated))
+		assert.True(t, activated.User.TwoFactorEnabled)
+		assert.Equal(t, 10, len(activated.RecoveryCodes))
+		assert.NotEmpty(t, activated.Token)
+		recovery = activated.RecoveryCodes
+
+		// -- the sessions opened without the second factor are revoked
+		res = postForm("/auth/logout-all", session.Token, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("Testing login requires the second factor", func(t *testing.T) {
+		challenge := mustLoginChallenge(t, "jus@pop.com")
+		assert.Equal(t, auth.PurposeTwoFactor, challenge.Purpose)
+
+		// -- the challenge is not an access token
+		res := postForm("/auth/logout-all", challenge.Challenge, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+		res = postForm("/auth/2fa/verify", "", url.Values{"challenge": {challenge.Challenge}, "code": {"000000"}})
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+		// -- the code used to activate can't be used again, so the next one is used
+		code, err := auth.GenerateTOTPCode(secret, time.Now().Add(30*time.Second))
+		require.Nil(t, err)
+		res = postForm("/auth/2fa/verify", "", url.Values{"challenge": {challenge.Challenge}, "code": {code}})
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var session sessionResponse
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &session))
+		assert.NotEmpty(t, session.Token)
+
+		res = postForm("/auth/2fa/verify", "", url.Values{"challenge": {challenge.Challenge}, "code": {code}})
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("Testing login with recovery code", func(t *testing.T) {
+		challenge := mustLoginChallenge(t, "jus@pop.com")
+
+		res := postForm("/auth/2fa/verify", "", url.Values{"challenge": {challenge.Challenge}, "code": {recovery[0]}})
+		require.Equal(t, http.StatusOK, res.Code)
+
+		res = postForm("/auth/2fa/verify", "", url.Values{"challenge": {challenge.Challenge}, "code": {recovery[0]}})
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+
+	t.Run("Testing disable", func(t *testing.T) {
+		challenge := mustLoginChallenge(t, "jus@pop.com")
+		res := postForm("/auth/2fa/verify", "", url.Values{"challenge": {challenge.Challenge}, "code": {recovery[1]}})
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var session sessionResponse
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &session))
+
+		res = postForm("/auth/2fa/disable", session.Token, url.Values{"code": {"not-a-code"}})
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+		res = postForm("/auth/2fa/disable", session.Token, url.Values{"code": {recovery[2]}})
+		require.Equal(t, http.StatusOK, res.Code)
+
+		mustLogin(t, "jus@pop.com")
+	})
+
+	t.Run("Testing admin requires two-factor", func(t *testing.T) {
+		admin := mustLogin(t, "pat@shiu.com")
+		pierre := mustLogin(t, "pierre.depaz@gmail.com")
+
+		res := patchForm("/admin/users/e7b74bcd-c864-41ee-b5a7-d3031f76c8a9", admin.Token, url.Values{"two_factor_required": {"maybe"}})
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+
+		res = patchForm("/admin/users/e7b74bcd-c864-41ee-b5a7-d3031f76c8a9", admin.Token, url.Values{"two_factor_required": {"true"}})
+		require.Equal(t, http.StatusOK, res.Code)
+
+		res = postForm("/auth/logout-all", pierre.Token, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+		challenge := mustLoginChallenge(t, "pierre.depaz@gmail.com")
+		assert.Equal(t, auth.PurposeEnrollment, challenge.Purpose)
+
+		res = postForm("/auth/2fa/verify", "", url.Values{"challenge": {challenge.Challenge}, "code": {"000000"}})
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+		res = postForm("/auth/2fa/enroll", "", url.Values{"challenge": {challenge.Challenge}})
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var enrollment struct {
+			Secret string `json:"secret"`
+		}
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &enrollment))
+
+		code, err := auth.GenerateTOTPCode(enrollment.Secret, time.Now())
+		require.Nil(t, err)
+		res = postForm("/auth/2fa/activate", "", url.Values{"challenge": {challenge.Challenge}, "code": {code}})
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var session sessionResponse
+		require.Nil(t, json.Unmarshal(res.Body.Bytes(), &session))
+		assert.True(t, session.User.TwoFactorRequired)
+
+		res = postForm("/auth/2fa/disable", session.Token, url.Values{"code": {session.RecoveryCodes[0]}})
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	})
+}
+
+func mustLogin(t *testing.T, email string) sessionResponse {
+	res := postForm("/login", "", url.Values{"email": {email}, "password": {"12345678"}})
+	require.Equal(t, http.StatusOK, res.Code, "%s could not log in", email)
+
+	var session sessionResponse
+	require.Nil(t, json.Unmarshal(res.Body.Bytes(), &session))
+	return session
+}
+
+func mustLoginChallenge(t *testing.T, email string) challengeResponse {
+	res := postForm("/login", "", url.Values{"email": {email}, "password": {"12345678"}})
+	require.Equal(t, http.StatusAccepted, res.Code, "%s was not challenged", email)
+
+	var challenge challengeResponse
+	require.Nil(t, json.Unmarshal(res.Body.Bytes(), &challenge))
+	return challenge
+}
+
+func postForm(path string, token string, form url.Values) *httptest.ResponseRecorder {
+	return sendForm(http.MethodPost, path, token, form)
+}
+
+func patchForm(path string, token string, form url.Values) *httptest.ResponseRecorder {
+	return sendForm(http.MethodPatch, path, token, form)
+}
+
+func sendForm(method string, path string, token string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBuffer([]byte(form.Encode())))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
@@ -1,40 +1,185 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
 // Config holds port numbers, target directories
 type Config struct {
-	PublicDir    string `yaml:"public_dir"`
-	TemplatesDir string `yaml:"templates_dir"`
-	FixturesDir  string `yaml:"fixtures_dir"`
-	UploadsDir   string `yaml:"uploads_dir"`
+	PublicDir    string          `yaml:"public_dir"`
+	TemplatesDir string          `yaml:"templates_dir"`
+	FixturesDir  string          `yaml:"fixtures_dir"`
+	UploadsDir   string          `yaml:"uploads_dir"`
+	JWT          JWTConfig       `yaml:"jwt"`
+	OIDC         []OIDCProvider  `yaml:"oidc"`
+	RateLimit    RateLimitConfig `yaml:"rate_limit"`
 }
 
-// DefaultConf is called if there is an error opening and parsing the config file
-func (c *Config) DefaultConf() {
+// JWTConfig holds the keys used to sign and verify authentication tokens. The current key signs every new token,
+// while retired keys are only accepted for verification until their grace period runs out.
+type JWTConfig struct {
+	KeyID       string        `yaml:"key_id"`
+	Secret      string        `yaml:"secret"`
+	RetiredKeys []RetiredKey  `yaml:"retired_keys"`
+	Grace       time.Duration `yaml:"grace"`
+	AccessTTL   time.Duration `yaml:"access_ttl"`
+	RefreshTTL  time.Duration `yaml:"refresh_ttl"`
+}
+
+// RetiredKey is a former signing key, kept around so that tokens it signed remain valid after a rotation
+type RetiredKey struct {
+	KeyID     string    `yaml:"key_id"`
+	Secret    string    `yaml:"secret"`
+	RetiredAt time.Time `yaml:"retired_at"`
+}
+
+// OIDCProvider is an OpenID Connect identity provider, usually a partner university, that users can log in with.
+// Its endpoints are discovered from the issuer. InstitutionClaim names the ID token claim holding the institutions of the user, if any.
+type OIDCProvider struct {
+	Name             string   `yaml:"name"`
+	Issuer           string   `yaml:"issuer"`
+	ClientID         string   `yaml:"client_id"`
+	ClientSecret     string   `yaml:"client_secret"`
+	RedirectURL      string   `yaml:"redirect_url"`
+	Scopes           []string `yaml:"scopes"`
+	InstitutionClaim string   `yaml:"institution_claim"`
+}
+
+// RateLimitConfig turns on the rate limits of the authentication endpoints. Store is either memory, which only counts
+// the requests made to this instance, or postgres, which shares the counts between all instances.
+// TrustedProxies are the ranges of the proxies in front of the API, in CIDR notation, whose X-Forwarded-For headers are trusted.
+// Without any, clients are told by the address they connect from.
+type RateLimitConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	Store          string   `yaml:"store"`
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// DefaultConf sets sensible defaults, which the config file then overrides. It fails if the environment holds malformed keys.
+func (c *Config) DefaultConf() error {
 	c.PublicDir = "./www/public"
 	c.TemplatesDir = "./api/templates"
 	c.UploadsDir = "/tmp/explorer/uploads"
+	c.JWT.KeyID = "default"
+	c.JWT.Grace = 72 * time.Hour
+	c.JWT.AccessTTL = 15 * time.Minute
+	c.JWT.RefreshTTL = 30 * 24 * time.Hour
+	c.RateLimit.Enabled = true
+	c.RateLimit.Store = "memory"
+
+	return c.loadEnv()
 }
 
-// LoadConf tries to load a yaml file from disk, and marshals it. Sensible defaults are provided, and loading a file overrides them
+// LoadConf tries to load a yaml file from disk, and marshals it. Sensible defaults are provided, and loading a file overrides
+// the ones it sets. If the file can't be read or parsed, only the defaults are kept.
 func (c *Config) LoadConf(path string) error {
+	if err := c.DefaultConf(); err != nil {
+		return err
+	}
+
 	cwd, _ := os.Getwd()
 	content, err := os.ReadFile(filepath.Join(cwd, path))
 	if err != nil {
-		c.DefaultConf()
 		return err
 	}
 
-	err = yaml.Unmarshal(content, &c)
+	err = yaml.Unmarshal(content, c)
 	if err != nil {
-		c.DefaultConf()
+		*c = Config{}
+		if env_err := c.DefaultConf(); env_err != nil {
+			return env_err
+		}
 		return err
 	}
+
+	return c.loadEnv()
+}
+
+// loadEnv overrides the signing keys with the JWT_ environment variables, if they are set.
+// JWT_RETIRED_KEYS is a comma-separated list of key_id:secret:retired_at, with retired_at as YYYY-MM-DD.
+// The client secret of each OIDC provider can be set with OIDC_<NAME>_CLIENT_SECRET.
+// RATE_LIMIT_STORE picks the store of the rate limits, and RATE_LIMIT_TRUSTED_PROXIES is a comma-separated list of proxy ranges.
+func (c *Config) loadEnv() error {
+	if s := os.Getenv("RATE_LIMIT_STORE"); s != "" {
+		c.RateLimit.Store = s
+	}
+
+	if s := os.Getenv("RATE_LIMIT_TRUSTED_PROXIES"); s != "" {
+		c.RateLimit.TrustedProxies = strings.Split(s, ",")
+	}
+
+	for i, p := range c.OIDC {
+		if s := os.Getenv(fmt.Sprintf("OIDC_%s_CLIENT_SECRET", strings.ToUpper(p.Name))); s != "" {
+			c.OIDC[i].ClientSecret = s
+		}
+	}
+
+	if s := os.Getenv("JWT_SECRET"); s != "" {
+		c.JWT.Secret = s
+	}
+
+	if id := os.Getenv("JWT_KEY_ID"); id != "" {
+		c.JWT.KeyID = id
+	}
+
+	if g, err := time.ParseDuration(os.Getenv("JWT_GRACE")); err == nil {
+		c.JWT.Grace = g
+	}
+
+	if ttl, err := time.ParseDuration(os.Getenv("JWT_ACCESS_TTL")); err == nil {
+		c.JWT.AccessTTL = ttl
+	}
+
+	if ttl, err := time.ParseDuration(os.Getenv("JWT_REFRESH_TTL")); err == nil {
+		c.JWT.RefreshTTL = ttl
+	}
+
+	if raw := os.Getenv("JWT_RETIRED_KEYS"); raw != "" {
+		keys, err := ParseRetiredKeys(raw)
+		if err != nil {
+			return err
+		}
+		c.JWT.RetiredKeys = keys
+	}
+
 	return nil
 }
+
+// ParseRetiredKeys reads a comma-separated list of key_id:secret:retired_at. The secret is what lies between the first and
+// the last colon, so that it can hold colons itself. A malformed key is an error rather than skipped, since dropping it
+// would invalidate the tokens it signed.
+func ParseRetiredKeys(raw string) ([]RetiredKey, error) {
+	keys := make([]RetiredKey, 0)
+	for _, k := range strings.Split(raw, ",") {
+		k = strings.TrimSpace(k)
+
+		frags := strings.SplitN(k, ":", 2)
+		last := strings.LastIndex(k, ":")
+		if len(frags) != 2 || frags[0] == "" || last <= len(frags[0]) {
+			return keys, fmt.Errorf("malformed retired JWT key %q: expected key_id:secret:retired_at", frags[0])
+		}
+
+		secret := k[len(frags[0])+1 : last]
+		if secret == "" {
+			return keys, fmt.Errorf("retired JWT key %s has an empty secret", frags[0])
+		}
+
+		retired, err := time.Parse("2006-01-02", k[last+1:])
+		if err != nil {
+			return keys, fmt.Errorf("retired JWT key %s has a malformed date: %w", frags[0], err)
+		}
+
+		keys = append(keys, RetiredKey{
+			KeyID:     frags[0],
+			Secret:    secret,
+			RetiredAt: retired,
+		})
+	}
+	return keys, nil
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// GetAdminOverview returns the first page of all the syllabi, collections and accounts, whatever their status, and is only accessible
+// to administrators. Each listing is continued from its next cursor with GetAdminSyllabi, GetAdminCollections and GetAllUsers.
+func GetAdminOverview(c echo.Context) error {
+	page, err := parsePage(c)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	// -- a cursor only points into the listing it was returned with
+	page.Cursor = ""
+
+	syll, syll_meta, err := models.GetAdminSyllabi(page)
+	if err != nil {
+		return adminListingError(c, err)
+	}
+
+	coll, coll_meta, err := models.GetAdminCollections(page)
+	if err != nil {
+		return adminListingError(c, err)
+	}
+
+	users, users_meta, err := models.GetAllUsers(page)
+	if err != nil {
+		return adminListingError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"syllabi":     syll,
+		"collections": coll,
+		"users":       users,
+		"meta":        echo.Map{"syllabi": syll_meta, "collections": coll_meta, "users": users_meta},
+	})
+}
+
+// GetAdminSyllabi returns a page of all the syllabi, including the unlisted and moderated ones
+func GetAdminSyllabi(c echo.Context) error {
+	page, err := parsePage(c)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	syllabi, meta, err := models.GetAdminSyllabi(page)
+	if err != nil {
+		return adminListingError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"syllabi": syllabi, "meta": meta})
+}
+
+// GetAdminCollections returns a page of all the collections, including the unlisted and moderated ones
+func GetAdminCollections(c echo.Context) error {
+	page, err := parsePage(c)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	coll, meta, err := models.GetAdminCollections(page)
+	if err != nil {
+		return adminListingError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"collections": coll, "meta": meta})
+}
+
+func adminListingError(c echo.Context, err error) error {
+	zero.Error(err.Error())
+	if errors.Is(err, models.ErrInvalidPage) {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	return c.String(http.StatusInternalServerError, err.Error())
+}
+
+// AdminUpdateUser sets the role and the status of any account, and whether it requires two-factor authentication. Any of them can be omitted.
+func AdminUpdateUser(c echo.Context) error {
+	id := c.Param("id")
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, "Not a valid ID.")
+	}
+
+	var account struct {
+		Role              string `json:"role" form:"role"`
+		Status            string `json:"status" form:"status"`
+		TwoFactorRequired string `json:"two_factor_required" form:"two_factor_required"`
+	}
+	err = c.Bind(&account)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, "There was an error getting the update data.")
+	}
+
+	user_uuid := mustGetUser(c)
+
+	if uid == user_uuid && account.Role != "" && account.Role != models.RoleAdmin {
+		return c.String(http.StatusBadRequest, "You cannot remove your own admin role.")
+	}
+
+	required, err := strconv.ParseBool(account.TwoFactorRequired)
+	if account.TwoFactorRequired != "" && err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, "Not a valid two-factor requirement.")
+	}
+
+	updated, err := models.UpdateUserAccount(uid, account.Role, account.Status)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidAccount) {
+			return c.String(http.StatusBadRequest, "Not a valid role or status.")
+		}
+		return c.String(http.StatusNotFound, "We could not find the requested user.")
+	}
+
+	if account.TwoFactorRequired != "" {
+		updated, err = models.SetTwoFactorRequired(uid, required)
+		if err != nil {
+			zero.Error(err.Error())
+			return c.String(http.StatusNotFound, "We could not find the requested user.")
+		}
+	}
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// AdminDeleteUser deletes any account, along with its content
+func AdminDeleteUser(c echo.Context) error {
+	id := c.Param("id")
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, "Not a valid ID.")
+	}
+
+	user, err := models.DeleteUser(uid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "Error finding the user to delete.")
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// UnlistSyllabus hides any syllabus from the public listings
+func UnlistSyllabus(c echo.Context) error {
+	id := c.Param("id")
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, "Not a valid ID.")
+	}
+
+	syll, err := models.UnlistSyllabus(uid, mustGetUser(c))
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "We could not find the requested syllabus.")
+	}
+
+	return c.JSON(http.StatusOK, syll)
+}
+
+// UnlistCollection hides any collection from the public listings
+func UnlistCollection(c echo.Context) error {
+	id := c.Param("id")
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, "Not a valid ID.")
+	}
+
+	coll, err := models.UnlistCollection(uid, mustGetUser(c))
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "We could not find the requested collection.")
+	}
+
+	return c.JSON(http.StatusOK, coll)
+}
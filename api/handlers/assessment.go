@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// GetAssessments lists the assessments of a syllabus, in order
+func GetAssessments(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
+	}
+
+	assessments, err := models.GetAssessments(uid, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "There was an error getting the assessments of the Syllabus.")
+	}
+
+	return c.JSON(http.StatusOK, assessments)
+}
+
+// UpdateAssessments replaces the assessments of a syllabus with the ones in the form
+func UpdateAssessments(c echo.Context) error {
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
+	}
+
+	assessments, err := parseAssessments(c)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	updated, err := models.UpdateAssessments(uid, assessments)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidAssessment) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusNotFound, "There was an error updating the assessments of the Syllabus.")
+	}
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// GetAssessmentMix reports the typical assessment of the syllabi in each of the fields, or in each field of the level
+func GetAssessmentMix(c echo.Context) error {
+	fields, err := models.ParseFields(c.QueryParams())
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, "Error parsing the academic fields.")
+	}
+
+	mixes, err := models.GetAssessmentMix(fields, strings.TrimSpace(c.QueryParam("level")))
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidAssessment) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusInternalServerError, "There was an error getting the assessment mix.")
+	}
+
+	return c.JSON(http.StatusOK, mixes)
+}
+
+// parseAssessments reads the assessments in the form, as lists of their types, weights, due weeks and descriptions in the
+// same order. Weights and due weeks can be left empty, and an assessment without a type nor a description is skipped, so
+// that all of them can be removed.
+func parseAssessments(c echo.Context) ([]models.Assessment, error) {
+	assessments := make([]models.Assessment, 0)
+
+	form, err := c.FormParams()
+	if err != nil {
+		return assessments, fmt.Errorf("there was an error parsing your parameters: %w", err)
+	}
+
+	types := form["type[]"]
+	weights := form["weight[]"]
+	weeks := form["due_week[]"]
+	descriptions := form["description[]"]
+
+	if len(weights) > len(types) || len(weeks) > len(types) || len(descriptions) > len(types) {
+		return assessments, errors.New("each assessment should have a type")
+	}
+
+	at := func(list []string, i int) string {
+		if i < len(list) {
+			return strings.TrimSpace(list[i])
+		}
+		return ""
+	}
+
+	for i := range types {
+		a := models.Assessment{
+			Type:        strings.ToLower(at(types, i)),
+			Description: at(descriptions, i),
+		}
+		if a.Type == "" && a.Description == "" {
+			continue
+		}
+
+		if raw := at(weights, i); raw != "" {
+			weight, err := strconv.ParseFloat(strings.TrimSuffix(raw, "%"), 64)
+			if err != nil {
+				return assessments, fmt.Errorf("the weight should be a number, given '%s'", raw)
+			}
+			a.Weight = &weight
+		}
+
+		if raw := at(weeks, i); raw != "" {
+			week, err := strconv.Atoi(raw)
+			if err != nil {
+				return assessments, fmt.Errorf("the due week should be a number, given '%s'", raw)
+			}
+			a.DueWeek = &week
+		}
+
+		assessments = append(assessments, a)
+	}
+
+	return assessments, nil
+}
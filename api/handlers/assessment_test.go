@@ -0,0 +1,126 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/handlers"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssessmentHandler(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	t.Run("Test update assessments", func(t *testing.T) {
+		f := make(url.Values)
+		f["type[]"] = []string{"project", "Essay"}
+		f["weight[]"] = []string{"70", "30%"}
+		f["due_week[]"] = []string{"12", ""}
+		f["description[]"] = []string{"Neighbourhood map", ""}
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi/:id/assessments")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.UpdateAssessments(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var assessments []models.Assessment
+		err := json.Unmarshal(res.Body.Bytes(), &assessments)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(assessments))
+		assert.Equal(t, models.AssessmentEssay, assessments[1].Type)
+		require.NotNil(t, assessments[0].DueWeek)
+		assert.Equal(t, 12, *assessments[0].DueWeek)
+		assert.Nil(t, assessments[1].DueWeek)
+	})
+
+	t.Run("Test update assessments malformed", func(t *testing.T) {
+		for _, form := range []url.Values{
+			{"type[]": {"exam"}, "weight[]": {"sixty"}},
+			{"type[]": {"exam"}, "due_week[]": {"last"}},
+			{"type[]": {"exam"}, "description[]": {"Final", "Midterm"}},
+			{"type[]": {"exam", "essay"}, "weight[]": {"60", "30"}},
+			{"type[]": {"thesis"}},
+		} {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(form.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			c := newContext(req, res, userID)
+			c.SetPath("/syllabi/:id/assessments")
+			c.SetParamNames("id")
+			c.SetParamValues(syllabusID.String())
+
+			handlers.UpdateAssessments(c)
+			assert.Equal(t, http.StatusBadRequest, res.Code, form.Encode())
+		}
+	})
+
+	t.Run("Test get assessments", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi/:id/assessments")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.GetAssessments(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var assessments []models.Assessment
+		err := json.Unmarshal(res.Body.Bytes(), &assessments)
+		require.Nil(t, err)
+		assert.Equal(t, 2, len(assessments))
+	})
+
+	t.Run("Test get assessments unlisted", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		c := newContext(req, res, userDeleteID)
+		c.SetPath("/syllabi/:id/assessments")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusOtherID.String())
+
+		handlers.GetAssessments(c)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("Test get assessment mix", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/?fields=200&subfields=true&level=narrow", nil)
+		c := newContext(req, res, userID)
+		c.SetPath("/assessments/mix")
+
+		handlers.GetAssessmentMix(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var mixes []models.AssessmentMix
+		err := json.Unmarshal(res.Body.Bytes(), &mixes)
+		require.Nil(t, err)
+		assert.NotEmpty(t, mixes)
+	})
+
+	t.Run("Test get assessment mix malformed", func(t *testing.T) {
+		for _, query := range []string{"?fields=abc", "?fields=9999", "?level=subfield"} {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/"+query, nil)
+			c := newContext(req, res, userID)
+			c.SetPath("/assessments/mix")
+
+			handlers.GetAssessmentMix(c)
+			assert.Equal(t, http.StatusBadRequest, res.Code, query)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -22,21 +23,28 @@ import (
 )
 
 func GetAllAttachments(c echo.Context) error {
-	attachments, err := models.GetAllAttachments()
+	// authenticating to return the attachments of unlisted but owned syllabi
+	user_uuid := mustGetUser(c)
+
+	page, err := parsePage(c)
 	if err != nil {
 		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	attachments, meta, err := models.GetAllAttachments(page, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidPage) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
-	return c.JSON(http.StatusOK, attachments)
+	return c.JSON(http.StatusOK, echo.Map{"attachments": attachments, "meta": meta})
 }
 
 func CreateAttachment(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	conf, ok := c.Get("config").(config.Config)
 	if !ok {
 		zero.Error("Could not parse configuration from context")
@@ -169,7 +177,7 @@ func CreateAttachment(c echo.Context) error {
 		}
 	}
 
-	created, err := models.CreateAttachment(syll_id, &att, user_uuid)
+	created, err := models.CreateAttachment(syll_id, &att)
 	if err != nil {
 		zero.Errorf("error creating Attachment: %v", err)
 		return c.String(http.StatusInternalServerError, "Error linking the attachment to the syllabus.")
@@ -179,11 +187,6 @@ func CreateAttachment(c echo.Context) error {
 }
 
 func UpdateAttachment(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	conf, ok := c.Get("config").(config.Config)
 	if !ok {
 		zero.Error("Could not parse configuration from context")
@@ -273,7 +276,7 @@ func UpdateAttachment(c echo.Context) error {
 		}
 	}
 
-	updated, err := models.UpdateAttachment(uid, user_uuid, &att)
+	updated, err := models.UpdateAttachment(uid, &att)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusInternalServerError, "Failed to update attachment, please try again later")
@@ -308,11 +311,6 @@ func GetAttachment(c echo.Context) error {
 }
 
 func DeleteAttachment(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	id := c.Param("id")
 	uid, err := uuid.Parse(id)
 	if err != nil {
@@ -320,7 +318,7 @@ func DeleteAttachment(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "Not a valid ID")
 	}
 
-	att, err := models.DeleteAttachment(uid, user_uuid)
+	att, err := models.DeleteAttachment(uid)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusNotFound, "There was an error deleting the attachments.")
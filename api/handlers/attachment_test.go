@@ -28,16 +28,21 @@ func TestAttachmentHandler(t *testing.T) {
 
 	t.Run("Test get all attachments", func(t *testing.T) {
 		res := httptest.NewRecorder()
-		req := httptest.NewRequest(http.MethodGet, "/attachments", nil)
-		c := echo.New().NewContext(req, res)
+		req := httptest.NewRequest(http.MethodGet, "/attachments?page_size=100", nil)
+		c := newContext(req, res, userID)
 
 		handlers.GetAllAttachments(c)
 		assert.Equal(t, http.StatusOK, res.Code)
 
-		atts := make([]models.Attachment, 0)
-		err := json.Unmarshal(res.Body.Bytes(), &atts)
+		var resp struct {
+			Attachments []models.Attachment `json:"attachments"`
+			Meta        models.PageMeta     `json:"meta"`
+		}
+		err := json.Unmarshal(res.Body.Bytes(), &resp)
 		require.Nil(t, err)
-		assert.Equal(t, 23, len(atts))
+		assert.Equal(t, 22, len(resp.Attachments))
+		assert.Equal(t, int64(22), resp.Meta.Total)
+		assert.Empty(t, resp.Meta.NextCursor)
 	})
 
 	t.Run("Test create attachment with file", func(t *testing.T) {
@@ -56,7 +61,7 @@ func TestAttachmentHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/attachments?"+q.Encode(), body)
 		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType()) // <<< important part
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.Set("config", conf)
 
 		handlers.CreateAttachment(c)
@@ -82,7 +87,7 @@ func TestAttachmentHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/attachments?"+q.Encode(), strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.Set("config", conf)
 
 		handlers.CreateAttachment(c)
@@ -104,7 +109,7 @@ func TestAttachmentHandler(t *testing.T) {
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/attachments?"+q.Encode(), strings.NewReader(f.Encode()))
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.Set("config", conf)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
@@ -121,7 +126,7 @@ func TestAttachmentHandler(t *testing.T) {
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/attachments?"+q.Encode(), strings.NewReader(f.Encode()))
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.Set("config", conf)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
@@ -134,7 +139,7 @@ func TestAttachmentHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/attachments", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetParamNames("id")
 		c.SetParamValues(attachmentID.String())
 
@@ -154,7 +159,7 @@ func TestAttachmentHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/attachments", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetParamNames("id")
 		c.SetParamValues(attachmentSlug)
 
@@ -174,7 +179,7 @@ func TestAttachmentHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/attachments", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetParamNames("id")
 		c.SetParamValues("wrong")
 
@@ -187,7 +192,7 @@ func TestAttachmentHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/attachments", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetParamNames("id")
 		c.SetParamValues(attachmentUnknownID.String())
 
@@ -203,7 +208,7 @@ func TestAttachmentHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/attachments", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetParamNames("id")
 		c.SetParamValues(attachmentID.String())
 		c.Set("config", conf)
@@ -225,7 +230,7 @@ func TestAttachmentHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/attachments", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetParamNames("id")
 		c.SetParamValues("wrong")
 		c.Set("config", conf)
@@ -243,7 +248,7 @@ func TestAttachmentHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/attachments", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetParamNames("id")
 		c.SetParamValues(attachmentID.String())
 		c.Set("config", conf)
@@ -260,7 +265,7 @@ func TestAttachmentHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/attachments", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetParamNames("id")
 		c.SetParamValues(attachmentUnknownID.String())
 		c.Set("config", conf)
@@ -277,7 +282,7 @@ func TestAttachmentHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/attachments", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetParamNames("id")
 		c.SetParamValues(attachmentID.String())
 		c.Set("config", conf)
@@ -291,7 +296,7 @@ func TestAttachmentHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodDelete, "/attachments", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetParamNames("id")
 		c.SetParamValues(attachmentDeleteID.String())
 
@@ -309,7 +314,7 @@ func TestAttachmentHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodDelete, "/attachments", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetParamNames("id")
 		c.SetParamValues("wrong")
 
@@ -322,7 +327,7 @@ func TestAttachmentHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodDelete, "/attachments", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetParamNames("id")
 		c.SetParamValues(attachmentUnknownID.String())
 
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/labstack/echo/v4"
+)
+
+// GetSuggestions completes the text q with the tags, instructors, institutions or readings in use, depending on kind.
+// Their number is set by limit.
+func GetSuggestions(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
+	limit := models.DEFAULT_SUGGESTIONS_LIMIT
+	if raw := strings.TrimSpace(c.QueryParam("limit")); raw != "" {
+		l, err := strconv.Atoi(raw)
+		if err != nil || l < 1 || l > models.MAX_SUGGESTIONS_LIMIT {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("The limit should be between 1 and %d.", models.MAX_SUGGESTIONS_LIMIT))
+		}
+		limit = l
+	}
+
+	suggestions, err := models.GetSuggestions(c.Param("kind"), c.QueryParam("q"), limit, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidSuggestion) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusInternalServerError, "There was an error getting the suggestions.")
+	}
+
+	return c.JSON(http.StatusOK, suggestions)
+}
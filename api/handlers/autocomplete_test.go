@@ -0,0 +1,63 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/handlers"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestionHandler(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	t.Run("Test get suggestions", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/?q=isl&limit=1", nil)
+		c := newContext(req, res, uuid.Nil)
+		c.SetPath("/autocomplete/:kind")
+		c.SetParamNames("kind")
+		c.SetParamValues("tags")
+
+		handlers.GetSuggestions(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var suggestions []models.Suggestion
+		err := json.Unmarshal(res.Body.Bytes(), &suggestions)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(suggestions))
+		assert.Equal(t, "Islam", suggestions[0].Value)
+	})
+
+	t.Run("Test get suggestions malformed parameters", func(t *testing.T) {
+		for _, query := range []string{"/?q=", "/?q=isl&limit=0", "/?q=isl&limit=1000"} {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, query, nil)
+			c := newContext(req, res, uuid.Nil)
+			c.SetPath("/autocomplete/:kind")
+			c.SetParamNames("kind")
+			c.SetParamValues("tags")
+
+			handlers.GetSuggestions(c)
+			assert.Equal(t, http.StatusBadRequest, res.Code, query)
+		}
+	})
+
+	t.Run("Test get suggestions unknown kind", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/?q=red", nil)
+		c := newContext(req, res, uuid.Nil)
+		c.SetPath("/autocomplete/:kind")
+		c.SetParamNames("kind")
+		c.SetParamValues("colors")
+
+		handlers.GetSuggestions(c)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
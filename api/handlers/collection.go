@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -14,20 +15,27 @@ import (
 
 func GetAllCollections(c echo.Context) error {
 	user_uuid := mustGetUser(c)
-	collections, err := models.GetAllCollections(user_uuid)
+
+	page, err := parsePage(c)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	collections, meta, err := models.GetAllCollections(user_uuid, page)
 	if err != nil {
 		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidPage) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
 		return c.String(http.StatusInternalServerError, "There was an error getting the Collections.")
 	}
 
-	return c.JSON(http.StatusOK, collections)
+	return c.JSON(http.StatusOK, echo.Map{"collections": collections, "meta": meta})
 }
 
 func CreateCollection(c echo.Context) error {
 	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
 
 	err := sanitizeCollection(c)
 	if err != nil {
@@ -53,9 +61,6 @@ func CreateCollection(c echo.Context) error {
 
 func UpdateCollection(c echo.Context) error {
 	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
 
 	id := c.Param("id")
 	uid, err := uuid.Parse(id)
@@ -82,8 +87,12 @@ func UpdateCollection(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "Error binding to the Collection to update.")
 	}
 
-	updated, err := models.UpdateCollection(uid, user_uuid, &coll)
+	updated, err := models.UpdateCollection(uid, &coll, user_uuid)
 	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrModerated) {
+			return c.String(http.StatusForbidden, "This Collection was unlisted by a moderator, only an admin can change its status.")
+		}
 		return c.String(http.StatusInternalServerError, "Error updating the Collection. Please try again later.")
 	}
 
@@ -92,9 +101,6 @@ func UpdateCollection(c echo.Context) error {
 
 func AddCollectionSyllabus(c echo.Context) error {
 	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
 
 	coll_id := c.Param("id")
 	coll_uid, err := uuid.Parse(coll_id)
@@ -121,6 +127,7 @@ func AddCollectionSyllabus(c echo.Context) error {
 
 func GetCollection(c echo.Context) error {
 	user_uuid := mustGetUser(c)
+
 	id := c.Param("id")
 	uid, err := uuid.Parse(id)
 	if err != nil {
@@ -148,6 +155,7 @@ func GetCollection(c echo.Context) error {
 
 func GetCollectionSyllabi(c echo.Context) error {
 	user_uuid := mustGetUser(c)
+
 	id := c.Param("id")
 	uid, err := uuid.Parse(id)
 	if err != nil {
@@ -167,9 +175,6 @@ func GetCollectionSyllabi(c echo.Context) error {
 func GetCollectionSyllabus(c echo.Context) error {
 	// authenticating to return unlisted but owned syllabi
 	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
 
 	coll_id := c.Param("id")
 	coll_uid, err := uuid.Parse(coll_id)
@@ -197,18 +202,13 @@ func GetCollectionSyllabus(c echo.Context) error {
 }
 
 func DeleteCollection(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	id := c.Param("id")
 	uid, err := uuid.Parse(id)
 	if err != nil {
 		return c.String(http.StatusBadRequest, "Not a valid ID")
 	}
 
-	coll, err := models.DeleteCollection(uid, user_uuid)
+	coll, err := models.DeleteCollection(uid)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusNotFound, "There was an error deleting the Collection.")
@@ -218,11 +218,6 @@ func DeleteCollection(c echo.Context) error {
 }
 
 func RemoveCollectionSyllabus(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	coll_id := c.Param("id")
 	coll_uid, err := uuid.Parse(coll_id)
 	if err != nil {
@@ -236,7 +231,7 @@ func RemoveCollectionSyllabus(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, "Not a valid Syllabus ID.")
 	}
 
-	updated, err := models.RemoveCollectionSyllabus(coll_uid, syll_uid, user_uuid)
+	updated, err := models.RemoveCollectionSyllabus(coll_uid, syll_uid)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusInternalServerError, "There was an error updating the Collection.")
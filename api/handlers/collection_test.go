@@ -4,7 +4,6 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
-	"os"
 	"strings"
 	"testing"
 
@@ -12,44 +11,49 @@ import (
 
 	"github.com/commonsyllabi/explorer/api/handlers"
 	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+type CollectionsResponse struct {
+	Collections []models.Collection `json:"collections"`
+	Meta        models.PageMeta     `json:"meta"`
+}
+
 func TestCollectionHandler(t *testing.T) {
 	teardown := setup(t)
 	defer teardown(t)
 
 	t.Run("Test get all listed collections", func(t *testing.T) {
-		os.Setenv("API_MODE", "debug")
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, uuid.Nil)
 		c.SetPath("/collections")
 		handlers.GetAllCollections(c)
 		assert.Equal(t, http.StatusOK, res.Code)
 
-		colls := make([]models.Collection, 0)
-		err := json.Unmarshal(res.Body.Bytes(), &colls)
+		var resp CollectionsResponse
+		err := json.Unmarshal(res.Body.Bytes(), &resp)
 		require.Nil(t, err)
-		assert.Equal(t, 2, len(colls))
-		os.Setenv("API_MODE", "test")
+		assert.Equal(t, 2, len(resp.Collections))
+		assert.Equal(t, int64(2), resp.Meta.Total)
 	})
 
 	t.Run("Test get all listed and owned collections", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections")
 		handlers.GetAllCollections(c)
 		assert.Equal(t, http.StatusOK, res.Code)
 
-		colls := make([]models.Collection, 0)
-		err := json.Unmarshal(res.Body.Bytes(), &colls)
+		var resp CollectionsResponse
+		err := json.Unmarshal(res.Body.Bytes(), &resp)
 		require.Nil(t, err)
-		assert.Equal(t, 3, len(colls))
+		assert.Equal(t, 3, len(resp.Collections))
 	})
 
 	t.Run("Test create collection", func(t *testing.T) {
@@ -59,7 +63,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections")
 
 		handlers.CreateCollection(c)
@@ -80,7 +84,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/collections", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections")
 
 		handlers.CreateCollection(c)
@@ -92,7 +96,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections")
 		c.SetParamNames("id")
 		c.SetParamValues(collectionID.String())
@@ -112,7 +116,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections")
 		c.SetParamNames("id")
 		c.SetParamValues(collectionSlug)
@@ -132,7 +136,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections")
 		c.SetParamNames("id")
 		c.SetParamValues(collectionUnknownID.String())
@@ -145,7 +149,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections/:id")
 		c.SetParamNames("id")
 		c.SetParamValues("wrong")
@@ -161,7 +165,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPatch, "/collections", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(collectionID.String())
@@ -184,7 +188,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(collectionUnknownID.String())
@@ -200,7 +204,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections/:id")
 		c.SetParamNames("id")
 		c.SetParamValues("wrong")
@@ -216,7 +220,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(collectionID.String())
@@ -232,7 +236,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections/:id/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(collectionID.String())
@@ -250,7 +254,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections/:id/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(collectionID.String())
@@ -268,7 +272,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections/:id/syllabi/:syll_id")
 		c.SetParamNames("id", "syll_id")
 		c.SetParamValues(collectionID.String(), syllabusID.String())
@@ -285,7 +289,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodDelete, "/", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections/:id/syllabi/:syll_id")
 		c.SetParamNames("id", "syll_id")
 		c.SetParamValues(collectionID.String(), syllabusID.String())
@@ -303,7 +307,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(collectionID.String())
@@ -316,7 +320,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(collectionUnknownID.String())
@@ -329,7 +333,7 @@ func TestCollectionHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/collections/:id")
 		c.SetParamNames("id")
 		c.SetParamValues("wrong")
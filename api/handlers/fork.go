@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// ForkSyllabus copies a syllabus into the account of the user, unless its license doesn't allow derivatives
+func ForkSyllabus(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
+	}
+
+	fork, err := models.ForkSyllabus(uid, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrForkNotAllowed) {
+			return c.String(http.StatusForbidden, "The license of this Syllabus does not allow forking it.")
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.String(http.StatusNotFound, "We could not find the requested Syllabus.")
+		}
+		return c.String(http.StatusInternalServerError, "There was an error forking the Syllabus.")
+	}
+
+	return c.JSON(http.StatusCreated, fork)
+}
+
+// GetSyllabusDerivatives returns a page of the syllabi forked from the syllabus
+func GetSyllabusDerivatives(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
+	}
+
+	page, err := parsePage(c)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	syllabi, meta, err := models.GetSyllabusDerivatives(uid, page, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidPage) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusNotFound, "There was an error getting the derivatives of the Syllabus.")
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"syllabi": syllabi, "meta": meta})
+}
@@ -0,0 +1,83 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/handlers"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForkHandler(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	t.Run("Test fork syllabus", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		c := newContext(req, res, userDeleteID)
+		c.SetPath("/syllabi/:id/fork")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.ForkSyllabus(c)
+		require.Equal(t, http.StatusCreated, res.Code)
+
+		var fork models.Syllabus
+		err := json.Unmarshal(res.Body.Bytes(), &fork)
+		require.Nil(t, err)
+		require.NotNil(t, fork.DerivedFromUUID)
+		assert.Equal(t, syllabusID, *fork.DerivedFromUUID)
+		assert.Equal(t, userDeleteID, fork.UserUUID)
+	})
+
+	t.Run("Test fork syllabus not visible", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		c := newContext(req, res, userDeleteID)
+		c.SetPath("/syllabi/:id/fork")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusOtherID.String())
+
+		handlers.ForkSyllabus(c)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("Test fork syllabus without derivatives", func(t *testing.T) {
+		_, err := models.UpdateSyllabus(syllabusID, &models.Syllabus{License: "All rights reserved"}, userID)
+		require.Nil(t, err)
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		c := newContext(req, res, userDeleteID)
+		c.SetPath("/syllabi/:id/fork")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.ForkSyllabus(c)
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	})
+
+	t.Run("Test get syllabus derivatives", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		c := newContext(req, res, userDeleteID)
+		c.SetPath("/syllabi/:id/derivatives")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.GetSyllabusDerivatives(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var page struct {
+			Syllabi []models.Syllabus `json:"syllabi"`
+		}
+		err := json.Unmarshal(res.Body.Bytes(), &page)
+		require.Nil(t, err)
+		assert.Equal(t, 1, len(page.Syllabi))
+	})
+}
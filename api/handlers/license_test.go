@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/handlers"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLicenseHandler(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	t.Run("Test get licenses", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/taxonomy/licenses", nil)
+		c := newContext(req, res, userID)
+
+		handlers.GetLicenses(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var licenses []models.License
+		err := json.Unmarshal(res.Body.Bytes(), &licenses)
+		require.Nil(t, err)
+		assert.Equal(t, len(models.LICENSES), len(licenses))
+	})
+
+	t.Run("Test create syllabus with invalid license", func(t *testing.T) {
+		f := make(url.Values)
+		f.Set("title", "Test Syllabus Licensing")
+		f.Set("description", "Lorem ipsum dolores sit amore.")
+		f.Set("language", "en")
+		f.Set("academic_level", "0")
+		f.Set("license", "free to use")
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi")
+
+		handlers.CreateSyllabus(c)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+
+	t.Run("Test update syllabus license", func(t *testing.T) {
+		f := make(url.Values)
+		f.Set("license", "CC BY 4.0")
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.UpdateSyllabus(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var syll models.Syllabus
+		err := json.Unmarshal(res.Body.Bytes(), &syll)
+		require.Nil(t, err)
+		assert.Equal(t, "CC-BY-4.0", syll.License)
+	})
+
+	t.Run("Test update syllabus with invalid license", func(t *testing.T) {
+		f := make(url.Values)
+		f.Set("license", "CC BY-XY 4.0")
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.UpdateSyllabus(c)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+
+	t.Run("Test get all syllabi by license", func(t *testing.T) {
+		q := make(url.Values)
+		q.Set("licenses", "cc by 4.0")
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
+		c := newContext(req, res, userID)
+
+		handlers.GetSyllabi(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var resp SyllabusResponse
+		err := json.Unmarshal(res.Body.Bytes(), &resp)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(resp.Syllabi))
+		assert.Equal(t, syllabusID, resp.Syllabi[0].UUID)
+	})
+
+	t.Run("Test get all syllabi by malformed license filters", func(t *testing.T) {
+		for _, q := range []url.Values{{"licenses": {"free to use"}}, {"permits": {"sell"}}} {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
+			c := newContext(req, res, userID)
+
+			handlers.GetSyllabi(c)
+			assert.Equal(t, http.StatusBadRequest, res.Code, q.Encode())
+		}
+	})
+}
@@ -11,7 +11,6 @@ import (
 
 	zero "github.com/commonsyllabi/explorer/api/logger"
 	"github.com/commonsyllabi/explorer/api/models"
-	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
@@ -30,13 +29,6 @@ func getProbability(jsonResponse interface{}) (float64, error) {
 // Function that fetches the URL (env var: OPENSYLLABUS_PARSER_API_URL) with the token (env: OPENSYLLABUS_PARSER_API_TOKEN) and appends the file to its body.
 // and returns then returns the parsed syllabus as a json object and handles errors
 func ParseSyllabusFile(c echo.Context) error {
-	// Make sure the user is authenticated
-	userUuid := mustGetUser(c)
-	if userUuid == uuid.Nil {
-		zero.Error("missing user")
-		return c.String(http.StatusUnauthorized, "Unauthorized")
-	}
-
 	// Get the file from the request
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -135,11 +127,13 @@ func ParseSyllabusFile(c echo.Context) error {
 		Language:         openSyllabus.Data.Language,
 		AcademicFields:   openSyllabus.GetAcademicField(),
 		Readings:         openSyllabus.GetReadings(),
+		Citations:        openSyllabus.GetCitations(),
 		LearningOutcomes: openSyllabus.GetLearningOutcomes(),
 		GradingRubric:    openSyllabus.GetGradingRubric(),
 		Schedule:         openSyllabus.GetSchedule(),
 		URLs:             openSyllabus.Data.URLs,
-		Assessments:      openSyllabus.GetAssignments(),
+		Assignments:      openSyllabus.GetAssignments(),
+		Assessments:      openSyllabus.GetAssessments(),
 	}
 
 	// Return a success message
@@ -46,7 +46,7 @@ func TestParseOpenSyllabus(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/attachments", body)
 		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType()) // <<< important part
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.Set("config", conf)
 
 		handlers.ParseSyllabusFile(c)
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// GetReadings returns the readings assigned by the most listed syllabi, along with how many assign them. They can be restricted
+// to the syllabi filed under fields, as with the search of syllabi, and their number is set by limit.
+func GetReadings(c echo.Context) error {
+	fields, err := models.ParseFields(c.QueryParams())
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	limit := models.DEFAULT_READINGS_LIMIT
+	if raw := strings.TrimSpace(c.QueryParam("limit")); raw != "" {
+		l, err := strconv.Atoi(raw)
+		if err != nil || l < 1 || l > models.MAX_READINGS_LIMIT {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("The limit should be between 1 and %d.", models.MAX_READINGS_LIMIT))
+		}
+		limit = l
+	}
+
+	readings, err := models.GetTopReadings(fields, limit)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error getting the readings.")
+	}
+
+	return c.JSON(http.StatusOK, readings)
+}
+
+func GetReading(c echo.Context) error {
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Reading ID.")
+	}
+
+	reading, err := models.GetReading(uid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "There was an error getting the requested Reading.")
+	}
+
+	return c.JSON(http.StatusOK, reading)
+}
+
+// GetReadingSyllabi returns a page of the syllabi which assign the reading
+func GetReadingSyllabi(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Reading ID.")
+	}
+
+	page, err := parsePage(c)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	syllabi, meta, err := models.GetReadingSyllabi(uid, page, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidPage) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusNotFound, "There was an error getting the syllabi of the requested Reading.")
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"syllabi": syllabi, "meta": meta})
+}
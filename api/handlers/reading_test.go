@@ -0,0 +1,111 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/handlers"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadingHandler(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	var readings []models.ReadingCount
+	t.Run("Test get readings", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/?limit=2", nil)
+		c := newContext(req, res, uuid.Nil)
+		c.SetPath("/readings")
+
+		handlers.GetReadings(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		err := json.Unmarshal(res.Body.Bytes(), &readings)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(readings))
+		assert.Equal(t, int64(2), readings[0].Count)
+	})
+
+	t.Run("Test get readings per field", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/?fields=500&subfields=true", nil)
+		c := newContext(req, res, uuid.Nil)
+		c.SetPath("/readings")
+
+		handlers.GetReadings(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var field []models.ReadingCount
+		err := json.Unmarshal(res.Body.Bytes(), &field)
+		require.Nil(t, err)
+		assert.Equal(t, 0, len(field))
+	})
+
+	t.Run("Test get readings malformed parameters", func(t *testing.T) {
+		for _, query := range []string{"/?limit=0", "/?limit=1000", "/?fields=999"} {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, query, nil)
+			c := newContext(req, res, uuid.Nil)
+			c.SetPath("/readings")
+
+			handlers.GetReadings(c)
+			assert.Equal(t, http.StatusBadRequest, res.Code, query)
+		}
+	})
+
+	t.Run("Test get reading", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		c := newContext(req, res, uuid.Nil)
+		c.SetPath("/readings/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(readings[0].UUID.String())
+
+		handlers.GetReading(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var reading models.ReadingCount
+		err := json.Unmarshal(res.Body.Bytes(), &reading)
+		require.Nil(t, err)
+		assert.Equal(t, readings[0].Title, reading.Title)
+		assert.Equal(t, int64(2), reading.Count)
+	})
+
+	t.Run("Test get reading non-existing ID", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		c := newContext(req, res, uuid.Nil)
+		c.SetPath("/readings/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(uuid.New().String())
+
+		handlers.GetReading(c)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("Test get reading syllabi", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/?page_size=1", nil)
+		c := newContext(req, res, uuid.Nil)
+		c.SetPath("/readings/:id/syllabi")
+		c.SetParamNames("id")
+		c.SetParamValues(readings[0].UUID.String())
+
+		handlers.GetReadingSyllabi(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var resp SyllabusResponse
+		err := json.Unmarshal(res.Body.Bytes(), &resp)
+		require.Nil(t, err)
+		assert.Equal(t, 1, len(resp.Syllabi))
+		assert.Equal(t, 2, resp.Meta.Total)
+		assert.NotEmpty(t, resp.Meta.NextCursor)
+	})
+}
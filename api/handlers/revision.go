@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// GetSyllabusRevisions lists the revisions of a syllabus, latest first
+func GetSyllabusRevisions(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
+	}
+
+	revisions, err := models.GetSyllabusRevisions(uid, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "There was an error getting the revisions of the Syllabus.")
+	}
+
+	return c.JSON(http.StatusOK, revisions)
+}
+
+func GetSyllabusRevision(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
+	}
+
+	number, err := parseRevisionNumber(c.Param("number"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not a valid revision number.")
+	}
+
+	revision, err := models.GetSyllabusRevision(uid, number, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "There was an error getting the requested revision.")
+	}
+
+	return c.JSON(http.StatusOK, revision)
+}
+
+// DiffSyllabusRevisions compares the revision from of a syllabus with the revision to, or with its latest one if to is missing
+func DiffSyllabusRevisions(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
+	}
+
+	from, err := parseRevisionNumber(c.QueryParam("from"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "The revision to compare from should be a positive number.")
+	}
+
+	to := 0
+	if raw := strings.TrimSpace(c.QueryParam("to")); raw != "" {
+		to, err = parseRevisionNumber(raw)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "The revision to compare to should be a positive number.")
+		}
+	}
+
+	diff, err := models.DiffSyllabusRevisions(uid, from, to, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidRevision) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusNotFound, "There was an error getting the revisions of the Syllabus.")
+	}
+
+	return c.JSON(http.StatusOK, diff)
+}
+
+// RestoreSyllabusRevision sets a syllabus back to one of its revisions, as a new revision
+func RestoreSyllabusRevision(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
+	}
+
+	number, err := parseRevisionNumber(c.Param("number"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not a valid revision number.")
+	}
+
+	syll, err := models.RestoreSyllabusRevision(uid, number, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidLicense) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusNotFound, "There was an error restoring the requested revision.")
+	}
+
+	return c.JSON(http.StatusOK, syll)
+}
+
+func parseRevisionNumber(raw string) (int, error) {
+	number, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, err
+	}
+	if number < 1 {
+		return 0, errors.New("revisions are numbered from 1")
+	}
+	return number, nil
+}
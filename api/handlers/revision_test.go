@@ -0,0 +1,155 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/handlers"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevisionHandler(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	t.Run("Test update syllabus records a revision", func(t *testing.T) {
+		f := make(url.Values)
+		f.Set("title", "Updated Title")
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.UpdateSyllabus(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		res = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		c = newContext(req, res, uuid.Nil)
+		c.SetPath("/syllabi/:id/revisions")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.GetSyllabusRevisions(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var revisions []models.SyllabusRevision
+		err := json.Unmarshal(res.Body.Bytes(), &revisions)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(revisions))
+		assert.Equal(t, 2, revisions[0].Number)
+	})
+
+	t.Run("Test update syllabus the user can't see", func(t *testing.T) {
+		// -- such as an admin editing someone else's unlisted syllabus, once the policy allowed it
+		f := make(url.Values)
+		f.Set("title", "Web Design Basics, revised")
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("46de6a2b-aacb-4c24-b1e1-3495821f8777")
+
+		handlers.UpdateSyllabus(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var syll models.Syllabus
+		err := json.Unmarshal(res.Body.Bytes(), &syll)
+		require.Nil(t, err)
+		assert.Equal(t, "Web Design Basics, revised", syll.Title)
+		assert.Equal(t, "unlisted", syll.Status)
+	})
+
+	t.Run("Test diff revisions", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/?from=1&to=2", nil)
+		c := newContext(req, res, uuid.Nil)
+		c.SetPath("/syllabi/:id/revisions/diff")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.DiffSyllabusRevisions(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var diff models.RevisionDiff
+		err := json.Unmarshal(res.Body.Bytes(), &diff)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(diff.Changes))
+		assert.Equal(t, "title", diff.Changes[0].Field)
+		assert.Equal(t, "Updated Title", diff.Changes[0].To)
+	})
+
+	t.Run("Test diff revisions malformed parameters", func(t *testing.T) {
+		for _, query := range []string{"/", "/?from=0", "/?from=1&to=two", "/?from=1&to=42"} {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, query, nil)
+			c := newContext(req, res, uuid.Nil)
+			c.SetPath("/syllabi/:id/revisions/diff")
+			c.SetParamNames("id")
+			c.SetParamValues(syllabusID.String())
+
+			handlers.DiffSyllabusRevisions(c)
+			assert.Equal(t, http.StatusBadRequest, res.Code, query)
+		}
+	})
+
+	t.Run("Test restore revision", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi/:id/revisions/:number/restore")
+		c.SetParamNames("id", "number")
+		c.SetParamValues(syllabusID.String(), "1")
+
+		handlers.RestoreSyllabusRevision(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var syll models.Syllabus
+		err := json.Unmarshal(res.Body.Bytes(), &syll)
+		require.Nil(t, err)
+		assert.Equal(t, "Ungewohnt", syll.Title)
+
+		res = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		c = newContext(req, res, uuid.Nil)
+		c.SetPath("/syllabi/:id/revisions/:number")
+		c.SetParamNames("id", "number")
+		c.SetParamValues(syllabusID.String(), "3")
+
+		handlers.GetSyllabusRevision(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var revision models.SyllabusRevision
+		err = json.Unmarshal(res.Body.Bytes(), &revision)
+		require.Nil(t, err)
+		assert.Equal(t, 1, revision.RestoredFrom)
+		assert.Equal(t, "Ungewohnt", revision.Snapshot.Title)
+	})
+
+	t.Run("Test restore unknown revision", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi/:id/revisions/:number/restore")
+		c.SetParamNames("id", "number")
+		c.SetParamValues(syllabusID.String(), "42")
+
+		handlers.RestoreSyllabusRevision(c)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/labstack/echo/v4"
+)
+
+// -- the pagination of a search is not saved along with it
+var pageParams = []string{"cursor", "page_size", "sort", "order"}
+
+// GetSavedSearches lists the saved searches of the current user
+func GetSavedSearches(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
+	searches, err := models.GetSavedSearches(user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusInternalServerError, "There was an error getting your saved searches.")
+	}
+
+	return c.JSON(http.StatusOK, searches)
+}
+
+// CreateSavedSearch saves a search of the current user, from a name, a query holding the url-encoded parameters of GET /syllabi/,
+// and the frequency of its digests, weekly by default
+func CreateSavedSearch(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
+	query, err := parseSavedQuery(c.FormValue("query"))
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	search, err := models.CreateSavedSearch(user_uuid, c.FormValue("name"), query, c.FormValue("frequency"))
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidSavedSearch) || errors.Is(err, models.ErrTooManySavedSearches) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusInternalServerError, "There was an error saving your search.")
+	}
+
+	return c.JSON(http.StatusCreated, search)
+}
+
+// UpdateSavedSearch changes the name, query or frequency of a saved search
+func UpdateSavedSearch(c echo.Context) error {
+	search_uuid := parseUUIDParam(c, "id")
+
+	form, err := c.FormParams()
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, "There was an error parsing your parameters.")
+	}
+
+	var query *string
+	if _, found := form["query"]; found {
+		q, err := parseSavedQuery(form.Get("query"))
+		if err != nil {
+			zero.Error(err.Error())
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		query = &q
+	}
+
+	search, err := models.UpdateSavedSearch(search_uuid, form.Get("name"), query, form.Get("frequency"))
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidSavedSearch) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusNotFound, "We could not find the requested saved search.")
+	}
+
+	return c.JSON(http.StatusOK, search)
+}
+
+func DeleteSavedSearch(c echo.Context) error {
+	search_uuid := parseUUIDParam(c, "id")
+
+	err := models.DeleteSavedSearch(search_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrSavedSearchNotFound) {
+			return c.String(http.StatusNotFound, "We could not find the requested saved search.")
+		}
+		return c.String(http.StatusInternalServerError, "There was an error deleting your saved search.")
+	}
+
+	return c.String(http.StatusOK, "The saved search has been deleted.")
+}
+
+// UnsubscribeSavedSearch turns off the digests of the saved search matching the token of the link they come with
+func UnsubscribeSavedSearch(c echo.Context) error {
+	search, err := models.UnsubscribeSavedSearch(c.QueryParam("token"))
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrSavedSearchNotFound) {
+			return c.String(http.StatusNotFound, "We could not find the requested saved search.")
+		}
+		return c.String(http.StatusInternalServerError, "There was an error unsubscribing you.")
+	}
+
+	return c.String(http.StatusOK, fmt.Sprintf("You will no longer receive new syllabi matching %s.", search.Name))
+}
+
+// parseSavedQuery checks that the parameters of a saved search are those of a valid search, and drops its pagination
+func parseSavedQuery(raw string) (string, error) {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return "", fmt.Errorf("the query of the search should be url-encoded: %v", err)
+	}
+
+	for _, p := range pageParams {
+		values.Del(p)
+	}
+
+	_, err = models.ParseSearchParams(values)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidQuery) {
+			return "", err
+		}
+		return "", fmt.Errorf("the parameters of the search are invalid: %v", err)
+	}
+
+	return values.Encode(), nil
+}
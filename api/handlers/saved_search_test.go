@@ -0,0 +1,164 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/handlers"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedSearchHandler(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	var search models.SavedSearch
+	t.Run("Test create saved search", func(t *testing.T) {
+		f := make(url.Values)
+		f.Set("name", "Media theory")
+		f.Set("query", "tags=media&q=year:2021..&cursor=abc")
+		f.Set("frequency", models.DigestDaily)
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		c := newContext(req, res, userID)
+		c.SetPath("/searches")
+
+		handlers.CreateSavedSearch(c)
+		require.Equal(t, http.StatusCreated, res.Code)
+
+		err := json.Unmarshal(res.Body.Bytes(), &search)
+		require.Nil(t, err)
+		assert.Equal(t, "Media theory", search.Name)
+		assert.Equal(t, "q=year%3A2021..&tags=media", search.Query)
+		assert.Equal(t, models.DigestDaily, search.Frequency)
+		assert.NotContains(t, res.Body.String(), "unsubscribe")
+	})
+
+	t.Run("Test create saved search malformed query", func(t *testing.T) {
+		for _, query := range []string{"q=(tag:art", "fields=999", "languages=klingon"} {
+			f := make(url.Values)
+			f.Set("name", "Broken")
+			f.Set("query", query)
+
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			c := newContext(req, res, userID)
+			c.SetPath("/searches")
+
+			handlers.CreateSavedSearch(c)
+			assert.Equal(t, http.StatusBadRequest, res.Code, query)
+		}
+	})
+
+	t.Run("Test get saved searches", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		c := newContext(req, res, userID)
+		c.SetPath("/searches")
+
+		handlers.GetSavedSearches(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var searches []models.SavedSearch
+		err := json.Unmarshal(res.Body.Bytes(), &searches)
+		require.Nil(t, err)
+		assert.Equal(t, 1, len(searches))
+	})
+
+	t.Run("Test update saved search", func(t *testing.T) {
+		f := make(url.Values)
+		f.Set("frequency", models.DigestWeekly)
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		c := newContext(req, res, userID)
+		c.SetPath("/searches/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(search.UUID.String())
+
+		handlers.UpdateSavedSearch(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var updated models.SavedSearch
+		err := json.Unmarshal(res.Body.Bytes(), &updated)
+		require.Nil(t, err)
+		assert.Equal(t, models.DigestWeekly, updated.Frequency)
+		assert.Equal(t, search.Query, updated.Query)
+	})
+
+	t.Run("Test update saved search wrong frequency", func(t *testing.T) {
+		f := make(url.Values)
+		f.Set("frequency", "hourly")
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		c := newContext(req, res, userID)
+		c.SetPath("/searches/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(search.UUID.String())
+
+		handlers.UpdateSavedSearch(c)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+
+	t.Run("Test unsubscribe saved search", func(t *testing.T) {
+		saved, err := models.GetSavedSearch(search.UUID)
+		require.Nil(t, err)
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/?token="+url.QueryEscape(saved.UnsubscribeToken), nil)
+		c := newContext(req, res, uuid.Nil)
+		c.SetPath("/searches/unsubscribe")
+
+		handlers.UnsubscribeSavedSearch(c)
+		assert.Equal(t, http.StatusOK, res.Code)
+
+		saved, err = models.GetSavedSearch(search.UUID)
+		require.Nil(t, err)
+		assert.Equal(t, models.DigestNone, saved.Frequency)
+	})
+
+	t.Run("Test unsubscribe unknown token", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/?token=unknown", nil)
+		c := newContext(req, res, uuid.Nil)
+		c.SetPath("/searches/unsubscribe")
+
+		handlers.UnsubscribeSavedSearch(c)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("Test delete saved search", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		c := newContext(req, res, userID)
+		c.SetPath("/searches/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(search.UUID.String())
+
+		handlers.DeleteSavedSearch(c)
+		assert.Equal(t, http.StatusOK, res.Code)
+
+		res = httptest.NewRecorder()
+		c = newContext(req, res, userID)
+		c.SetPath("/searches/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(search.UUID.String())
+
+		handlers.DeleteSavedSearch(c)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// GetSessions lists the sessions of a syllabus, in the order they are taught
+func GetSessions(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
+	}
+
+	sessions, err := models.GetSessions(uid, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "There was an error getting the sessions of the Syllabus.")
+	}
+
+	return c.JSON(http.StatusOK, sessions)
+}
+
+func CreateSession(c echo.Context) error {
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
+	}
+
+	input, err := parseSessionInput(c)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	session, err := models.CreateSession(uid, input)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidSession) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusNotFound, "There was an error creating the session.")
+	}
+
+	return c.JSON(http.StatusCreated, session)
+}
+
+func UpdateSession(c echo.Context) error {
+	uid := parseUUIDParam(c, "id")
+	session_uid := parseUUIDParam(c, "session_id")
+	if uid == uuid.Nil || session_uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid ID.")
+	}
+
+	input, err := parseSessionInput(c)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	session, err := models.UpdateSession(uid, session_uid, input)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidSession) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusNotFound, "There was an error updating the session.")
+	}
+
+	return c.JSON(http.StatusOK, session)
+}
+
+func DeleteSession(c echo.Context) error {
+	uid := parseUUIDParam(c, "id")
+	session_uid := parseUUIDParam(c, "session_id")
+	if uid == uuid.Nil || session_uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid ID.")
+	}
+
+	session, err := models.DeleteSession(uid, session_uid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "There was an error deleting the session.")
+	}
+
+	return c.JSON(http.StatusOK, session)
+}
+
+// parseSessionInput reads the fields of a session which are in the form. The date is written as 2006-01-02, and an empty
+// date or week clears it.
+func parseSessionInput(c echo.Context) (models.SessionInput, error) {
+	var input models.SessionInput
+
+	form, err := c.FormParams()
+	if err != nil {
+		return input, fmt.Errorf("there was an error parsing your parameters: %w", err)
+	}
+
+	if _, found := form["title"]; found {
+		title := form.Get("title")
+		input.Title = &title
+	}
+
+	if _, found := form["date"]; found {
+		var date time.Time
+		if raw := strings.TrimSpace(form.Get("date")); raw != "" {
+			date, err = time.Parse("2006-01-02", raw)
+			if err != nil {
+				return input, fmt.Errorf("the date should be written as YYYY-MM-DD, given '%s'", raw)
+			}
+		}
+		input.Date = &date
+	}
+
+	if _, found := form["week"]; found {
+		week := 0
+		if raw := strings.TrimSpace(form.Get("week")); raw != "" {
+			week, err = strconv.Atoi(raw)
+			if err != nil {
+				return input, fmt.Errorf("the week should be a number, given '%s'", raw)
+			}
+		}
+		input.Week = &week
+	}
+
+	if _, found := form["position"]; found {
+		position, err := strconv.Atoi(strings.TrimSpace(form.Get("position")))
+		if err != nil {
+			return input, fmt.Errorf("the position should be a number, given '%s'", form.Get("position"))
+		}
+		input.Position = &position
+	}
+
+	input.Topics = formList(form, "topics[]")
+	input.Assignments = formList(form, "assignments[]")
+
+	if readings := formList(form, "readings[]"); readings != nil {
+		input.Readings = make([]uuid.UUID, 0)
+		for _, r := range readings {
+			id, err := uuid.Parse(r)
+			if err != nil {
+				return input, fmt.Errorf("not a valid Reading ID: '%s'", r)
+			}
+			input.Readings = append(input.Readings, id)
+		}
+	}
+
+	return input, nil
+}
+
+// formList returns the non-empty values of a list in the form, an empty list if it only has empty values so that it can be
+// cleared, or nil if it is missing
+func formList(form url.Values, key string) []string {
+	values, found := form[key]
+	if !found {
+		return nil
+	}
+
+	list := make([]string, 0)
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
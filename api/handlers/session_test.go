@@ -0,0 +1,131 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/handlers"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionHandler(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	var session models.Session
+	t.Run("Test create session", func(t *testing.T) {
+		f := make(url.Values)
+		f.Set("title", "Introduction")
+		f.Set("date", "2022-10-17")
+		f.Add("topics[]", "Urban form")
+		f.Add("topics[]", "Public space")
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi/:id/sessions")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.CreateSession(c)
+		require.Equal(t, http.StatusCreated, res.Code)
+
+		err := json.Unmarshal(res.Body.Bytes(), &session)
+		require.Nil(t, err)
+		assert.Equal(t, "Introduction", session.Title)
+		assert.Equal(t, 2, len(session.Topics))
+		require.NotNil(t, session.Date)
+	})
+
+	t.Run("Test create session malformed", func(t *testing.T) {
+		for _, form := range []url.Values{
+			{"title": {""}},
+			{"title": {"Introduction"}, "date": {"17/10/2022"}},
+			{"title": {"Introduction"}, "week": {"third"}},
+			{"title": {"Introduction"}, "readings[]": {"wrong"}},
+		} {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			c := newContext(req, res, userID)
+			c.SetPath("/syllabi/:id/sessions")
+			c.SetParamNames("id")
+			c.SetParamValues(syllabusID.String())
+
+			handlers.CreateSession(c)
+			assert.Equal(t, http.StatusBadRequest, res.Code, form.Encode())
+		}
+	})
+
+	t.Run("Test update session", func(t *testing.T) {
+		f := make(url.Values)
+		f.Set("week", "2")
+		f.Set("date", "")
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi/:id/sessions/:session_id")
+		c.SetParamNames("id", "session_id")
+		c.SetParamValues(syllabusID.String(), session.UUID.String())
+
+		handlers.UpdateSession(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var updated models.Session
+		err := json.Unmarshal(res.Body.Bytes(), &updated)
+		require.Nil(t, err)
+		assert.Nil(t, updated.Date)
+		require.NotNil(t, updated.Week)
+		assert.Equal(t, 2, *updated.Week)
+		assert.Equal(t, 2, len(updated.Topics))
+	})
+
+	t.Run("Test get sessions", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		c := newContext(req, res, uuid.Nil)
+		c.SetPath("/syllabi/:id/sessions")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.GetSessions(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var sessions []models.Session
+		err := json.Unmarshal(res.Body.Bytes(), &sessions)
+		require.Nil(t, err)
+		assert.Equal(t, 1, len(sessions))
+	})
+
+	t.Run("Test delete session", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi/:id/sessions/:session_id")
+		c.SetParamNames("id", "session_id")
+		c.SetParamValues(syllabusID.String(), session.UUID.String())
+
+		handlers.DeleteSession(c)
+		assert.Equal(t, http.StatusOK, res.Code)
+
+		res = httptest.NewRecorder()
+		c = newContext(req, res, userID)
+		c.SetPath("/syllabi/:id/sessions/:session_id")
+		c.SetParamNames("id", "session_id")
+		c.SetParamValues(syllabusID.String(), session.UUID.String())
+
+		handlers.DeleteSession(c)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
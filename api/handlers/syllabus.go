@@ -1,18 +1,19 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 
-	"github.com/commonsyllabi/explorer/api/auth"
 	zero "github.com/commonsyllabi/explorer/api/logger"
 	"github.com/commonsyllabi/explorer/api/models"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/text/language"
+	"gorm.io/gorm"
 )
 
 var (
@@ -24,32 +25,59 @@ var (
 func GetSyllabi(c echo.Context) error {
 	user_uuid := mustGetUser(c)
 
-	params, err := parseSearchParams(c)
+	params, err := models.ParseSearchParams(c.QueryParams())
 	if err != nil {
 		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidQuery) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
 		return c.String(http.StatusBadRequest, "There was an error in parsing your search parameters.")
 	}
 
-	syllabi, err := models.GetSyllabi(params, user_uuid)
+	page, err := parsePage(c)
 	if err != nil {
 		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	syllabi, meta, err := models.GetSyllabi(params, page, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidPage) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
 		return c.String(http.StatusInternalServerError, "There was an error getting the syllabi.")
 	}
 
-	filters, err := models.GetSyllabiFilters()
+	facets, err := models.GetSyllabiFacets(params, user_uuid)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusInternalServerError, "There was an error getting the syllabus count.")
 	}
 
+	filters := echo.Map{
+		"facets":      facets,
+		"total":       meta.Total,
+		"page_size":   meta.PageSize,
+		"next_cursor": meta.NextCursor,
+		"sort":        meta.Sort,
+		"order":       meta.Order,
+	}
+
+	// -- the values of the facets are also listed on their own, as the filters available to further query
+	for _, f := range []string{"languages", "academic_levels", "academic_fields", "academic_years"} {
+		values := make([]string, 0, len(facets[f]))
+		for _, count := range facets[f] {
+			values = append(values, count.Value)
+		}
+		filters[f] = values
+	}
+
 	return c.JSON(http.StatusOK, echo.Map{"syllabi": syllabi, "meta": filters})
 }
 
 func CreateSyllabus(c echo.Context) error {
 	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
 
 	err := sanitizeSyllabusCreate(c)
 	if err != nil {
@@ -64,12 +92,24 @@ func CreateSyllabus(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "There was an error parsing the Syllabus information.")
 	}
 
-	if os.Getenv("API_MODE") == "test" {
-		user_uuid = uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c8a8")
+	// -- syllabi are only derived from others by forking them
+	syll.DerivedFromUUID = nil
+
+	// -- the citations of a parsed syllabus are sent back as the parse returned them, in JSON
+	if raw := c.FormValue("citations"); raw != "" {
+		err = json.Unmarshal([]byte(raw), &syll.Citations)
+		if err != nil {
+			zero.Error(err.Error())
+			return c.String(http.StatusBadRequest, "The citations should be a list of readings, as returned by the parse.")
+		}
 	}
+
 	syll, err = models.CreateSyllabus(&syll, user_uuid)
 	if err != nil {
 		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidLicense) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
 		return c.String(http.StatusInternalServerError, "There was an error creating the Syllabus.")
 	}
 
@@ -103,12 +143,37 @@ func GetSyllabus(c echo.Context) error {
 	return c.JSON(http.StatusOK, syll)
 }
 
-func AddSyllabusAttachment(c echo.Context) error {
+// GetSimilarSyllabi returns the listed syllabi most similar to the given one, with the reasons they matched. The number of
+// syllabi returned is set by limit.
+func GetSimilarSyllabi(c echo.Context) error {
 	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
+
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
 	}
 
+	limit := models.DEFAULT_SIMILAR_LIMIT
+	if raw := strings.TrimSpace(c.QueryParam("limit")); raw != "" {
+		l, err := strconv.Atoi(raw)
+		if err != nil || l < 1 || l > models.MAX_SIMILAR_LIMIT {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("The limit should be between 1 and %d.", models.MAX_SIMILAR_LIMIT))
+		}
+		limit = l
+	}
+
+	similar, err := models.GetSimilarSyllabi(uid, limit, user_uuid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "There was an error getting the similar syllabi.")
+	}
+
+	return c.JSON(http.StatusOK, similar)
+}
+
+func AddSyllabusAttachment(c echo.Context) error {
+	user_uuid := mustGetUser(c)
+
 	uid := parseUUIDParam(c, "id")
 	att_uid := parseUUIDForm(c, "att_id")
 	if uid == uuid.Nil || att_uid == uuid.Nil {
@@ -118,6 +183,9 @@ func AddSyllabusAttachment(c echo.Context) error {
 	syll, err := models.AddAttachmentToSyllabus(uid, att_uid, user_uuid)
 	if err != nil {
 		zero.Error(err.Error())
+		if errors.Is(err, models.ErrAccessDenied) {
+			return c.String(http.StatusForbidden, "You cannot move an Attachment from a Syllabus you do not edit.")
+		}
 		return c.String(http.StatusNotFound, "We couldn't add the  Attachment to the requested Syllabus.")
 	}
 
@@ -171,9 +239,6 @@ func GetSyllabusAttachment(c echo.Context) error {
 
 func UpdateSyllabus(c echo.Context) error {
 	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
 
 	uid := parseUUIDParam(c, "id")
 	if uid == uuid.Nil {
@@ -197,21 +262,19 @@ func UpdateSyllabus(c echo.Context) error {
 		return c.String(http.StatusNoContent, "You must specify at least one field to update the Syllabus.")
 	}
 
-	syll, err := models.GetSyllabus(uid, user_uuid)
-	if err != nil {
-		zero.Error(err.Error())
-		return c.String(http.StatusNotFound, "There was an error getting the Syllabus.")
-	}
-
-	err = c.Bind(&syll)
-	if err != nil {
-		zero.Error(err.Error())
-		return c.String(http.StatusBadRequest, "There was an error getting the information to update the Syllabus.")
-	}
-
-	updated, err := models.UpdateSyllabus(uid, user_uuid, &syll)
+	// -- the policy already checked that the user can edit the syllabus, even if they couldn't see it, as an admin
+	updated, err := models.UpdateSyllabus(uid, &input, user_uuid)
 	if err != nil {
 		zero.Error(err.Error())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.String(http.StatusNotFound, "There was an error getting the Syllabus.")
+		}
+		if errors.Is(err, models.ErrInvalidLicense) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, models.ErrModerated) {
+			return c.String(http.StatusForbidden, "This Syllabus was unlisted by a moderator, only an admin can change its status.")
+		}
 		return c.String(http.StatusInternalServerError, "There was an error updating the Syllabus.")
 	}
 
@@ -219,16 +282,11 @@ func UpdateSyllabus(c echo.Context) error {
 }
 
 func DeleteSyllabus(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	uid := parseUUIDParam(c, "id")
 	if uid == uuid.Nil {
 		return c.String(http.StatusBadRequest, "Invalid UUID")
 	}
-	syll, err := models.DeleteSyllabus(uid, user_uuid)
+	syll, err := models.DeleteSyllabus(uid)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusNotFound, "There was an error deleting the Syllabus.")
@@ -238,11 +296,6 @@ func DeleteSyllabus(c echo.Context) error {
 }
 
 func RemoveSyllabusAttachment(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	uid := parseUUIDParam(c, "id")
 	if uid == uuid.Nil {
 		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID")
@@ -252,7 +305,7 @@ func RemoveSyllabusAttachment(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "Not a valid Attachment ID")
 	}
 
-	syll, err := models.RemoveAttachmentFromSyllabus(uid, att_uid, user_uuid)
+	syll, err := models.RemoveAttachmentFromSyllabus(uid, att_uid)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusNotFound, "There was an error removing the Attachment form the Syllabus.")
@@ -262,11 +315,6 @@ func RemoveSyllabusAttachment(c echo.Context) error {
 }
 
 func AddSyllabusInstitution(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	uid := parseUUIDParam(c, "id")
 	if uid == uuid.Nil {
 		return c.String(http.StatusBadRequest, "Not a valid ID.")
@@ -275,7 +323,7 @@ func AddSyllabusInstitution(c echo.Context) error {
 	var inst models.Institution
 	c.Bind(&inst)
 
-	inst, err := models.AddInstitutionToSyllabus(uid, user_uuid, &inst)
+	inst, err := models.AddInstitutionToSyllabus(uid, &inst)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusNotFound, "There was an error adding the Institution to the Syllabus.")
@@ -285,11 +333,6 @@ func AddSyllabusInstitution(c echo.Context) error {
 }
 
 func EditSyllabusInstitution(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	owner_id := c.Param("id")
 	owner_uuid, err := uuid.Parse(owner_id)
 	if err != nil {
@@ -321,11 +364,6 @@ func EditSyllabusInstitution(c echo.Context) error {
 }
 
 func RemoveSyllabusInstitution(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	uid := parseUUIDParam(c, "id")
 	if uid == uuid.Nil {
 		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
@@ -335,7 +373,7 @@ func RemoveSyllabusInstitution(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "Not a valid Institution ID.")
 	}
 
-	syll, err := models.RemoveInstitutionFromSyllabus(uid, inst_uid, user_uuid)
+	syll, err := models.RemoveInstitutionFromSyllabus(uid, inst_uid)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusNotFound, "There was an error removing the Institution.")
@@ -344,100 +382,42 @@ func RemoveSyllabusInstitution(c echo.Context) error {
 	return c.JSON(http.StatusOK, syll)
 }
 
-func parseSearchParams(c echo.Context) (map[string]any, error) {
-	params := make(map[string]any, 0)
-	params["page"] = 0
-	params["fields"] = "%"
-	params["keywords"] = "%"
-	params["languages"] = "%"
-	params["levels"] = "%"
-	params["tags"] = "%"
-
-	p := c.QueryParam("page")
-	p = strings.Trim(p, " ")
-	page, err := strconv.Atoi(p)
-	if err != nil {
-		page = 0
-	}
-	params["page"] = page
-
-	fields := c.QueryParam("fields")
-	fields = strings.Trim(fields, " ")
-	all_fields := strings.Split(fields, ",")
-	if len(all_fields) > 0 && all_fields[0] != "" {
-		for i := range all_fields {
-			all_fields[i] = strings.Trim(all_fields[i], " ")
-			f, err := strconv.Atoi(all_fields[i])
-			if err != nil {
-				return params, fmt.Errorf("field is not compliant integer: %v", err)
-			}
-			if _, found := models.ACADEMIC_FIELDS[f]; !found {
-				return params, fmt.Errorf("field is not ISCED-F 2013 compliant: %v", err)
-			}
-		}
-		params["fields"] = fmt.Sprintf("%%(%s)%%", strings.Join(all_fields, "|"))
+func AddSyllabusCollaborator(c echo.Context) error {
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
+	}
+	collab_uid := parseUUIDForm(c, "user_id")
+	if collab_uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid User ID.")
 	}
 
-	kws := c.QueryParam("keywords")
-	kws = strings.Trim(kws, " ")
-	all_kws := strings.Split(kws, ",")
-	if len(all_kws) > 0 {
-		for i := range all_kws {
-			all_kws[i] = strings.Trim(all_kws[i], " ")
-			all_kws[i] = strings.ToLower(all_kws[i])
-		}
-		params["keywords"] = fmt.Sprintf("%%(%s)%%", strings.Join(all_kws, "|"))
+	syll, err := models.AddCollaboratorToSyllabus(uid, collab_uid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "We couldn't add the collaborator to the requested Syllabus.")
 	}
 
-	tags := c.QueryParam("tags")
-	tags = strings.Trim(tags, " ")
-	all_tags := strings.Split(tags, ",")
-	if len(all_tags) > 0 {
-		for i := range all_tags {
-			all_tags[i] = strings.Trim(all_tags[i], " ")
-			all_tags[i] = strings.ToLower(all_tags[i])
-		}
-		params["tags"] = fmt.Sprintf("%%(%s)%%", strings.Join(all_tags, "|"))
-	}
-
-	langs := c.QueryParam("languages")
-	langs = strings.Trim(langs, " ")
-	all_langs := strings.Split(langs, ",")
-	if len(all_langs) > 0 {
-		for i := range all_langs {
-			all_langs[i] = strings.Trim(all_langs[i], " ")
-			all_langs[i] = strings.ToLower(all_langs[i])
-			if all_langs[i] != "" {
-				_, err := language.ParseBase(all_langs[i])
-				if err != nil {
-					return params, fmt.Errorf("language is not bcp-47 compliant: %v", err)
-				}
-			}
-		}
-		params["languages"] = fmt.Sprintf("%%(%s)%%", strings.Join(all_langs, "|"))
-	}
-
-	levels := c.QueryParam("levels")
-	levels = strings.Trim(levels, " ")
-	all_levels := strings.Split(levels, ",")
-	if len(all_levels) > 0 {
-		for i := range all_levels {
-			if all_levels[i] != "" {
-				l, err := strconv.Atoi(all_levels[i])
-				if err != nil {
-					return params, fmt.Errorf("the level of the syllabus should be between 0 and 3: %v", err)
-				}
-				_, found := models.LEVELS[l]
-				if !found {
-					return params, fmt.Errorf("the level of the syllabus should be between 0 and 3: %v", err)
-				}
-			}
-		}
+	return c.JSON(http.StatusOK, syll)
+}
+
+func RemoveSyllabusCollaborator(c echo.Context) error {
+	uid := parseUUIDParam(c, "id")
+	if uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid Syllabus ID.")
+	}
+	collab_uid := parseUUIDParam(c, "user_id")
+	if collab_uid == uuid.Nil {
+		return c.String(http.StatusBadRequest, "Not a valid User ID.")
+	}
 
-		params["levels"] = fmt.Sprintf("%%(%s)%%", strings.Join(all_levels, "|"))
+	syll, err := models.RemoveCollaboratorFromSyllabus(uid, collab_uid)
+	if err != nil {
+		zero.Error(err.Error())
+		return c.String(http.StatusNotFound, "There was an error removing the collaborator.")
 	}
 
-	return params, nil
+	return c.JSON(http.StatusOK, syll)
 }
 
 func sanitizeSyllabusCreate(c echo.Context) error {
@@ -467,7 +447,8 @@ func sanitizeSyllabusCreate(c echo.Context) error {
 		return fmt.Errorf("the level of the syllabus should be between 0 and 3")
 	}
 
-	return nil
+	_, err = models.NormalizeLicense(c.FormValue("license"))
+	return err
 }
 
 func sanitizeSyllabusUpdate(c echo.Context) error {
@@ -497,7 +478,28 @@ func sanitizeSyllabusUpdate(c echo.Context) error {
 		}
 	}
 
-	return nil
+	_, err := models.NormalizeLicense(c.FormValue("license"))
+	return err
+}
+
+// parsePage reads the page_size, cursor, sort and order query params shared by all listings
+func parsePage(c echo.Context) (models.Page, error) {
+	page := models.Page{
+		Size:   models.DEFAULT_PAGE_SIZE,
+		Cursor: strings.TrimSpace(c.QueryParam("cursor")),
+		Sort:   strings.TrimSpace(c.QueryParam("sort")),
+		Order:  strings.ToLower(strings.TrimSpace(c.QueryParam("order"))),
+	}
+
+	if s := strings.TrimSpace(c.QueryParam("page_size")); s != "" {
+		size, err := strconv.Atoi(s)
+		if err != nil || size < 1 || size > models.MAX_PAGE_SIZE {
+			return page, fmt.Errorf("the page size should be between 1 and %d", models.MAX_PAGE_SIZE)
+		}
+		page.Size = size
+	}
+
+	return page, nil
 }
 
 func parseUUIDParam(c echo.Context, tag string) uuid.UUID {
@@ -520,17 +522,12 @@ func parseUUIDForm(c echo.Context, tag string) uuid.UUID {
 	return uid
 }
 
+// mustGetUser returns the user set on the context by the authentication middleware, or uuid.Nil for anonymous requests
 func mustGetUser(c echo.Context) uuid.UUID {
-	if os.Getenv("API_MODE") == "test" {
-		uuid, _ := auth.Authenticate(c)
-		return uuid
-	}
-
-	if c.Get("user_uuid") == nil {
+	user_uuid, ok := c.Get("user_uuid").(uuid.UUID)
+	if !ok {
 		return uuid.Nil
 	}
 
-	id := fmt.Sprintf("%s", c.Get("user_uuid"))
-	uuid := uuid.MustParse(id)
-	return uuid
+	return user_uuid
 }
@@ -82,14 +82,22 @@ func setup(t *testing.T) func(t *testing.T) {
 	}
 }
 
+// newContext returns a context for the request as if it had been authenticated as the given user, uuid.Nil being anonymous
+func newContext(req *http.Request, res *httptest.ResponseRecorder, user_uuid uuid.UUID) echo.Context {
+	c := echo.New().NewContext(req, res)
+	c.Set("user_uuid", user_uuid)
+	return c
+}
+
 type SyllabusResponse struct {
 	Meta struct {
-		AcademicFields []string `json:"academic_fields"`
-		AcademicLevels []string `json:"academic_levels"`
-		AacademicYears []string `json:"academic_years"`
-		Languages      []string `json:"langauges"`
-		TotalPages     int      `json:"total_pages"`
-		TotalSyllabi   int      `json:"total_syllabi"`
+		AcademicFields []string                       `json:"academic_fields"`
+		AcademicLevels []string                       `json:"academic_levels"`
+		AacademicYears []string                       `json:"academic_years"`
+		Languages      []string                       `json:"langauges"`
+		Total          int                            `json:"total"`
+		NextCursor     string                         `json:"next_cursor"`
+		Facets         map[string][]models.FacetCount `json:"facets"`
 	}
 	Syllabi []models.Syllabus
 }
@@ -100,8 +108,8 @@ func TestSyllabusHandler(t *testing.T) {
 
 	t.Run("Test get all syllabi", func(t *testing.T) {
 		res := httptest.NewRecorder()
-		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		c := echo.New().NewContext(req, res)
+		req := httptest.NewRequest(http.MethodGet, "/?page_size=100", nil)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		handlers.GetSyllabi(c)
 		assert.Equal(t, http.StatusOK, res.Code)
@@ -117,7 +125,7 @@ func TestSyllabusHandler(t *testing.T) {
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 
 		handlers.GetSyllabi(c)
 		assert.Equal(t, http.StatusOK, res.Code)
@@ -128,13 +136,114 @@ func TestSyllabusHandler(t *testing.T) {
 		assert.Equal(t, 2, len(resp.Syllabi))
 	})
 
+	t.Run("Test get all syllabi with facets", func(t *testing.T) {
+		q := make(url.Values)
+		q.Set("languages", "de")
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
+		c := newContext(req, res, uuid.Nil)
+
+		handlers.GetSyllabi(c)
+		assert.Equal(t, http.StatusOK, res.Code)
+
+		var resp SyllabusResponse
+		err := json.Unmarshal(res.Body.Bytes(), &resp)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(resp.Syllabi))
+
+		var levels int64
+		for _, f := range resp.Meta.Facets["academic_levels"] {
+			levels += f.Count
+		}
+		assert.Equal(t, int64(resp.Meta.Total), levels)
+		assert.Greater(t, len(resp.Meta.Facets["languages"]), 1, "the languages facet should not be narrowed down by the language filter")
+		assert.Equal(t, len(resp.Meta.Facets["academic_levels"]), len(resp.Meta.AcademicLevels))
+	})
+
+	t.Run("Test get all syllabi with a query", func(t *testing.T) {
+		q := make(url.Values)
+		q.Set("q", `language:de -tag:design`)
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
+		c := newContext(req, res, userID)
+
+		handlers.GetSyllabi(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var resp SyllabusResponse
+		err := json.Unmarshal(res.Body.Bytes(), &resp)
+		require.Nil(t, err)
+		assert.Equal(t, 1, len(resp.Syllabi))
+	})
+
+	t.Run("Test get all syllabi with a malformed query", func(t *testing.T) {
+		q := make(url.Values)
+		q.Set("q", `tag:art (year:2019`)
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
+		c := newContext(req, res, userID)
+
+		handlers.GetSyllabi(c)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		assert.Contains(t, res.Body.String(), "at position 9")
+	})
+
+	t.Run("Test get all syllabi by institution", func(t *testing.T) {
+		q := make(url.Values)
+		q.Set("years", "2021")
+		q.Set("terms", "Fall")
+		q.Set("countries", "DZA")
+
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
+		c := newContext(req, res, userID)
+
+		handlers.GetSyllabi(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var resp SyllabusResponse
+		err := json.Unmarshal(res.Body.Bytes(), &resp)
+		require.Nil(t, err)
+		assert.Equal(t, 3, len(resp.Syllabi))
+	})
+
+	t.Run("Test get all syllabi by malformed institution filters", func(t *testing.T) {
+		for _, q := range []url.Values{{"countries": {"Atlantis"}}, {"years": {"2022..2019"}}, {"years": {"recent"}}} {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
+			c := newContext(req, res, userID)
+
+			handlers.GetSyllabi(c)
+			assert.Equal(t, http.StatusBadRequest, res.Code, q.Encode())
+		}
+	})
+
+	t.Run("Test get all syllabi in academic fields with subfields", func(t *testing.T) {
+		for fields, count := range map[string]int{"22": 0, "22&subfields=true": 3, "21,22&subfields=true": 10, "200&subfields=true": 12} {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/syllabi/?page_size=100&fields="+fields, nil)
+			c := newContext(req, res, userID)
+
+			handlers.GetSyllabi(c)
+			require.Equal(t, http.StatusOK, res.Code)
+
+			var resp SyllabusResponse
+			err := json.Unmarshal(res.Body.Bytes(), &resp)
+			require.Nil(t, err)
+			assert.Equal(t, count, len(resp.Syllabi), fields)
+		}
+	})
+
 	t.Run("Test get all syllabi in wrong academic fields", func(t *testing.T) {
 		q := make(url.Values)
 		q.Set("fields", "666")
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 
 		handlers.GetSyllabi(c)
 		assert.Equal(t, http.StatusBadRequest, res.Code)
@@ -146,7 +255,7 @@ func TestSyllabusHandler(t *testing.T) {
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 
 		handlers.GetSyllabi(c)
 		assert.Equal(t, http.StatusBadRequest, res.Code)
@@ -158,7 +267,7 @@ func TestSyllabusHandler(t *testing.T) {
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		handlers.GetSyllabi(c)
 		assert.Equal(t, http.StatusOK, res.Code)
 
@@ -174,7 +283,7 @@ func TestSyllabusHandler(t *testing.T) {
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		handlers.GetSyllabi(c)
 		assert.Equal(t, http.StatusOK, res.Code)
 
@@ -190,7 +299,7 @@ func TestSyllabusHandler(t *testing.T) {
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 
 		handlers.GetSyllabi(c)
 		assert.Equal(t, http.StatusBadRequest, res.Code)
@@ -202,7 +311,7 @@ func TestSyllabusHandler(t *testing.T) {
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		handlers.GetSyllabi(c)
 		assert.Equal(t, http.StatusOK, res.Code)
 
@@ -218,7 +327,7 @@ func TestSyllabusHandler(t *testing.T) {
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 
 		handlers.GetSyllabi(c)
 		assert.Equal(t, http.StatusBadRequest, res.Code)
@@ -230,7 +339,7 @@ func TestSyllabusHandler(t *testing.T) {
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 
 		handlers.GetSyllabi(c)
 		assert.Equal(t, http.StatusBadRequest, res.Code)
@@ -242,7 +351,7 @@ func TestSyllabusHandler(t *testing.T) {
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 
 		handlers.GetSyllabi(c)
 		assert.Equal(t, http.StatusOK, res.Code)
@@ -260,7 +369,7 @@ func TestSyllabusHandler(t *testing.T) {
 
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 
 		handlers.GetSyllabi(c)
 		assert.Equal(t, http.StatusOK, res.Code)
@@ -271,6 +380,53 @@ func TestSyllabusHandler(t *testing.T) {
 		assert.Equal(t, 1, len(resp.Syllabi))
 	})
 
+	t.Run("Test get all syllabi page by page", func(t *testing.T) {
+		seen := make(map[uuid.UUID]bool)
+		cursor := ""
+		for pages := 0; pages < syllabiCount; pages++ {
+			q := make(url.Values)
+			q.Set("page_size", "6")
+			q.Set("sort", "title")
+			q.Set("cursor", cursor)
+
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+q.Encode(), nil)
+			c := newContext(req, res, userID)
+
+			handlers.GetSyllabi(c)
+			require.Equal(t, http.StatusOK, res.Code)
+
+			var resp SyllabusResponse
+			err := json.Unmarshal(res.Body.Bytes(), &resp)
+			require.Nil(t, err)
+			assert.Equal(t, syllabiCount, resp.Meta.Total)
+			assert.LessOrEqual(t, len(resp.Syllabi), 6)
+
+			for _, s := range resp.Syllabi {
+				assert.False(t, seen[s.UUID], "%s was listed twice", s.UUID)
+				seen[s.UUID] = true
+			}
+
+			cursor = resp.Meta.NextCursor
+			if cursor == "" {
+				break
+			}
+		}
+
+		assert.Equal(t, syllabiCount, len(seen))
+	})
+
+	t.Run("Test get all syllabi with invalid pagination", func(t *testing.T) {
+		for _, query := range []string{"page_size=0", "page_size=1000", "cursor=not-a-cursor", "sort=popularity", "sort=relevance", "order=sideways"} {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/syllabi/?"+query, nil)
+			c := newContext(req, res, userID)
+
+			handlers.GetSyllabi(c)
+			assert.Equal(t, http.StatusBadRequest, res.Code, query)
+		}
+	})
+
 	t.Run("Test create syllabus", func(t *testing.T) {
 		f := make(url.Values)
 		f.Set("title", "Test Syllabus Handling")
@@ -283,7 +439,7 @@ func TestSyllabusHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 
 		handlers.CreateSyllabus(c)
@@ -304,7 +460,7 @@ func TestSyllabusHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 
 		handlers.CreateSyllabus(c)
@@ -320,7 +476,7 @@ func TestSyllabusHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 
 		handlers.CreateSyllabus(c)
@@ -330,7 +486,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test get syllabus", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -347,7 +503,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test get syllabus with listed collections", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -368,7 +524,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test get syllabus by slug", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusSlug)
@@ -385,7 +541,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test get syllabus non-existing ID", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusUnknownID.String())
@@ -397,7 +553,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test get syllabus malformed ID", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues("wrong")
@@ -406,6 +562,55 @@ func TestSyllabusHandler(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, res.Code)
 	})
 
+	t.Run("Test get similar syllabi", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/?limit=3", nil)
+		c := newContext(req, res, userID)
+		c.SetPath("/syllabi/:id/similar")
+		c.SetParamNames("id")
+		c.SetParamValues(syllabusID.String())
+
+		handlers.GetSimilarSyllabi(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var similar []models.SimilarSyllabus
+		err := json.Unmarshal(res.Body.Bytes(), &similar)
+		require.Nil(t, err)
+		require.NotEmpty(t, similar)
+		assert.LessOrEqual(t, len(similar), 3)
+		assert.Equal(t, "Politics of Code", similar[0].Syllabus.Title)
+		assert.Contains(t, similar[0].Reasons, "Also in Education, Arts and humanities")
+		for _, s := range similar {
+			assert.NotEqual(t, syllabusID, s.Syllabus.UUID)
+			assert.Equal(t, "listed", s.Syllabus.Status)
+		}
+	})
+
+	t.Run("Test get similar syllabi with wrong parameters", func(t *testing.T) {
+		cases := []struct {
+			id     string
+			query  string
+			status int
+		}{
+			{syllabusID.String(), "?limit=0", http.StatusBadRequest},
+			{syllabusID.String(), "?limit=1000", http.StatusBadRequest},
+			{"wrong", "", http.StatusBadRequest},
+			{syllabusUnknownID.String(), "", http.StatusNotFound},
+		}
+
+		for _, tc := range cases {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/"+tc.query, nil)
+			c := newContext(req, res, userID)
+			c.SetPath("/syllabi/:id/similar")
+			c.SetParamNames("id")
+			c.SetParamValues(tc.id)
+
+			handlers.GetSimilarSyllabi(c)
+			assert.Equal(t, tc.status, res.Code, tc.id+tc.query)
+		}
+	})
+
 	t.Run("Test update syllabus", func(t *testing.T) {
 		f := make(url.Values)
 		f.Set("title", "Updated Title")
@@ -414,7 +619,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -436,7 +641,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -453,7 +658,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -470,7 +675,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -487,7 +692,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -504,7 +709,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -521,7 +726,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusUnknownID.String())
@@ -538,7 +743,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues("wrong")
@@ -555,7 +760,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -577,7 +782,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusUnknownID.String())
@@ -594,7 +799,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -611,7 +816,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues("000-0-00-0-0lol00")
@@ -628,7 +833,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -640,7 +845,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test get all attachments from syllabus", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi/:id/attachments")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -657,7 +862,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test get attachment from syllabus", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi/:id/attachments/:att_id")
 		c.SetParamNames("id", "att_id")
 		c.SetParamValues(syllabusID.String(), attachmentID.String())
@@ -674,7 +879,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test get non-existing attachment from syllabus", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi/:id/attachments/:att_id")
 		c.SetParamNames("id", "att_id")
 		c.SetParamValues(syllabusID.String(), attachmentUnknownID.String())
@@ -686,7 +891,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test remove attachment from syllabus", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodDelete, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi/:id/attachments/:att_id")
 		c.SetParamNames("id", "att_id")
 		c.SetParamValues(syllabusID.String(), attachmentID.String())
@@ -710,7 +915,7 @@ func TestSyllabusHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi/:id/institutions")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -729,7 +934,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test remove institution from syllabus", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi/:id/institutions/:inst_id")
 		c.SetParamNames("id", "inst_id")
 		c.SetParamValues(syllabusID.String(), newInstID.String())
@@ -746,7 +951,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test delete syllabus", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodDelete, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusID.String())
@@ -758,7 +963,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test delete syllabus malformed ID", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi")
 		c.SetParamNames("id")
 		c.SetParamValues("wrong")
@@ -770,7 +975,7 @@ func TestSyllabusHandler(t *testing.T) {
 	t.Run("Test delete syllabus non-existant ID", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodPost, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/syllabi/:id/institutions")
 		c.SetParamNames("id")
 		c.SetParamValues(syllabusUnknownID.String())
@@ -789,3 +994,22 @@ func mustSeedDB(t *testing.T) {
 	_, err := models.InitDB(databaseTestURL)
 	require.Nil(t, err)
 }
+
+func TestTaxonomyHandler(t *testing.T) {
+	t.Run("Test get fields taxonomy", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/taxonomy/fields", nil)
+		c := newContext(req, res, uuid.Nil)
+
+		handlers.GetFieldsTaxonomy(c)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var fields []models.Field
+		err := json.Unmarshal(res.Body.Bytes(), &fields)
+		require.Nil(t, err)
+		require.Equal(t, 11, len(fields))
+		assert.Equal(t, 500, fields[5].Code)
+		assert.Equal(t, 54, fields[5].Children[3].Code)
+		assert.Equal(t, 541, fields[5].Children[3].Children[0].Code)
+	})
+}
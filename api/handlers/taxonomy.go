@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/labstack/echo/v4"
+)
+
+// GetFieldsTaxonomy returns the tree of the ISCED-F 2013 fields syllabi can be filed under
+func GetFieldsTaxonomy(c echo.Context) error {
+	return c.JSON(http.StatusOK, models.GetFieldsTaxonomy())
+}
+
+// GetLicenses returns the licenses syllabi can be published under, along with what each of them allows
+func GetLicenses(c echo.Context) error {
+	return c.JSON(http.StatusOK, models.LICENSES)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/mail"
@@ -16,13 +17,22 @@ import (
 )
 
 func GetAllUsers(c echo.Context) error {
-	users, err := models.GetAllUsers()
+	page, err := parsePage(c)
 	if err != nil {
 		zero.Error(err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	users, meta, err := models.GetAllUsers(page)
+	if err != nil {
+		zero.Error(err.Error())
+		if errors.Is(err, models.ErrInvalidPage) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
-	return c.JSON(http.StatusOK, users)
+	return c.JSON(http.StatusOK, echo.Map{"users": users, "meta": meta})
 }
 
 func GetUser(c echo.Context) error {
@@ -81,7 +91,7 @@ func CreateUser(c echo.Context) error {
 		return c.String(http.StatusInternalServerError, "There already is a user with this email address. Try to login instead?")
 	}
 
-	token, err := models.CreateToken(user.UUID)
+	token, _, err := models.CreateToken(user.UUID, models.TokenConfirmation)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusInternalServerError, "There was an error completing your account creation. Please try again later.")
@@ -97,7 +107,7 @@ func CreateUser(c echo.Context) error {
 	payload := mailer.ConfirmationPayload{
 		Name:  user.Name,
 		Host:  host,
-		Token: token.UUID.String(),
+		Token: token,
 	}
 
 	if os.Getenv("API_MODE") != "test" {
@@ -112,9 +122,6 @@ func CreateUser(c echo.Context) error {
 
 func UpdateUser(c echo.Context) error {
 	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
 
 	id := c.Param("id")
 	uid, err := uuid.Parse(id)
@@ -142,7 +149,7 @@ func UpdateUser(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "There was an error getting the update data.")
 	}
 
-	updated, err := models.UpdateUser(uid, user_uuid, &user)
+	updated, err := models.UpdateUser(uid, &user)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusInternalServerError, "There was an error updating the user.")
@@ -152,11 +159,6 @@ func UpdateUser(c echo.Context) error {
 }
 
 func AddUserInstitution(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	user_id := c.Param("id")
 	user_uid, err := uuid.Parse(user_id)
 	if err != nil {
@@ -171,7 +173,7 @@ func AddUserInstitution(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "We had a problem binding your information, please check it again.")
 	}
 
-	user, err := models.AddInstitutionToUser(user_uid, user_uuid, &inst)
+	user, err := models.AddInstitutionToUser(user_uid, &inst)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusInternalServerError, "We had a problem adding the Institution to the User profile.")
@@ -181,11 +183,6 @@ func AddUserInstitution(c echo.Context) error {
 }
 
 func EditUserInstitution(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	owner_id := c.Param("id")
 	owner_uuid, err := uuid.Parse(owner_id)
 	if err != nil {
@@ -217,11 +214,6 @@ func EditUserInstitution(c echo.Context) error {
 }
 
 func RemoveUserInstitution(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	user_id := c.Param("id")
 	user_uid, err := uuid.Parse(user_id)
 	if err != nil {
@@ -236,7 +228,7 @@ func RemoveUserInstitution(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, "Not a valid institution ID.")
 	}
 
-	user, err := models.RemoveInstitutionFromUser(user_uid, inst_uid, user_uuid)
+	user, err := models.RemoveInstitutionFromUser(user_uid, inst_uid)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusInternalServerError, "There was an error removing the Institution.")
@@ -246,11 +238,6 @@ func RemoveUserInstitution(c echo.Context) error {
 }
 
 func DeleteUser(c echo.Context) error {
-	user_uuid := mustGetUser(c)
-	if user_uuid == uuid.Nil {
-		return c.String(http.StatusUnauthorized, "unauthorized")
-	}
-
 	id := c.Param("id")
 	uid, err := uuid.Parse(id)
 	if err != nil {
@@ -258,7 +245,7 @@ func DeleteUser(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "Not a valid ID.")
 	}
 
-	user, err := models.DeleteUser(uid, user_uuid)
+	user, err := models.DeleteUser(uid)
 	if err != nil {
 		zero.Error(err.Error())
 		return c.String(http.StatusNotFound, "Error finding the user to delete.")
@@ -27,15 +27,18 @@ func TestUserHandler(t *testing.T) {
 	t.Run("Test get all users", func(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users")
 		handlers.GetAllUsers(c)
 		assert.Equal(t, http.StatusOK, res.Code)
 
-		users := make([]models.User, 0)
-		err := json.Unmarshal(res.Body.Bytes(), &users)
+		var resp struct {
+			Users []models.User   `json:"users"`
+			Meta  models.PageMeta `json:"meta"`
+		}
+		err := json.Unmarshal(res.Body.Bytes(), &resp)
 		require.Nil(t, err)
-		assert.Equal(t, userCount, len(users))
+		assert.Equal(t, userCount, len(resp.Users))
 	})
 
 	t.Run("Test create user", func(t *testing.T) {
@@ -47,7 +50,7 @@ func TestUserHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users")
 		handlers.CreateUser(c)
 		assert.Equal(t, http.StatusCreated, res.Code)
@@ -67,7 +70,7 @@ func TestUserHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users")
 
 		handlers.CreateUser(c)
@@ -83,7 +86,7 @@ func TestUserHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users")
 		handlers.CreateUser(c)
 
@@ -98,7 +101,7 @@ func TestUserHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users")
 		handlers.CreateUser(c)
 
@@ -109,7 +112,7 @@ func TestUserHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users")
 		c.SetParamNames("id")
 		c.SetParamValues(userID.String())
@@ -127,7 +130,7 @@ func TestUserHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users")
 		c.SetParamNames("id")
 		c.SetParamValues(userSlug)
@@ -145,7 +148,7 @@ func TestUserHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users")
 		c.SetParamNames("id")
 		c.SetParamValues("malformed")
@@ -158,7 +161,7 @@ func TestUserHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users")
 		c.SetParamNames("id")
 		c.SetParamValues(userUnknownID.String())
@@ -175,7 +178,7 @@ func TestUserHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users")
 		c.SetParamNames("id")
 		c.SetParamValues(userID.String())
@@ -198,7 +201,7 @@ func TestUserHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users")
 		c.SetParamNames("id")
 		c.SetParamValues(userUnknownID.String())
@@ -217,7 +220,7 @@ func TestUserHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(f.Encode()))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users/:id/institutions")
 		c.SetParamNames("id")
 		c.SetParamValues(userID.String())
@@ -237,7 +240,7 @@ func TestUserHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodDelete, "/", nil)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users/:id/institutions/:inst_id")
 		c.SetParamNames("id", "inst_id")
 		c.SetParamValues(userID.String(), newInstID.String())
@@ -255,7 +258,7 @@ func TestUserHandler(t *testing.T) {
 		res := httptest.NewRecorder()
 		req := httptest.NewRequest(http.MethodDelete, "/", nil)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users/:id")
 		c.SetParamNames("id")
 		c.SetParamValues(userID.String())
@@ -274,7 +277,7 @@ func TestUserHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users/:id")
 		c.SetParamNames("id")
 		c.SetParamValues("wrong")
@@ -288,7 +291,7 @@ func TestUserHandler(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/", nil)
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 
-		c := echo.New().NewContext(req, res)
+		c := newContext(req, res, userID)
 		c.SetPath("/users/:id/institutions")
 		c.SetParamNames("id")
 		c.SetParamValues(userUnknownID.String())
@@ -0,0 +1,138 @@
+package jobs
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/commonsyllabi/explorer/mailer"
+)
+
+// digestExcerptLength is the number of characters of the description of each syllabus shown in a digest
+const digestExcerptLength = 200
+
+// sendSearchDigests emails the users whose saved searches are due the syllabi listed since their previous digest.
+// Each search is claimed before its digest is sent, so that instances running at the same time don't send it twice,
+// and a digest which fails to send is given back to be tried again on the next run.
+func sendSearchDigests() error {
+	// -- the digests end where the next ones start, at the precision postgres keeps
+	now := time.Now().Truncate(time.Microsecond)
+
+	searches, err := models.GetDueSavedSearches(now)
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	for _, s := range searches {
+		claimed, err := models.ClaimSavedSearch(s, now)
+		if err != nil {
+			zero.Errorf("error claiming saved search %s: %v", s.UUID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		ok, err := sendSearchDigest(s, now)
+		if err != nil {
+			zero.Errorf("error sending the digest of saved search %s: %v", s.UUID, err)
+			err = models.ReleaseSavedSearch(s, now)
+			if err != nil {
+				zero.Errorf("error releasing saved search %s: %v", s.UUID, err)
+			}
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	zero.Infof("sent %d search digests out of %d saved searches due", sent, len(searches))
+	return nil
+}
+
+// sendSearchDigest runs the saved search again over the syllabi listed since its last digest, and emails those it finds.
+// The search was claimed as sent even if nothing new matches, so that the next digest starts from now.
+func sendSearchDigest(search models.SavedSearch, now time.Time) (bool, error) {
+	values, err := url.ParseQuery(search.Query)
+	if err != nil {
+		return false, err
+	}
+
+	params, err := models.ParseSearchParams(values)
+	if err != nil {
+		return false, err
+	}
+	params["listed_between"] = [2]time.Time{search.LastSentAt, now}
+
+	syllabi, meta, err := models.GetSyllabi(params, models.Page{Size: models.MAX_DIGEST_SYLLABI, Sort: models.SortCreated}, search.UserUUID)
+	if err != nil {
+		return false, err
+	}
+
+	if len(syllabi) > 0 {
+		payload := mailer.DigestPayload{
+			Name:        search.User.Name,
+			Host:        frontendHost(),
+			Search:      search.Name,
+			Syllabi:     make([]mailer.DigestSyllabus, 0, len(syllabi)),
+			More:        int(meta.Total) - len(syllabi),
+			Unsubscribe: fmt.Sprintf("%s/searches/unsubscribe?token=%s", apiHost(), url.QueryEscape(search.UnsubscribeToken)),
+		}
+		for _, s := range syllabi {
+			payload.Syllabi = append(payload.Syllabi, mailer.DigestSyllabus{
+				UUID:        s.UUID.String(),
+				Title:       s.Title,
+				Description: excerpt(s.Description, digestExcerptLength),
+			})
+		}
+
+		err = mailer.SendMail(search.User.Email, fmt.Sprintf("New syllabi for %s", search.Name), "search_digest", payload)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return len(syllabi) > 0, nil
+}
+
+// frontendHost is where the links to syllabi point to
+func frontendHost() string {
+	if os.Getenv("API_MODE") == "release" {
+		return "https://cosyll.org"
+	}
+	return "http://localhost:3000"
+}
+
+// apiHost is where the unsubscribe links point to, since they are handled by the API itself
+func apiHost() string {
+	if host := os.Getenv("API_URL"); host != "" {
+		return strings.TrimSuffix(host, "/")
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	return fmt.Sprintf("http://localhost:%s", port)
+}
+
+// excerpt shortens a text to at most n characters, cutting it at the last word which fits
+func excerpt(text string, n int) string {
+	text = strings.TrimSpace(text)
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+
+	cut := string(runes[:n])
+	if i := strings.LastIndexAny(cut, " \n\t"); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "..."
+}
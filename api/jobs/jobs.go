@@ -0,0 +1,93 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/commonsyllabi/explorer/api/models"
+)
+
+// Job is a task which runs in the background at a fixed interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() error
+}
+
+var registry = []Job{
+	{Name: "purge expired tokens", Interval: time.Hour, Run: purgeExpiredTokens},
+	{Name: "purge expired rate limits", Interval: 15 * time.Minute, Run: purgeExpiredRateLimits},
+	{Name: "send search digests", Interval: time.Hour, Run: sendSearchDigests},
+}
+
+// Start runs every registered job once, then on its interval, until the context is cancelled
+func Start(ctx context.Context) {
+	for _, j := range registry {
+		go schedule(ctx, j)
+	}
+}
+
+func schedule(ctx context.Context, j Job) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		run(j)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func run(j Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			zero.Errorf("job %s panicked: %v", j.Name, r)
+		}
+	}()
+
+	zero.Debugf("running job %s", j.Name)
+	err := j.Run()
+	if err != nil {
+		zero.Errorf("error running job %s: %v", j.Name, err)
+	}
+}
+
+func purgeExpiredTokens() error {
+	tokens, err := models.PurgeExpiredTokens()
+	if err != nil {
+		return err
+	}
+
+	refresh, err := models.PurgeExpiredRefreshTokens()
+	if err != nil {
+		return err
+	}
+
+	states, err := models.PurgeExpiredOIDCStates()
+	if err != nil {
+		return err
+	}
+
+	api, err := models.PurgeExpiredAPITokens()
+	if err != nil {
+		return err
+	}
+
+	zero.Infof("purged %d expired tokens, %d expired refresh tokens, %d expired login requests and %d expired API tokens", tokens, refresh, states, api)
+	return nil
+}
+
+func purgeExpiredRateLimits() error {
+	limits, err := models.PurgeExpiredRateLimits()
+	if err != nil {
+		return err
+	}
+
+	zero.Infof("purged %d expired rate limits", limits)
+	return nil
+}
@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule(t *testing.T) {
+	t.Run("Test job runs on start and on interval", func(t *testing.T) {
+		var count int32
+		j := Job{Name: "count", Interval: 10 * time.Millisecond, Run: func() error {
+			atomic.AddInt32(&count, 1)
+			return nil
+		}}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+		defer cancel()
+		schedule(ctx, j)
+
+		assert.GreaterOrEqual(t, atomic.LoadInt32(&count), int32(3))
+	})
+
+	t.Run("Test failing job keeps running", func(t *testing.T) {
+		var count int32
+		j := Job{Name: "fail", Interval: 10 * time.Millisecond, Run: func() error {
+			if atomic.AddInt32(&count, 1) == 1 {
+				panic("first run")
+			}
+			return errors.New("next runs")
+		}}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 35*time.Millisecond)
+		defer cancel()
+		schedule(ctx, j)
+
+		assert.GreaterOrEqual(t, atomic.LoadInt32(&count), int32(2))
+	})
+}
+
+func TestExcerpt(t *testing.T) {
+	assert.Equal(t, "A short course.", excerpt(" A short course. ", 20))
+	assert.Equal(t, "A longer course...", excerpt("A longer course, about code.", 20))
+	assert.Equal(t, "Ungewöhnt...", excerpt("Ungewöhnt Ungewöhnt", 12))
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrAccessDenied is returned when a request reaches for a resource beyond the one its route is authorized against
+var ErrAccessDenied = errors.New("the user has no rights over the resource")
+
+// Access lists who holds rights over a resource, for the authorization policies to check against
+type Access struct {
+	Owner         uuid.UUID
+	Collaborators []uuid.UUID
+}
+
+// IsOwner checks whether the user owns the resource
+func (a Access) IsOwner(user_uuid uuid.UUID) bool {
+	return user_uuid != uuid.Nil && a.Owner == user_uuid
+}
+
+// IsCollaborator checks whether the user has been invited to edit the resource
+func (a Access) IsCollaborator(user_uuid uuid.UUID) bool {
+	if user_uuid == uuid.Nil {
+		return false
+	}
+
+	for _, c := range a.Collaborators {
+		if c == user_uuid {
+			return true
+		}
+	}
+	return false
+}
+
+func GetSyllabusAccess(uuid uuid.UUID) (Access, error) {
+	var access Access
+	var syll Syllabus
+	result := db.Select("id", "uuid", "user_uuid").Where("uuid = ?", uuid).First(&syll)
+	if result.Error != nil {
+		return access, result.Error
+	}
+	access.Owner = syll.UserUUID
+
+	var collaborators []User
+	err := db.Model(&syll).Association("Collaborators").Find(&collaborators)
+	if err != nil {
+		return access, err
+	}
+
+	for _, c := range collaborators {
+		access.Collaborators = append(access.Collaborators, c.UUID)
+	}
+
+	return access, nil
+}
+
+// canEditSyllabus checks whether the user owns the syllabus, collaborates on it or is an admin
+func canEditSyllabus(syll_uuid uuid.UUID, user_uuid uuid.UUID) (bool, error) {
+	access, err := GetSyllabusAccess(syll_uuid)
+	if err != nil {
+		return false, err
+	}
+
+	if access.IsOwner(user_uuid) || access.IsCollaborator(user_uuid) {
+		return true, nil
+	}
+
+	var user User
+	result := db.Select("uuid", "role").Where("uuid = ?", user_uuid).First(&user)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return HasRole(user.Role, RoleAdmin), nil
+}
+
+func GetCollectionAccess(uuid uuid.UUID) (Access, error) {
+	var coll Collection
+	result := db.Select("uuid", "user_uuid").Where("uuid = ?", uuid).First(&coll)
+	return Access{Owner: coll.UserUUID}, result.Error
+}
+
+// GetAttachmentAccess returns the access to the syllabus the attachment belongs to
+func GetAttachmentAccess(uuid uuid.UUID) (Access, error) {
+	var att Attachment
+	result := db.Select("uuid", "syllabus_uuid").Where("uuid = ?", uuid).First(&att)
+	if result.Error != nil {
+		return Access{}, result.Error
+	}
+
+	return GetSyllabusAccess(att.SyllabusUUID)
+}
+
+// GetUserAccess returns the access to a user account, which is only owned by the user themselves
+func GetUserAccess(uuid uuid.UUID) (Access, error) {
+	var user User
+	result := db.Select("uuid").Where("uuid = ?", uuid).First(&user)
+	return Access{Owner: user.UUID}, result.Error
+}
+
+// GetAPITokenAccess returns the access to an API token, which is owned by the user it acts for
+func GetAPITokenAccess(uuid uuid.UUID) (Access, error) {
+	var token APIToken
+	result := db.Select("uuid", "user_uuid").Where("uuid = ?", uuid).First(&token)
+	return Access{Owner: token.UserUUID}, result.Error
+}
+
+// GetSavedSearchAccess returns the access to a saved search, which is owned by the user who saved it
+func GetSavedSearchAccess(uuid uuid.UUID) (Access, error) {
+	var search SavedSearch
+	result := db.Select("uuid", "user_uuid").Where("uuid = ?", uuid).First(&search)
+	return Access{Owner: search.UserUUID}, result.Error
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	ScopeRead             string = "read"
+	ScopeWriteSyllabi     string = "write:syllabi"
+	ScopeWriteCollections string = "write:collections"
+)
+
+// SCOPES describes what each scope of an API token allows
+var SCOPES = map[string]string{
+	ScopeRead:             "Read syllabi, collections, attachments and users",
+	ScopeWriteSyllabi:     "Create, edit and delete syllabi and their attachments",
+	ScopeWriteCollections: "Create, edit and delete collections",
+}
+
+// APITokenPrefix starts every API token, so that they can be told apart from access tokens, and found if they leak
+const APITokenPrefix = "cosyll_"
+
+// apiTokenTouchEvery is how often the last use of a token is recorded, to avoid a write on every request
+const apiTokenTouchEvery = time.Minute
+
+var (
+	ErrInvalidAPIToken = errors.New("the API token is invalid")
+	ErrAPITokenInvalid = errors.New("the API token is unknown, expired or revoked")
+)
+
+// APIToken is a long-lived credential used by scripts to act on behalf of a user, within the limits of its scopes.
+// Only the hash of the token is stored, the token itself is only shown once, when it is created.
+type APIToken struct {
+	ID         uint           `gorm:"primaryKey" json:"-"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	UUID       uuid.UUID      `gorm:"uniqueIndex;type:uuid;primaryKey;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
+	UserUUID   uuid.UUID      `gorm:"type:uuid;index;not null" json:"user_uuid"`
+	Name       string         `gorm:"not null" json:"name"`
+	Scopes     pq.StringArray `gorm:"type:text[]" json:"scopes"`
+	Hash       string         `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt  *time.Time     `json:"expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+}
+
+// CreateAPIToken creates a named token for the user, and returns the clear token along with its record. A nil expiry never expires.
+func CreateAPIToken(user_uuid uuid.UUID, name string, scopes []string, expires_at *time.Time) (string, APIToken, error) {
+	var token APIToken
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", token, fmt.Errorf("%w: the name can't be empty", ErrInvalidAPIToken)
+	}
+
+	if len(scopes) == 0 {
+		return "", token, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIToken)
+	}
+
+	for _, s := range scopes {
+		if _, found := SCOPES[s]; !found {
+			return "", token, fmt.Errorf("%w: unknown scope %s", ErrInvalidAPIToken, s)
+		}
+	}
+
+	if expires_at != nil && !expires_at.After(time.Now()) {
+		return "", token, fmt.Errorf("%w: the expiry is in the past", ErrInvalidAPIToken)
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return "", token, err
+	}
+	secret = APITokenPrefix + secret
+
+	token = APIToken{
+		UUID:      uuid.New(),
+		UserUUID:  user_uuid,
+		Name:      name,
+		Scopes:    scopes,
+		Hash:      hashSecret(secret),
+		ExpiresAt: expires_at,
+	}
+	result := db.Create(&token)
+	return secret, token, result.Error
+}
+
+// GetAPITokens lists the tokens of the user, including the expired ones
+func GetAPITokens(user_uuid uuid.UUID) ([]APIToken, error) {
+	tokens := make([]APIToken, 0)
+	result := db.Where("user_uuid = ?", user_uuid).Order("created_at DESC").Find(&tokens)
+	return tokens, result.Error
+}
+
+// RevokeAPIToken deletes the token, after which it can't be used anymore
+func RevokeAPIToken(token_uuid uuid.UUID) error {
+	result := db.Where("uuid = ?", token_uuid).Delete(&APIToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrAPITokenInvalid
+	}
+	return nil
+}
+
+// AuthenticateAPIToken returns the token matching the secret and its user, as long as the token hasn't expired
+// and the account is still confirmed. It also records when the token was last used.
+func AuthenticateAPIToken(secret string) (User, APIToken, error) {
+	var user User
+	var token APIToken
+	now := time.Now()
+
+	result := db.Where("hash = ? AND (expires_at IS NULL OR expires_at > ?)", hashSecret(secret), now).Limit(1).Find(&token)
+	if result.Error != nil {
+		return user, token, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return user, token, ErrAPITokenInvalid
+	}
+
+	result = db.Where("uuid = ?", token.UserUUID).First(&user)
+	if result.Error != nil {
+		return user, token, result.Error
+	}
+
+	if user.Status != UserConfirmed {
+		return user, token, fmt.Errorf("%w: user %s is %s", ErrAPITokenInvalid, user.UUID, user.Status)
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > apiTokenTouchEvery {
+		result = db.Model(&APIToken{}).Where("uuid = ?", token.UUID).UpdateColumn("last_used_at", now)
+		if result.Error != nil {
+			return user, token, result.Error
+		}
+		token.LastUsedAt = &now
+	}
+
+	return user, token, nil
+}
+
+// PurgeExpiredAPITokens deletes all tokens which have expired
+func PurgeExpiredAPITokens() (int64, error) {
+	result := db.Where("expires_at < ?", time.Now()).Delete(&APIToken{})
+	return result.RowsAffected, result.Error
+}
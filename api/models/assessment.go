@@ -0,0 +1,422 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	MAX_ASSESSMENTS                   = 50
+	MAX_ASSESSMENT_DESCRIPTION_LENGTH = 1000
+)
+
+const (
+	AssessmentExam          string = "exam"
+	AssessmentQuiz          string = "quiz"
+	AssessmentEssay         string = "essay"
+	AssessmentProject       string = "project"
+	AssessmentPresentation  string = "presentation"
+	AssessmentParticipation string = "participation"
+	AssessmentHomework      string = "homework"
+	AssessmentLab           string = "lab"
+	AssessmentPortfolio     string = "portfolio"
+	AssessmentOther         string = "other"
+)
+
+var ASSESSMENT_TYPES = map[string]string{
+	AssessmentExam:          "Exam",
+	AssessmentQuiz:          "Quiz",
+	AssessmentEssay:         "Essay",
+	AssessmentProject:       "Project",
+	AssessmentPresentation:  "Presentation",
+	AssessmentParticipation: "Participation",
+	AssessmentHomework:      "Homework",
+	AssessmentLab:           "Lab",
+	AssessmentPortfolio:     "Portfolio",
+	AssessmentOther:         "Other",
+}
+
+var ErrInvalidAssessment = errors.New("the assessments are invalid")
+
+var (
+	weightPattern  = regexp.MustCompile(`(\d{1,3}(?:[.,]\d+)?)\s*%`)
+	dueWeekPattern = regexp.MustCompile(`(?i)\b(?:week|woche|semaine|semana)\s+(\d{1,2})\b`)
+)
+
+// -- the type of a free-text assessment is told from the first of these words it holds, in this order, so that
+// a "final project" is a project and a "mid-term essay" an essay rather than exams
+var assessmentKeywords = []struct {
+	Type  string
+	Words []string
+}{
+	{AssessmentParticipation, []string{"participation", "attendance", "engagement"}},
+	{AssessmentPresentation, []string{"presentation", "pitch", "talk"}},
+	{AssessmentProject, []string{"project", "capstone", "prototype"}},
+	{AssessmentPortfolio, []string{"portfolio", "blog", "journal"}},
+	{AssessmentLab, []string{"lab", "laboratory"}},
+	{AssessmentQuiz, []string{"quiz", "quizzes"}},
+	{AssessmentEssay, []string{"essay", "essays", "paper", "papers", "response", "reflection", "reflections", "analysis", "review", "report", "reports", "writing"}},
+	{AssessmentExam, []string{"exam", "examination", "midterm", "mid-term", "final", "test", "tests", "interview"}},
+	{AssessmentHomework, []string{"homework", "assignment", "assignments", "exercise", "exercises", "problem"}},
+}
+
+// Assessment is a part of the grade of a syllabus, such as an exam or an essay, along with its weight in percents
+// and the week it is due
+type Assessment struct {
+	ID          uint           `gorm:"primaryKey" json:"-"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	UUID        uuid.UUID      `gorm:"uniqueIndex;type:uuid;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
+	SyllabusID  uint           `gorm:"index;not null" json:"-"`
+	Position    int            `gorm:"not null;default:0" json:"position"`
+	Type        string         `gorm:"not null;default:other" json:"type"`
+	Weight      *float64       `json:"weight"`
+	DueWeek     *int           `json:"due_week"`
+	Description string         `json:"description"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// AssessmentShare is how much a type of assessment weighs in the syllabi of a field: its average weight, the syllabi which
+// don't use it counting as 0, and the share of syllabi which use it
+type AssessmentShare struct {
+	Type   string  `json:"type"`
+	Weight float64 `json:"weight"`
+	Share  float64 `json:"share"`
+}
+
+// AssessmentMix is the typical assessment of the listed syllabi of a field, out of the ones whose assessments are weighted
+type AssessmentMix struct {
+	Field   int               `json:"field"`
+	Name    string            `json:"name"`
+	Syllabi int               `json:"syllabi"`
+	Types   []AssessmentShare `json:"types"`
+}
+
+// ParseAssessment reads a free-text assessment, such as the assignments of a syllabus or the assessment strategy found by
+// OpenSyllabus. Its weight is the first percentage it holds, and its due week any week it mentions, as in
+//
+//	Mid-Term Docu-Fiction Essay (20%), due week 7
+func ParseAssessment(text string) (Assessment, bool) {
+	description := strings.Trim(strings.Join(strings.Fields(text), " "), " .,;:")
+	if description == "" {
+		return Assessment{}, false
+	}
+
+	a := Assessment{Type: AssessmentOther, Description: description}
+
+	if m := weightPattern.FindStringSubmatch(description); m != nil {
+		if w, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64); err == nil && w > 0 && w <= 100 {
+			a.Weight = &w
+		}
+	}
+
+	if m := dueWeekPattern.FindStringSubmatch(description); m != nil {
+		if week, _ := strconv.Atoi(m[1]); week >= 1 && week <= MAX_SESSION_WEEK {
+			a.DueWeek = &week
+		}
+	}
+
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !(r == '-' || ('a' <= r && r <= 'z'))
+	}) {
+		words[w] = true
+	}
+
+	for _, k := range assessmentKeywords {
+		for _, w := range k.Words {
+			if words[w] {
+				a.Type = k.Type
+				return a, true
+			}
+		}
+	}
+	return a, true
+}
+
+// ParseAssessments reads free-text assessments, and only keeps their weights if they add up to 100
+func ParseAssessments(texts []string) []Assessment {
+	assessments := make([]Assessment, 0)
+	for _, t := range texts {
+		if a, ok := ParseAssessment(t); ok {
+			a.Position = len(assessments)
+			assessments = append(assessments, a)
+		}
+	}
+
+	if checkWeights(assessments) != nil {
+		for i := range assessments {
+			assessments[i].Weight = nil
+		}
+	}
+	return assessments
+}
+
+// CheckAssessments checks the assessments of a syllabus. Weights are optional, but when they are given they must be given
+// for every assessment and add up to 100.
+func CheckAssessments(assessments []Assessment) error {
+	if len(assessments) > MAX_ASSESSMENTS {
+		return fmt.Errorf("%w: a syllabus can't have more than %d assessments", ErrInvalidAssessment, MAX_ASSESSMENTS)
+	}
+
+	for i, a := range assessments {
+		if _, found := ASSESSMENT_TYPES[a.Type]; !found {
+			return fmt.Errorf("%w: unknown type %q for assessment %d", ErrInvalidAssessment, a.Type, i+1)
+		}
+		if a.Weight != nil && (*a.Weight <= 0 || *a.Weight > 100) {
+			return fmt.Errorf("%w: the weight of assessment %d should be between 0 and 100", ErrInvalidAssessment, i+1)
+		}
+		if a.DueWeek != nil && (*a.DueWeek < 1 || *a.DueWeek > MAX_SESSION_WEEK) {
+			return fmt.Errorf("%w: the due week of assessment %d should be between 1 and %d", ErrInvalidAssessment, i+1, MAX_SESSION_WEEK)
+		}
+		if len([]rune(a.Description)) > MAX_ASSESSMENT_DESCRIPTION_LENGTH {
+			return fmt.Errorf("%w: the description of assessment %d can't be longer than %d characters", ErrInvalidAssessment, i+1, MAX_ASSESSMENT_DESCRIPTION_LENGTH)
+		}
+	}
+
+	return checkWeights(assessments)
+}
+
+func checkWeights(assessments []Assessment) error {
+	weighted, total := 0, 0.0
+	for _, a := range assessments {
+		if a.Weight != nil {
+			weighted++
+			total += *a.Weight
+		}
+	}
+
+	switch {
+	case weighted == 0:
+		return nil
+	case weighted < len(assessments):
+		return fmt.Errorf("%w: weights should be given for every assessment or none", ErrInvalidAssessment)
+	case math.Abs(total-100) > 0.01:
+		return fmt.Errorf("%w: the weights add up to %g instead of 100", ErrInvalidAssessment, total)
+	}
+	return nil
+}
+
+// replaceAssessments sets the assessments of a syllabus, in their order
+func replaceAssessments(tx *gorm.DB, syll_id uint, assessments []Assessment) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("syllabus_id = ?", syll_id).Delete(&Assessment{}).Error
+		if err != nil {
+			return err
+		}
+
+		if len(assessments) == 0 {
+			return nil
+		}
+
+		created := make([]Assessment, 0, len(assessments))
+		for i, a := range assessments {
+			created = append(created, Assessment{
+				UUID:        uuid.New(),
+				SyllabusID:  syll_id,
+				Position:    i,
+				Type:        a.Type,
+				Weight:      a.Weight,
+				DueWeek:     a.DueWeek,
+				Description: strings.TrimSpace(a.Description),
+			})
+		}
+		return tx.Create(&created).Error
+	})
+}
+
+// seedAssessments sets the assessments of a syllabus which never had any from its free-text assignments
+func seedAssessments(tx *gorm.DB, syll_id uint, assignments []string) error {
+	var count int64
+	err := tx.Unscoped().Model(&Assessment{}).Where("syllabus_id = ?", syll_id).Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+
+	assessments := ParseAssessments(assignments)
+	if len(assessments) > MAX_ASSESSMENTS {
+		assessments = assessments[:MAX_ASSESSMENTS]
+	}
+	return replaceAssessments(tx, syll_id, assessments)
+}
+
+// seedMissingAssessments sets the assessments of the syllabi which never had any from their assignments, such as the ones
+// created before assessments
+func seedMissingAssessments(db *gorm.DB) error {
+	var sylls []Syllabus
+	err := db.Select("id", "assignments").
+		Where("cardinality(assignments) > 0 AND NOT EXISTS (SELECT 1 FROM assessments WHERE assessments.syllabus_id = syllabi.id)").
+		Find(&sylls).Error
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sylls {
+		if err := seedAssessments(db, s.ID, s.Assignments); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyAssessments replaces the assessments of a syllabus with the ones of another, such as the one it was forked from
+func copyAssessments(tx *gorm.DB, from_id uint, to_id uint) error {
+	var assessments []Assessment
+	err := tx.Where("syllabus_id = ?", from_id).Order("position ASC, id ASC").Find(&assessments).Error
+	if err != nil || len(assessments) == 0 {
+		return err
+	}
+	return replaceAssessments(tx, to_id, assessments)
+}
+
+// GetAssessments lists the assessments of a syllabus visible to the user, in order
+func GetAssessments(syll_uuid uuid.UUID, user_uuid uuid.UUID) ([]Assessment, error) {
+	assessments := make([]Assessment, 0)
+
+	syll_id, err := visibleSyllabusID(syll_uuid, user_uuid)
+	if err != nil {
+		return assessments, err
+	}
+
+	err = db.Where("syllabus_id = ?", syll_id).Order("position ASC, id ASC").Find(&assessments).Error
+	return assessments, err
+}
+
+// UpdateAssessments replaces the assessments of a syllabus, once checked
+func UpdateAssessments(syll_uuid uuid.UUID, assessments []Assessment) ([]Assessment, error) {
+	var syll Syllabus
+	err := db.Where("uuid = ?", syll_uuid).First(&syll).Error
+	if err != nil {
+		return assessments, err
+	}
+
+	err = CheckAssessments(assessments)
+	if err != nil {
+		return assessments, err
+	}
+
+	err = replaceAssessments(db, syll.ID, assessments)
+	if err != nil {
+		return assessments, err
+	}
+
+	updated := make([]Assessment, 0)
+	err = db.Where("syllabus_id = ?", syll.ID).Order("position ASC, id ASC").Find(&updated).Error
+	return updated, err
+}
+
+// GetAssessmentMix reports the typical assessment of the listed syllabi in each of the fields, or in each field of the level,
+// broad by default, if there are none. The syllabi of a field include the ones of the fields it is divided into.
+func GetAssessmentMix(fields []int, level string) ([]AssessmentMix, error) {
+	mixes := make([]AssessmentMix, 0)
+
+	switch level {
+	case "":
+		level = FieldBroad
+	case FieldBroad, FieldNarrow, FieldDetailed:
+	default:
+		return mixes, fmt.Errorf("%w: unknown field level %q", ErrInvalidAssessment, level)
+	}
+
+	targets := fields
+	if len(targets) == 0 {
+		for code := range ACADEMIC_FIELDS {
+			if FieldLevel(code) == level {
+				targets = append(targets, code)
+			}
+		}
+	}
+
+	// -- each field stands for itself and the fields it is divided into, which a syllabus is matched on
+	codes, tops := make([]int, 0), make([]int, 0)
+	for _, target := range targets {
+		for _, code := range FieldDescendants(target) {
+			codes, tops = append(codes, code), append(tops, target)
+		}
+	}
+	if len(codes) == 0 {
+		return mixes, nil
+	}
+
+	// -- only the syllabi whose assessments are weighted tell the weight of each type, and a syllabus counts once in a field
+	// even when several of its fields belong to it
+	var rows []struct {
+		Field   int
+		Type    string
+		Weight  float64
+		Users   int
+		Syllabi int
+	}
+	err := db.Raw(`
+		WITH fields AS (
+			SELECT * FROM unnest(?::integer[], ?::integer[]) AS fields(code, target)
+		), syllabus_fields AS (
+			SELECT DISTINCT syllabi.id AS syllabus_id, fields.target AS field
+			FROM syllabi
+			CROSS JOIN LATERAL unnest(syllabi.academic_fields) AS syllabus_field
+			JOIN fields ON fields.code = syllabus_field
+			WHERE syllabi.deleted_at IS NULL AND syllabi.status = 'listed' AND syllabi.academic_fields && ?
+		), weights AS (
+			SELECT assessments.syllabus_id, assessments.type, sum(assessments.weight) AS weight
+			FROM assessments
+			WHERE assessments.deleted_at IS NULL AND assessments.weight IS NOT NULL
+			AND assessments.syllabus_id IN (SELECT syllabus_id FROM syllabus_fields)
+			GROUP BY assessments.syllabus_id, assessments.type
+		), counts AS (
+			SELECT syllabus_fields.field, count(DISTINCT syllabus_fields.syllabus_id) AS syllabi
+			FROM syllabus_fields
+			WHERE syllabus_fields.syllabus_id IN (SELECT syllabus_id FROM weights)
+			GROUP BY syllabus_fields.field
+		)
+		SELECT syllabus_fields.field, weights.type, sum(weights.weight) AS weight, count(*) AS users, counts.syllabi
+		FROM syllabus_fields
+		JOIN weights ON weights.syllabus_id = syllabus_fields.syllabus_id
+		JOIN counts ON counts.field = syllabus_fields.field
+		GROUP BY syllabus_fields.field, weights.type, counts.syllabi`,
+		fieldCodes(codes), fieldCodes(tops), fieldCodes(codes)).
+		Scan(&rows).Error
+	if err != nil {
+		return mixes, err
+	}
+
+	byField := make(map[int]*AssessmentMix)
+	for _, r := range rows {
+		mix, found := byField[r.Field]
+		if !found {
+			mix = &AssessmentMix{Field: r.Field, Name: ACADEMIC_FIELDS[r.Field], Syllabi: r.Syllabi, Types: make([]AssessmentShare, 0)}
+			byField[r.Field] = mix
+		}
+		mix.Types = append(mix.Types, AssessmentShare{
+			Type:   r.Type,
+			Weight: math.Round(r.Weight/float64(r.Syllabi)*100) / 100,
+			Share:  math.Round(float64(r.Users)/float64(r.Syllabi)*1000) / 1000,
+		})
+	}
+
+	for _, mix := range byField {
+		sort.Slice(mix.Types, func(i, j int) bool {
+			if mix.Types[i].Weight != mix.Types[j].Weight {
+				return mix.Types[i].Weight > mix.Types[j].Weight
+			}
+			return mix.Types[i].Type < mix.Types[j].Type
+		})
+		mixes = append(mixes, *mix)
+	}
+
+	sort.Slice(mixes, func(i, j int) bool {
+		if mixes[i].Syllabi != mixes[j].Syllabi {
+			return mixes[i].Syllabi > mixes[j].Syllabi
+		}
+		return mixes[i].Field < mixes[j].Field
+	})
+	return mixes, nil
+}
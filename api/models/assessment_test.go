@@ -0,0 +1,164 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssessmentModel(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	musicID := uuid.MustParse("46de6a2b-666b-4c24-b1e1-3595821f8469")
+
+	t.Run("Test assessments seeded from assignments", func(t *testing.T) {
+		assessments, err := models.GetAssessments(musicID, uuid.Nil)
+		require.Nil(t, err)
+		require.Equal(t, 3, len(assessments))
+		assert.Equal(t, models.AssessmentParticipation, assessments[0].Type)
+		assert.Equal(t, models.AssessmentHomework, assessments[1].Type)
+		assert.Equal(t, models.AssessmentExam, assessments[2].Type)
+		require.NotNil(t, assessments[1].Weight)
+		assert.Equal(t, 40.0, *assessments[1].Weight)
+	})
+
+	t.Run("Test assessments seeded without partial weights", func(t *testing.T) {
+		syll, err := models.CreateSyllabus(&models.Syllabus{
+			UUID:        uuid.New(),
+			Title:       "Assessments",
+			Description: "A syllabus whose weights don't add up",
+			Assignments: []string{"Essay (50%)", "Final exam, week 14"},
+		}, userID)
+		require.Nil(t, err)
+
+		assessments, err := models.GetAssessments(syll.UUID, userID)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(assessments))
+		assert.Nil(t, assessments[0].Weight)
+		require.NotNil(t, assessments[1].DueWeek)
+		assert.Equal(t, 14, *assessments[1].DueWeek)
+	})
+
+	t.Run("Test update assessments", func(t *testing.T) {
+		sixty, forty, week := 60.0, 40.0, 8
+		updated, err := models.UpdateAssessments(syllabusID, []models.Assessment{
+			{Type: models.AssessmentProject, Weight: &sixty, DueWeek: &week, Description: "Neighbourhood map"},
+			{Type: models.AssessmentEssay, Weight: &forty},
+		})
+		require.Nil(t, err)
+		require.Equal(t, 2, len(updated))
+		assert.Equal(t, models.AssessmentProject, updated[0].Type)
+		assert.Equal(t, 1, updated[1].Position)
+
+		assessments, err := models.GetAssessments(syllabusID, userID)
+		require.Nil(t, err)
+		assert.Equal(t, 2, len(assessments))
+	})
+
+	t.Run("Test update assessments clears them", func(t *testing.T) {
+		updated, err := models.UpdateAssessments(syllabusID, []models.Assessment{})
+		require.Nil(t, err)
+		assert.Equal(t, 0, len(updated))
+	})
+
+	t.Run("Test update assessments malformed", func(t *testing.T) {
+		sixty, week := 60.0, 0
+		for _, assessments := range [][]models.Assessment{
+			{{Type: "thesis"}},
+			{{Type: models.AssessmentExam, Weight: &sixty}},
+			{{Type: models.AssessmentExam, Weight: &sixty}, {Type: models.AssessmentEssay}},
+			{{Type: models.AssessmentExam, DueWeek: &week}},
+		} {
+			_, err := models.UpdateAssessments(syllabusID, assessments)
+			assert.True(t, errors.Is(err, models.ErrInvalidAssessment))
+		}
+	})
+
+	t.Run("Test update assessments non-existing", func(t *testing.T) {
+		_, err := models.UpdateAssessments(uuid.New(), []models.Assessment{})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test get assessments unlisted", func(t *testing.T) {
+		_, err := models.GetAssessments(syllabusUnlistedID, userDeleteID)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test fork copies assessments", func(t *testing.T) {
+		fork, err := models.ForkSyllabus(musicID, userID)
+		require.Nil(t, err)
+
+		assessments, err := models.GetAssessments(fork.UUID, userID)
+		require.Nil(t, err)
+		assert.Equal(t, 3, len(assessments))
+	})
+
+	t.Run("Test assessment mix", func(t *testing.T) {
+		mixes, err := models.GetAssessmentMix([]int{215}, "")
+		require.Nil(t, err)
+		require.Equal(t, 1, len(mixes))
+		assert.Equal(t, 215, mixes[0].Field)
+		assert.GreaterOrEqual(t, mixes[0].Syllabi, 1)
+
+		types := make(map[string]bool)
+		for _, share := range mixes[0].Types {
+			types[share.Type] = true
+		}
+		assert.True(t, types[models.AssessmentHomework])
+
+		detailed := mixes[0].Syllabi
+
+		mixes, err = models.GetAssessmentMix(nil, "")
+		require.Nil(t, err)
+		broad := make(map[int]int)
+		for _, mix := range mixes {
+			assert.Equal(t, models.FieldBroad, models.FieldLevel(mix.Field))
+			broad[mix.Field] = mix.Syllabi
+		}
+		// -- the syllabi of a detailed field count in its broad field
+		assert.GreaterOrEqual(t, broad[200], detailed)
+	})
+
+	t.Run("Test assessment mix malformed", func(t *testing.T) {
+		_, err := models.GetAssessmentMix(nil, "subfield")
+		assert.True(t, errors.Is(err, models.ErrInvalidAssessment))
+	})
+}
+
+func TestParseAssessment(t *testing.T) {
+	for text, expected := range map[string]string{
+		"Final project presentation (40%)":  models.AssessmentPresentation,
+		"Mid-Term Docu-Fiction Essay (20%)": models.AssessmentEssay,
+		"In-class tests (30%)":              models.AssessmentExam,
+		"Attendance & Participation (20%)":  models.AssessmentParticipation,
+		"Weekly quizzes":                    models.AssessmentQuiz,
+		"Something else entirely":           models.AssessmentOther,
+	} {
+		a, ok := models.ParseAssessment(text)
+		require.True(t, ok)
+		assert.Equal(t, expected, a.Type, text)
+	}
+
+	a, ok := models.ParseAssessment("  Second Analytical Essay (25,5%), due week 9;")
+	require.True(t, ok)
+	assert.Equal(t, "Second Analytical Essay (25,5%), due week 9", a.Description)
+	require.NotNil(t, a.Weight)
+	assert.Equal(t, 25.5, *a.Weight)
+	require.NotNil(t, a.DueWeek)
+	assert.Equal(t, 9, *a.DueWeek)
+
+	_, ok = models.ParseAssessment(" ; ")
+	assert.False(t, ok)
+
+	assessments := models.ParseAssessments([]string{"Essay (60%)", "Exam (40%)"})
+	require.Equal(t, 2, len(assessments))
+	assert.NotNil(t, assessments[0].Weight)
+
+	assessments = models.ParseAssessments([]string{"Essay (60%)", "Exam (30%)"})
+	assert.Nil(t, assessments[0].Weight)
+}
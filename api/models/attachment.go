@@ -37,8 +37,9 @@ func (a *Attachment) BeforeCreate(tx *gorm.DB) (err error) {
 	return nil
 }
 
-func CreateAttachment(syllabus_uuid uuid.UUID, att *Attachment, user_uuid uuid.UUID) (Attachment, error) {
-	syll, err := GetSyllabus(syllabus_uuid, user_uuid)
+func CreateAttachment(syllabus_uuid uuid.UUID, att *Attachment) (Attachment, error) {
+	var syll Syllabus
+	err := db.Where("uuid = ?", syllabus_uuid).First(&syll).Error
 	if err != nil {
 		return *att, err
 	}
@@ -65,13 +66,38 @@ func GetAttachmentBySlug(slug string) (Attachment, error) {
 	return att, result.Error
 }
 
-func GetAllAttachments() ([]Attachment, error) {
+// GetAllAttachments returns a page of the attachments of the syllabi visible to the user
+func GetAllAttachments(page Page, user_uuid uuid.UUID) ([]Attachment, PageMeta, error) {
 	res := make([]Attachment, 0)
-	result := db.Find(&res)
-	return res, result.Error
+	if page.Sort == "" {
+		page.Sort = SortCreated
+	}
+
+	visible := db.Model(&Attachment{}).
+		Joins("JOIN syllabi ON syllabi.uuid = attachments.syllabus_uuid AND syllabi.deleted_at IS NULL").
+		Where("(syllabi.status = 'listed' OR syllabi.user_uuid = ? OR syllabi.uuid IN (SELECT syllabus_uuid FROM syllabi_collaborators WHERE collaborator_uuid = ?))", user_uuid, user_uuid)
+
+	query, meta, err := paginate(visible, "attachments", page, ATTACHMENT_SORTS, nil)
+	if err != nil {
+		return res, meta, err
+	}
+
+	result := query.Find(&res)
+	if result.Error != nil {
+		return res, meta, result.Error
+	}
+
+	fetched := len(res)
+	if page.Size > 0 && fetched > page.Size {
+		res = res[:page.Size]
+		last := res[len(res)-1]
+		meta.nextCursor(fetched, sortValue(page.Sort, last.CreatedAt, last.UpdatedAt, last.Name, 0), last.ID)
+	}
+
+	return res, meta, nil
 }
 
-func UpdateAttachment(uuid uuid.UUID, user_uuid uuid.UUID, att *Attachment) (Attachment, error) {
+func UpdateAttachment(uuid uuid.UUID, att *Attachment) (Attachment, error) {
 	var existing Attachment
 	result := db.Where("uuid = ?", uuid).First(&existing)
 	if result.Error != nil {
@@ -82,7 +108,7 @@ func UpdateAttachment(uuid uuid.UUID, user_uuid uuid.UUID, att *Attachment) (Att
 	return existing, result.Error
 }
 
-func DeleteAttachment(uuid uuid.UUID, user_uuid uuid.UUID) (Attachment, error) {
+func DeleteAttachment(uuid uuid.UUID) (Attachment, error) {
 	var att Attachment
 	result := db.Where("uuid = ?", uuid).First(&att)
 	if result.Error != nil {
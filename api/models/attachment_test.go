@@ -5,12 +5,13 @@ import (
 	"testing"
 
 	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 var (
-	attachmentCount = 23
+	attachmentCount = 22
 )
 
 func TestAttachmentModel(t *testing.T) {
@@ -18,9 +19,14 @@ func TestAttachmentModel(t *testing.T) {
 	defer teardown(t)
 
 	t.Run("Test get all attachments", func(t *testing.T) {
-		res, err := models.GetAllAttachments()
+		res, _, err := models.GetAllAttachments(models.Page{}, userID)
 		require.Nil(t, err)
 		assert.Equal(t, attachmentCount, len(res))
+
+		// -- the attachments of unlisted syllabi are only listed for those who can see them
+		res, _, err = models.GetAllAttachments(models.Page{}, uuid.Nil)
+		require.Nil(t, err)
+		assert.Equal(t, attachmentCount-1, len(res))
 	})
 
 	t.Run("Test create attachment", func(t *testing.T) {
@@ -33,7 +39,7 @@ func TestAttachmentModel(t *testing.T) {
 		att := models.Attachment{
 			Name: "Test Name 2",
 		}
-		created, err := models.CreateAttachment(result.UUID, &att, userID)
+		created, err := models.CreateAttachment(result.UUID, &att)
 		require.Nil(t, err)
 		assert.Equal(t, att.Name, created.Name)
 		assert.Equal(t, syll.Title, created.Syllabus.Title)
@@ -65,7 +71,7 @@ func TestAttachmentModel(t *testing.T) {
 		var att models.Attachment
 		updatedName := fmt.Sprintf("%v (updated)", attachmentName)
 		att.Name = updatedName
-		updated, err := models.UpdateAttachment(attachmentID, userID, &att)
+		updated, err := models.UpdateAttachment(attachmentID, &att)
 		require.Nil(t, err)
 		require.False(t, updated.CreatedAt.IsZero())
 
@@ -78,19 +84,19 @@ func TestAttachmentModel(t *testing.T) {
 		res := models.Attachment{
 			Name: "Test Name 1 (updated)",
 		}
-		updated, err := models.UpdateAttachment(attachmentUnknownID, userID, &res)
+		updated, err := models.UpdateAttachment(attachmentUnknownID, &res)
 		assert.NotNil(t, err)
 		assert.True(t, updated.CreatedAt.IsZero())
 	})
 
 	t.Run("Test delete attachment", func(t *testing.T) {
-		res, err := models.DeleteAttachment(attachmentDeleteID, userID)
+		res, err := models.DeleteAttachment(attachmentDeleteID)
 		assert.NotNil(t, res)
 		assert.Nil(t, err)
 	})
 
 	t.Run("Test delete wrong attachment", func(t *testing.T) {
-		res, err := models.DeleteAttachment(attachmentUnknownID, userID)
+		res, err := models.DeleteAttachment(attachmentUnknownID)
 		assert.Zero(t, res)
 		assert.NotNil(t, err)
 	})
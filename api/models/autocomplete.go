@@ -0,0 +1,147 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DEFAULT_SUGGESTIONS_LIMIT = 10
+	MAX_SUGGESTIONS_LIMIT     = 50
+	MAX_SUGGESTION_LENGTH     = 100
+)
+
+var ErrInvalidSuggestion = errors.New("the autocomplete request is invalid")
+
+// Suggestion is a value already in use, along with the number of syllabi which use it. Readings also come with their UUID.
+type Suggestion struct {
+	Value string     `json:"value"`
+	Count int64      `json:"count"`
+	UUID  *uuid.UUID `json:"uuid,omitempty"`
+}
+
+// suggestionSource describes where the values of a kind of suggestion come from. Value is the expression suggested, Norm the
+// expression matched against and grouped by, Filter is backed by a trigram index to narrow the rows down before they are
+// matched one by one, and From joins the syllabi using each value.
+type suggestionSource struct {
+	Value  string
+	Norm   string
+	From   string
+	Filter string
+	Group  string
+	UUID   string
+}
+
+// SUGGESTIONS lists the kinds of values which can be autocompleted
+var SUGGESTIONS = map[string]suggestionSource{
+	"tags": {
+		Value:  "mode() WITHIN GROUP (ORDER BY trim(tag))",
+		Norm:   "suggest_normalize(tag)",
+		From:   "syllabi CROSS JOIN LATERAL unnest(syllabi.tags) AS tag",
+		Filter: "suggest_text(syllabi.tags)",
+	},
+	"instructors": {
+		Value:  "mode() WITHIN GROUP (ORDER BY trim(instructor))",
+		Norm:   "suggest_normalize(instructor)",
+		From:   "syllabi CROSS JOIN LATERAL unnest(syllabi.instructors) AS instructor",
+		Filter: "suggest_text(syllabi.instructors)",
+	},
+	"institutions": {
+		Value:  "mode() WITHIN GROUP (ORDER BY trim(institutions.name))",
+		Norm:   "suggest_normalize(institutions.name)",
+		From:   "institutions JOIN inst_syllabi ON inst_syllabi.institution_id = institutions.id AND institutions.deleted_at IS NULL JOIN syllabi ON syllabi.id = inst_syllabi.syllabus_id",
+		Filter: "suggest_normalize(institutions.name)",
+	},
+	"readings": {
+		Value:  "readings.title",
+		Norm:   "suggest_normalize(readings.title)",
+		From:   "readings JOIN syllabus_readings ON syllabus_readings.reading_id = readings.id JOIN syllabi ON syllabi.id = syllabus_readings.syllabus_id",
+		Filter: "suggest_normalize(readings.title)",
+		Group:  "readings.id",
+		UUID:   "readings.uuid",
+	},
+}
+
+// -- values are matched regardless of case, and of the spaces, dashes and underscores between their words,
+// so that "Machine Learning" and "machine-learning" are suggested as one
+var suggestionSeparators = regexp.MustCompile(`[[:space:]_-]+`)
+
+// initSuggestions indexes the trigrams of the values which can be autocompleted, normalized as they are matched. Arrays are
+// indexed as a whole, to find the syllabi which hold a matching value. It relies on pg_trgm, set up along with similar syllabi.
+func initSuggestions(db *gorm.DB) error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION suggest_normalize(t text) RETURNS text AS $$
+			SELECT lower(trim(regexp_replace(coalesce(t, ''), '[[:space:]_-]+', ' ', 'g')))
+		$$ LANGUAGE sql IMMUTABLE`,
+		`CREATE OR REPLACE FUNCTION suggest_text(a text[]) RETURNS text AS $$
+			SELECT suggest_normalize(array_to_string(a, ' | '))
+		$$ LANGUAGE sql IMMUTABLE`,
+		`CREATE INDEX IF NOT EXISTS idx_syllabi_tags_trgm ON syllabi USING GIN (suggest_text(tags) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_syllabi_instructors_trgm ON syllabi USING GIN (suggest_text(instructors) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_institutions_name_trgm ON institutions USING GIN (suggest_normalize(name) gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_readings_title_trgm ON readings USING GIN (suggest_normalize(title) gin_trgm_ops)`,
+	}
+
+	for _, s := range statements {
+		if err := db.Exec(s).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSuggestions returns the values of a kind which start like the text, or whose words are close to it, used by the syllabi
+// the user can see. Values starting like the text come first, then the most used ones.
+func GetSuggestions(kind string, text string, limit int, user_uuid uuid.UUID) ([]Suggestion, error) {
+	suggestions := make([]Suggestion, 0)
+
+	source, found := SUGGESTIONS[kind]
+	if !found {
+		return suggestions, fmt.Errorf("%w: unknown kind %s", ErrInvalidSuggestion, kind)
+	}
+
+	q := normalizeSuggestion(text)
+	if q == "" {
+		return suggestions, fmt.Errorf("%w: the text can't be empty", ErrInvalidSuggestion)
+	}
+	if len([]rune(q)) > MAX_SUGGESTION_LENGTH {
+		return suggestions, fmt.Errorf("%w: the text can't be longer than %d characters", ErrInvalidSuggestion, MAX_SUGGESTION_LENGTH)
+	}
+
+	group, id := source.Norm, "NULL::uuid"
+	if source.Group != "" {
+		group, id = source.Group, source.UUID
+	}
+
+	// -- concatenated rather than formatted, since the trigram operators hold a %
+	sql := "SELECT " + source.Value + " AS value, " + id + " AS uuid, count(DISTINCT syllabi.id) AS count," +
+		" bool_or(" + source.Norm + " LIKE @prefix) AS prefixed, max(word_similarity(@q, " + source.Norm + ")) AS similarity" +
+		" FROM " + source.From +
+		" WHERE syllabi.deleted_at IS NULL AND (syllabi.status = 'listed' OR syllabi.user_uuid = @user)" +
+		" AND (" + source.Filter + " LIKE @contains OR @q <% " + source.Filter + ")" +
+		" AND (" + source.Norm + " LIKE @prefix OR @q <% " + source.Norm + ")" +
+		" GROUP BY " + group +
+		" ORDER BY prefixed DESC, count DESC, similarity DESC, value ASC" +
+		" LIMIT @limit"
+
+	args := map[string]interface{}{
+		"q":        q,
+		"prefix":   escapeLike(q) + "%",
+		"contains": containing(q),
+		"user":     user_uuid.String(),
+		"limit":    limit,
+	}
+
+	err := db.Raw(sql, args).Scan(&suggestions).Error
+	return suggestions, err
+}
+
+// normalizeSuggestion matches the normalization of suggest_normalize
+func normalizeSuggestion(text string) string {
+	return strings.ToLower(strings.TrimSpace(suggestionSeparators.ReplaceAllString(text, " ")))
+}
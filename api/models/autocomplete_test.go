@@ -0,0 +1,71 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestionModel(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	t.Run("Test suggest tags by prefix", func(t *testing.T) {
+		suggestions, err := models.GetSuggestions("tags", "  IS", models.DEFAULT_SUGGESTIONS_LIMIT, uuid.Nil)
+		require.Nil(t, err)
+		require.NotEmpty(t, suggestions)
+		assert.Equal(t, "Islam", suggestions[0].Value)
+		assert.Equal(t, int64(2), suggestions[0].Count)
+		assert.Nil(t, suggestions[0].UUID)
+	})
+
+	t.Run("Test suggest tags regardless of separators", func(t *testing.T) {
+		suggestions, err := models.GetSuggestions("tags", "media-stud", models.DEFAULT_SUGGESTIONS_LIMIT, userID)
+		require.Nil(t, err)
+		require.NotEmpty(t, suggestions)
+		assert.Equal(t, "media studies", suggestions[0].Value)
+	})
+
+	t.Run("Test suggest tags of unlisted syllabi", func(t *testing.T) {
+		// -- the only syllabi tagged design are unlisted, one of them being the user's
+		suggestions, err := models.GetSuggestions("tags", "design", models.DEFAULT_SUGGESTIONS_LIMIT, uuid.Nil)
+		require.Nil(t, err)
+		for _, s := range suggestions {
+			assert.NotEqual(t, "design", s.Value)
+		}
+
+		suggestions, err = models.GetSuggestions("tags", "design", models.DEFAULT_SUGGESTIONS_LIMIT, userID)
+		require.Nil(t, err)
+		require.NotEmpty(t, suggestions)
+		assert.Equal(t, "design", suggestions[0].Value)
+		assert.Equal(t, int64(1), suggestions[0].Count)
+	})
+
+	t.Run("Test suggest institutions", func(t *testing.T) {
+		suggestions, err := models.GetSuggestions("institutions", "nyu", 1, uuid.Nil)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(suggestions))
+		assert.Equal(t, "NYU Abu Dhabi", suggestions[0].Value)
+	})
+
+	t.Run("Test suggest readings", func(t *testing.T) {
+		suggestions, err := models.GetSuggestions("readings", "death and life", models.DEFAULT_SUGGESTIONS_LIMIT, uuid.Nil)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(suggestions))
+		assert.Equal(t, "The Death and Life of Great American Cities", suggestions[0].Value)
+		assert.Equal(t, int64(2), suggestions[0].Count)
+		assert.NotNil(t, suggestions[0].UUID)
+	})
+
+	t.Run("Test suggest malformed", func(t *testing.T) {
+		_, err := models.GetSuggestions("colors", "red", models.DEFAULT_SUGGESTIONS_LIMIT, uuid.Nil)
+		assert.True(t, errors.Is(err, models.ErrInvalidSuggestion))
+
+		_, err = models.GetSuggestions("tags", " -_ ", models.DEFAULT_SUGGESTIONS_LIMIT, uuid.Nil)
+		assert.True(t, errors.Is(err, models.ErrInvalidSuggestion))
+	})
+}
@@ -0,0 +1,260 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+var ErrInvalidReading = errors.New("the reading is invalid")
+
+var (
+	doiPattern    = regexp.MustCompile(`(?i)(?:https?://(?:dx\.)?doi\.org/|doi:\s*)?(10\.\d{4,9}/[^\s"<>]+)`)
+	isbnPattern   = regexp.MustCompile(`(?i)isbn(?:-1[03])?:?\s*([0-9][0-9\- ]{8,15}[0-9x])`)
+	bareISBN      = regexp.MustCompile(`\b97[89][0-9\-]{10,14}\b`)
+	yearPattern   = regexp.MustCompile(`\((1[5-9]\d\d|20\d\d)[a-z]?\)|\b(1[5-9]\d\d|20\d\d)\b`)
+	quotedPattern = regexp.MustCompile(`["“«]([^"“”«»]+)["”»]`)
+	authorsSplit  = regexp.MustCompile(`\s*(?:;|&|\band\b)\s*`)
+	// -- OpenSyllabus fills in the parts of a citation it could not parse
+	unknownPattern = regexp.MustCompile(`(?i)^unknown (title|author)$`)
+)
+
+// -- articles are left out of the keys of titles, so that "The Republic" and "Republic" are the same reading
+var titleArticles = []string{"the ", "a ", "an ", "le ", "la ", "les ", "der ", "die ", "das "}
+
+// ParseReading reads a free-text citation, such as the readings of a syllabus. The DOI, ISBN and year are picked up wherever
+// they are, and the title and authors are told apart from the shape of the citation:
+//
+//	Jacobs, J. (1961). The Death and Life of Great American Cities. Random House.
+//	Lessig, L. "Code and Other Laws of Cyberspace", 1999
+//	The Death and Life of Great American Cities, Jane Jacobs
+//
+// the last one being the shape OpenSyllabus gives its citations in. Anything else is taken as a title.
+func ParseReading(text string) (Reading, error) {
+	var r Reading
+	rest := strings.Join(strings.Fields(text), " ")
+	if rest == "" {
+		return r, fmt.Errorf("%w: the citation is empty", ErrInvalidReading)
+	}
+
+	if m := doiPattern.FindStringSubmatchIndex(rest); m != nil {
+		r.DOI = strings.ToLower(strings.TrimRight(rest[m[2]:m[3]], ".,;)"))
+		rest = rest[:m[0]] + rest[m[1]:]
+	}
+
+	if m := isbnPattern.FindStringSubmatchIndex(rest); m != nil {
+		if isbn, ok := NormalizeISBN(rest[m[2]:m[3]]); ok {
+			r.ISBN = isbn
+			rest = rest[:m[0]] + rest[m[1]:]
+		}
+	} else if m := bareISBN.FindStringIndex(rest); m != nil {
+		if isbn, ok := NormalizeISBN(rest[m[0]:m[1]]); ok {
+			r.ISBN = isbn
+			rest = rest[:m[0]] + rest[m[1]:]
+		}
+	}
+
+	rest = strings.Trim(strings.Join(strings.Fields(rest), " "), " ,;")
+
+	// -- a year in parentheses comes right after the authors, as in APA
+	var authors string
+	for _, m := range yearPattern.FindAllStringSubmatchIndex(rest, -1) {
+		// -- a year the citation starts with is rather a title, such as 1984
+		if m[2] < 0 && m[0] == 0 {
+			continue
+		}
+
+		if m[2] >= 0 {
+			r.Year, _ = strconv.Atoi(rest[m[2]:m[3]])
+			if before := strings.TrimSpace(rest[:m[0]]); before != "" {
+				authors = before
+				rest = strings.TrimLeft(rest[m[1]:], " .,:")
+				if i := strings.Index(rest, ". "); i > 0 {
+					rest = rest[:i]
+				}
+			} else {
+				rest = rest[:m[0]] + rest[m[1]:]
+			}
+		} else {
+			r.Year, _ = strconv.Atoi(rest[m[4]:m[5]])
+			rest = rest[:m[0]] + rest[m[1]:]
+		}
+		break
+	}
+
+	rest = strings.Trim(strings.Join(strings.Fields(rest), " "), " ,;")
+
+	title := rest
+	if authors == "" {
+		if m := quotedPattern.FindStringSubmatchIndex(rest); m != nil {
+			title = rest[m[2]:m[3]]
+			authors = strings.TrimSpace(rest[:m[0]])
+			if authors == "" {
+				authors = strings.TrimSpace(rest[m[1]:])
+			}
+		} else if i := strings.LastIndex(rest, ", "); i > 0 {
+			title, authors = rest[:i], rest[i+2:]
+		}
+	}
+
+	r.Title = cleanCitationPart(title)
+	for _, a := range authorsSplit.Split(authors, -1) {
+		if a = cleanCitationPart(a); a != "" {
+			r.Authors = append(r.Authors, a)
+		}
+	}
+
+	if r.Title == "" && r.DOI == "" && r.ISBN == "" {
+		return r, fmt.Errorf("%w: no title could be found in %q", ErrInvalidReading, text)
+	}
+
+	r.Key = ReadingKey(r)
+	return r, nil
+}
+
+// normalizeCitation cleans up a reading given with its fields, as ParseReading does for the ones given as text. Years out of
+// the range of the ones found in citations are dropped.
+func normalizeCitation(c Reading) (Reading, error) {
+	r := Reading{Title: cleanCitationPart(c.Title)}
+	for _, a := range c.Authors {
+		if a = cleanCitationPart(a); a != "" {
+			r.Authors = append(r.Authors, a)
+		}
+	}
+
+	if c.Year >= 1500 && c.Year < 2100 {
+		r.Year = c.Year
+	}
+
+	if isbn, ok := NormalizeISBN(c.ISBN); ok {
+		r.ISBN = isbn
+	}
+
+	if m := doiPattern.FindStringSubmatch(c.DOI); m != nil {
+		r.DOI = strings.ToLower(strings.TrimRight(m[1], ".,;)"))
+	}
+
+	if r.Title == "" && r.DOI == "" && r.ISBN == "" {
+		return r, fmt.Errorf("%w: the citation has no title", ErrInvalidReading)
+	}
+
+	r.Key = ReadingKey(r)
+	return r, nil
+}
+
+// ReadingKey identifies a reading across syllabi: by its DOI, then its ISBN, and otherwise by its title and the last name
+// of its first author, regardless of case, accents, punctuation and leading articles
+func ReadingKey(r Reading) string {
+	switch {
+	case r.DOI != "":
+		return "doi:" + strings.ToLower(r.DOI)
+	case r.ISBN != "":
+		return "isbn:" + r.ISBN
+	}
+
+	title := normalizeKey(r.Title)
+	for _, a := range titleArticles {
+		if strings.HasPrefix(title, a) {
+			title = strings.TrimPrefix(title, a)
+			break
+		}
+	}
+
+	key := "title:" + title
+	if len(r.Authors) > 0 {
+		key += "|" + normalizeKey(lastName(r.Authors[0]))
+	}
+	return key
+}
+
+// NormalizeISBN checks an ISBN-10 or ISBN-13, and returns it as an ISBN-13 without separators
+func NormalizeISBN(s string) (string, bool) {
+	digits := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(s))
+
+	switch len(digits) {
+	case 10:
+		sum := 0
+		for i, c := range digits {
+			v := int(c - '0')
+			if c == 'X' && i == 9 {
+				v = 10
+			} else if c < '0' || c > '9' {
+				return "", false
+			}
+			sum += (10 - i) * v
+		}
+		if sum%11 != 0 {
+			return "", false
+		}
+		digits = "978" + digits[:9]
+		return digits + isbn13CheckDigit(digits), true
+	case 13:
+		for _, c := range digits {
+			if c < '0' || c > '9' {
+				return "", false
+			}
+		}
+		if isbn13CheckDigit(digits[:12]) != digits[12:] {
+			return "", false
+		}
+		return digits, true
+	default:
+		return "", false
+	}
+}
+
+func isbn13CheckDigit(digits string) string {
+	sum := 0
+	for i, c := range digits {
+		v := int(c - '0')
+		if i%2 == 1 {
+			v *= 3
+		}
+		sum += v
+	}
+	return strconv.Itoa((10 - sum%10) % 10)
+}
+
+// lastName is the part of a name before its comma, as in "Jacobs, J.", and otherwise its last word
+func lastName(name string) string {
+	if i := strings.Index(name, ","); i > 0 {
+		return name[:i]
+	}
+
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return ""
+	}
+	return words[len(words)-1]
+}
+
+// normalizeKey lowers a text and strips it of accents and punctuation
+func normalizeKey(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	s, _, _ = transform.String(t, strings.ToLower(s))
+
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func cleanCitationPart(s string) string {
+	s = strings.Trim(strings.TrimSpace(s), " .,;:\"“”«»")
+	if unknownPattern.MatchString(s) {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReading(t *testing.T) {
+	t.Run("Test parse citation shapes", func(t *testing.T) {
+		cases := []struct {
+			text    string
+			title   string
+			authors []string
+			year    int
+		}{
+			{"Jacobs, J. (1961). The Death and Life of Great American Cities. Random House.", "The Death and Life of Great American Cities", []string{"Jacobs, J"}, 1961},
+			{`Lessig, L. "Code and Other Laws of Cyberspace", 1999`, "Code and Other Laws of Cyberspace", []string{"Lessig, L"}, 1999},
+			{"The Death and Life of Great American Cities, Jane Jacobs", "The Death and Life of Great American Cities", []string{"Jane Jacobs"}, 0},
+			{"Smith, J., & Doe, A. (2001). Working Together. Press.", "Working Together", []string{"Smith, J", "Doe, A"}, 2001},
+			{"1984, George Orwell", "1984", []string{"George Orwell"}, 0},
+			{"Orientalism", "Orientalism", nil, 0},
+		}
+
+		for _, c := range cases {
+			r, err := models.ParseReading(c.text)
+			require.Nil(t, err, c.text)
+			assert.Equal(t, c.title, r.Title, c.text)
+			assert.Equal(t, c.authors, []string(r.Authors), c.text)
+			assert.Equal(t, c.year, r.Year, c.text)
+		}
+	})
+
+	t.Run("Test parse identifiers", func(t *testing.T) {
+		r, err := models.ParseReading("Chun, W. H. K. (2011). Programmed Visions. MIT Press. https://doi.org/10.7551/MITpress/9780262015424.001.0001.")
+		require.Nil(t, err)
+		assert.Equal(t, "10.7551/mitpress/9780262015424.001.0001", r.DOI)
+		assert.Equal(t, "Programmed Visions", r.Title)
+		assert.Equal(t, "doi:10.7551/mitpress/9780262015424.001.0001", r.Key)
+
+		r, err = models.ParseReading("Code and Other Laws of Cyberspace, Lawrence Lessig, ISBN 0-465-03913-8")
+		require.Nil(t, err)
+		assert.Equal(t, "9780465039135", r.ISBN)
+		assert.Equal(t, []string{"Lawrence Lessig"}, []string(r.Authors))
+		assert.Equal(t, "isbn:9780465039135", r.Key)
+
+		// -- an invalid ISBN is left in the citation
+		r, err = models.ParseReading("Code, Lessig, ISBN 0-465-03913-7")
+		require.Nil(t, err)
+		assert.Equal(t, "", r.ISBN)
+	})
+
+	t.Run("Test reading keys match across shapes", func(t *testing.T) {
+		a, err := models.ParseReading("Jacobs, J. (1961). The Death and Life of Great American Cities. Random House.")
+		require.Nil(t, err)
+		b, err := models.ParseReading("Death and Life of Great American Cities, Jane Jacobs")
+		require.Nil(t, err)
+		assert.Equal(t, "title:death and life of great american cities|jacobs", a.Key)
+		assert.Equal(t, a.Key, b.Key)
+
+		c, err := models.ParseReading("Écrits, Jacques Lacan")
+		require.Nil(t, err)
+		d, err := models.ParseReading("ecrits , lacan")
+		require.Nil(t, err)
+		assert.Equal(t, c.Key, d.Key)
+	})
+
+	t.Run("Test parse empty citations", func(t *testing.T) {
+		for _, text := range []string{"", "   ", "Unknown title, Unknown author"} {
+			_, err := models.ParseReading(text)
+			assert.ErrorIs(t, err, models.ErrInvalidReading, text)
+		}
+	})
+}
+
+func TestNormalizeISBN(t *testing.T) {
+	for _, isbn := range []string{"0-465-03913-8", "978-0-465-03913-5", "9780465039135"} {
+		normalized, ok := models.NormalizeISBN(isbn)
+		assert.True(t, ok, isbn)
+		assert.Equal(t, "9780465039135", normalized)
+	}
+
+	for _, isbn := range []string{"0-465-03913-7", "978-0-465-03913-4", "12345"} {
+		_, ok := models.NormalizeISBN(isbn)
+		assert.False(t, ok, isbn)
+	}
+}
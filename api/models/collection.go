@@ -18,6 +18,7 @@ type Collection struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 	UUID      uuid.UUID      `gorm:"uniqueIndex;type:uuid;primaryKey;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
 	Status    string         `gorm:"default:unlisted" json:"status" form:"status"`
+	Moderation
 
 	UserUUID uuid.UUID   `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"user_uuid" yaml:"user_uuid"`
 	User     User        `gorm:"foreignKey:UserUUID;references:UUID" json:"user"`
@@ -96,32 +97,89 @@ func GetCollectionBySlug(slug string, user_uuid uuid.UUID) (Collection, error) {
 	return coll, err
 }
 
-func GetAllCollections(user_uuid uuid.UUID) ([]Collection, error) {
+// GetAllCollections returns a page of the collections which are listed or owned by the user
+func GetAllCollections(user_uuid uuid.UUID, page Page) ([]Collection, PageMeta, error) {
 	coll := make([]Collection, 0)
-	result := db.Preload("User").Preload("Syllabi").Where("status = 'listed' OR user_uuid = ?", user_uuid).Find(&coll)
-	return coll, result.Error
+	if page.Sort == "" {
+		page.Sort = SortCreated
+	}
+
+	query := db.Model(&Collection{}).Where("status = 'listed' OR user_uuid = ?", user_uuid)
+	query, meta, err := paginate(query, "collections", page, COLLECTION_SORTS, nil)
+	if err != nil {
+		return coll, meta, err
+	}
+
+	coll, err = findCollections(query, page, &meta)
+	return coll, meta, err
+}
+
+// findCollections fetches a paginated query of collections, and sets the cursor of the next page on meta if there is one
+func findCollections(query *gorm.DB, page Page, meta *PageMeta) ([]Collection, error) {
+	coll := make([]Collection, 0)
+	result := query.Preload("User").Preload("Syllabi").Find(&coll)
+	if result.Error != nil {
+		return coll, result.Error
+	}
+
+	fetched := len(coll)
+	if page.Size > 0 && fetched > page.Size {
+		coll = coll[:page.Size]
+		last := coll[len(coll)-1]
+		meta.nextCursor(fetched, sortValue(page.Sort, last.CreatedAt, last.UpdatedAt, last.Name, 0), last.ID)
+	}
+
+	return coll, nil
 }
 
-func UpdateCollection(uuid uuid.UUID, user_uuid uuid.UUID, coll *Collection) (Collection, error) {
+// UpdateCollection sets the fields given in coll on the collection. Only admins can change the status of a collection
+// unlisted by a moderator, which lifts its moderation.
+func UpdateCollection(uuid uuid.UUID, coll *Collection, user_uuid uuid.UUID) (Collection, error) {
 	var existing Collection
 	result := db.Where("uuid = ?", uuid).First(&existing)
 	if result.Error != nil {
 		return *coll, result.Error
 	}
 
-	result = db.Model(&existing).Where("uuid = ?", uuid).Updates(&coll)
-	return existing, result.Error
+	lift, err := existing.checkModeration(existing.Status, coll.Status, user_uuid)
+	if err != nil {
+		return existing, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&existing).Where("uuid = ?", uuid).Omit(moderationColumns...).Updates(&coll).Error
+		if err != nil || !lift {
+			return err
+		}
+		return tx.Model(&existing).Select(moderationColumns).Updates(&Collection{}).Error
+	})
+	return existing, err
+}
+
+// UnlistCollection hides a collection from everyone but its owner, as a moderator. Only admins can list it again.
+func UnlistCollection(uuid uuid.UUID, moderator_uuid uuid.UUID) (Collection, error) {
+	var coll Collection
+	result := db.Where("uuid = ?", uuid).First(&coll)
+	if result.Error != nil {
+		return coll, result.Error
+	}
+
+	coll.Status = "unlisted"
+	coll.moderate(moderator_uuid)
+	result = db.Model(&coll).Select(append([]string{"status"}, moderationColumns...)).Updates(&coll)
+	return coll, result.Error
 }
 
 func AddSyllabusToCollection(coll_uuid uuid.UUID, syll_uuid uuid.UUID, user_uuid uuid.UUID) (Collection, error) {
 	var coll Collection
-	result := db.Where("uuid = ? AND user_uuid = ?", coll_uuid, user_uuid).First(&coll)
+	result := db.Where("uuid = ?", coll_uuid).First(&coll)
 	if result.Error != nil {
 		return coll, result.Error
 	}
 
+	// -- only syllabi visible to the user can be collected
 	var syll Syllabus
-	result = db.Preload("Collections").Where("uuid = ? ", syll_uuid).First(&syll)
+	result = db.Preload("Collections").Where("uuid = ? AND (status = 'listed' OR user_uuid = ?)", syll_uuid, user_uuid).First(&syll)
 	if result.Error != nil {
 		return coll, result.Error
 	}
@@ -142,9 +200,9 @@ func AddSyllabusToCollection(coll_uuid uuid.UUID, syll_uuid uuid.UUID, user_uuid
 }
 
 // -- removes the assocation between syllabus and collection
-func RemoveCollectionSyllabus(coll_uuid uuid.UUID, syll_uuid uuid.UUID, user_uuid uuid.UUID) (Collection, error) {
+func RemoveCollectionSyllabus(coll_uuid uuid.UUID, syll_uuid uuid.UUID) (Collection, error) {
 	var coll Collection
-	result := db.Where("uuid = ? AND user_uuid = ?", coll_uuid, user_uuid).First(&coll)
+	result := db.Where("uuid = ?", coll_uuid).First(&coll)
 	if result.Error != nil {
 		return coll, result.Error
 	}
@@ -159,7 +217,7 @@ func RemoveCollectionSyllabus(coll_uuid uuid.UUID, syll_uuid uuid.UUID, user_uui
 	return coll, err
 }
 
-func DeleteCollection(uuid uuid.UUID, user_uuid uuid.UUID) (Collection, error) {
+func DeleteCollection(uuid uuid.UUID) (Collection, error) {
 	var coll Collection
 	result := db.Where("uuid = ?", uuid).First(&coll)
 	if result.Error != nil {
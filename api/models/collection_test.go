@@ -1,6 +1,7 @@
 package models_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -14,7 +15,7 @@ func TestCollectionModel(t *testing.T) {
 	defer teardown(t)
 
 	t.Run("Test get all listed and owned collections", func(t *testing.T) {
-		res, err := models.GetAllCollections(userID)
+		res, _, err := models.GetAllCollections(userID, models.Page{})
 		require.Nil(t, err)
 		assert.Equal(t, len(res), 3)
 	})
@@ -64,7 +65,7 @@ func TestCollectionModel(t *testing.T) {
 		var coll models.Collection
 		updatedName := fmt.Sprintf("%s (updated)", collectionName)
 		coll.Name = updatedName
-		updated, err := models.UpdateCollection(collectionID, userID, &coll)
+		updated, err := models.UpdateCollection(collectionID, &coll, userID)
 
 		require.Nil(t, err)
 		require.False(t, updated.CreatedAt.IsZero())
@@ -78,7 +79,7 @@ func TestCollectionModel(t *testing.T) {
 		coll := models.Collection{
 			Name: "Test Name 1 (updated)",
 		}
-		updated, err := models.UpdateCollection(collectionUnknownID, userID, &coll)
+		updated, err := models.UpdateCollection(collectionUnknownID, &coll, userID)
 		assert.NotNil(t, err)
 		assert.True(t, updated.CreatedAt.IsZero())
 	})
@@ -90,19 +91,50 @@ func TestCollectionModel(t *testing.T) {
 	})
 
 	t.Run("Test remove syllabus from collection", func(t *testing.T) {
-		updated, err := models.RemoveCollectionSyllabus(collectionID, syllabusDeleteID, userID)
+		updated, err := models.RemoveCollectionSyllabus(collectionID, syllabusDeleteID)
 		assert.Nil(t, err)
 		assert.Equal(t, 0, len(updated.Syllabi))
 	})
 
+	t.Run("Test unlist collection", func(t *testing.T) {
+		coll, err := models.UnlistCollection(collectionDeleteID, adminID)
+		require.Nil(t, err)
+		assert.Equal(t, "unlisted", coll.Status)
+		assert.NotNil(t, coll.ModeratedAt)
+	})
+
+	t.Run("Test admin listing includes moderated collections", func(t *testing.T) {
+		coll, _, err := models.GetAdminCollections(models.Page{})
+		require.Nil(t, err)
+
+		found := false
+		for _, c := range coll {
+			found = found || c.UUID == collectionDeleteID
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("Test unlisted collection can only be listed by an admin", func(t *testing.T) {
+		_, err := models.UpdateCollection(collectionDeleteID, &models.Collection{Status: "listed"}, userDeleteID)
+		assert.True(t, errors.Is(err, models.ErrModerated))
+
+		_, err = models.UpdateCollection(collectionDeleteID, &models.Collection{Status: "listed"}, adminID)
+		require.Nil(t, err)
+
+		coll, err := models.GetCollection(collectionDeleteID, userDeleteID)
+		require.Nil(t, err)
+		assert.Equal(t, "listed", coll.Status)
+		assert.Nil(t, coll.ModeratedAt)
+	})
+
 	t.Run("Test delete collection", func(t *testing.T) {
-		coll, err := models.DeleteCollection(collectionDeleteID, userID)
+		coll, err := models.DeleteCollection(collectionDeleteID)
 		assert.NotNil(t, coll)
 		assert.Nil(t, err)
 	})
 
 	t.Run("Test delete wrong collection", func(t *testing.T) {
-		coll, err := models.DeleteCollection(collectionUnknownID, userID)
+		coll, err := models.DeleteCollection(collectionUnknownID)
 		assert.Zero(t, coll)
 		assert.NotNil(t, err)
 	})
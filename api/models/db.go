@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	zero "github.com/commonsyllabi/explorer/api/logger"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -43,12 +44,42 @@ func InitDB(url string) (*gorm.DB, error) {
 	}
 
 	// migration
-	err = db.AutoMigrate(&User{}, &Collection{}, &Syllabus{}, &Attachment{}, &Token{}, &Institution{})
+	err = db.AutoMigrate(&User{}, &Collection{}, &Syllabus{}, &Attachment{}, &Token{}, &Institution{}, &RefreshToken{}, &UserIdentity{}, &OIDCState{}, &RecoveryCode{}, &RateLimit{}, &APIToken{}, &SavedSearch{}, &Reading{}, &SyllabusReading{}, &SyllabusRevision{}, &Session{}, &Assessment{})
 	if err != nil {
 		zero.Errorf("error running migrations: %v", err)
 		log.Fatal(err)
 	}
 
+	err = initSearch(db)
+	if err != nil {
+		zero.Errorf("error setting up search: %v", err)
+		return db, err
+	}
+
+	err = initSimilar(db)
+	if err != nil {
+		zero.Errorf("error setting up similar syllabi: %v", err)
+		return db, err
+	}
+
+	err = initSuggestions(db)
+	if err != nil {
+		zero.Errorf("error setting up autocomplete: %v", err)
+		return db, err
+	}
+
+	err = initListing(db)
+	if err != nil {
+		zero.Errorf("error setting up listing dates: %v", err)
+		return db, err
+	}
+
+	err = initRevisions(db)
+	if err != nil {
+		zero.Errorf("error setting up revisions: %v", err)
+		return db, err
+	}
+
 	// fixtures
 	if os.Getenv("RUN_FIXTURES") == "true" || os.Getenv("API_MODE") == "test" {
 		err = runFixtures(true)
@@ -60,6 +91,36 @@ func InitDB(url string) (*gorm.DB, error) {
 		zero.Debug("RUN_FIXTURES env variable not set to true, skipping fixtures...")
 	}
 
+	err = indexMissingReadings(db)
+	if err != nil {
+		zero.Errorf("error indexing readings: %v", err)
+		return db, err
+	}
+
+	err = normalizeLicenses(db)
+	if err != nil {
+		zero.Errorf("error normalizing licenses: %v", err)
+		return db, err
+	}
+
+	err = recordMissingRevisions(db)
+	if err != nil {
+		zero.Errorf("error recording revisions: %v", err)
+		return db, err
+	}
+
+	err = seedMissingSessions(db)
+	if err != nil {
+		zero.Errorf("error seeding sessions: %v", err)
+		return db, err
+	}
+
+	err = seedMissingAssessments(db)
+	if err != nil {
+		zero.Errorf("error seeding assessments: %v", err)
+		return db, err
+	}
+
 	return db, err
 }
 
@@ -82,6 +143,61 @@ func runFixtures(shouldTruncateTables bool) error {
 		if err != nil {
 			return err
 		}
+
+		err = db.Exec("TRUNCATE TABLE refresh_tokens CASCADE").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec("TRUNCATE TABLE user_identities CASCADE").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec("TRUNCATE TABLE oidc_states CASCADE").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec("TRUNCATE TABLE recovery_codes CASCADE").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec("TRUNCATE TABLE rate_limits CASCADE").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec("TRUNCATE TABLE api_tokens CASCADE").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec("TRUNCATE TABLE saved_searches CASCADE").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec("TRUNCATE TABLE readings, syllabus_readings CASCADE").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec("TRUNCATE TABLE syllabus_revisions CASCADE").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec("TRUNCATE TABLE sessions, session_readings CASCADE").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec("TRUNCATE TABLE assessments CASCADE").Error
+		if err != nil {
+			return err
+		}
 	}
 
 	var fixtures_path = ""
@@ -134,14 +250,21 @@ func runFixtures(shouldTruncateTables bool) error {
 		return err
 	}
 
+	// -- the secrets of the fixture tokens are their UUID strings
 	token := Token{
-		UUID:   uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c801"),
-		UserID: uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c800"),
+		UUID:      uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c801"),
+		UserID:    uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c800"),
+		Kind:      TokenConfirmation,
+		Hash:      hashSecret("e7b74bcd-c864-41ee-b5a7-d3031f76c801"),
+		ExpiresAt: time.Now().Add(TOKEN_TTL[TokenConfirmation]),
 	}
 
 	token_recovery := Token{
-		UUID:   uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c901"),
-		UserID: uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c800"),
+		UUID:      uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c901"),
+		UserID:    uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c800"),
+		Kind:      TokenRecovery,
+		Hash:      hashSecret("e7b74bcd-c864-41ee-b5a7-d3031f76c901"),
+		ExpiresAt: time.Now().Add(TOKEN_TTL[TokenRecovery]),
 	}
 
 	err = db.Create(&token).Error
@@ -38,6 +38,7 @@ var (
 	userSlug       string
 	userDeleteID   uuid.UUID
 	userUnknownID  uuid.UUID
+	adminID        uuid.UUID
 	userEmail      string
 	userName       string
 	userDeleteName string
@@ -81,6 +82,7 @@ func setup(t *testing.T) func(t *testing.T) {
 	userSlug = "justyna-poplawska-e7b74bcd"
 	userDeleteID = uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c8a9")
 	userUnknownID = uuid.New()
+	adminID = uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c8a0")
 	userEmail = "jus@pop.com"
 	userName = "Justyna Poplawska"
 	userDeleteName = "Pierre Depaz"
@@ -32,6 +32,7 @@ var ACADEMIC_FIELDS = map[int]string{
 	221: "Religion and theology",
 	222: "History and archeology",
 	223: "Philosophy and ethics",
+	23:  "Languages",
 	231: "Language acquisition",
 	232: "Literature and linguistics",
 
@@ -126,6 +127,7 @@ var ACADEMIC_FIELDS = map[int]string{
 	102:  "Hygiene and occupational health services",
 	1021: "Community sanitation",
 	1022: "Occupational and health and safety",
+	103:  "Security services",
 	1031: "Military and defence",
 	1032: "Protection of persons and property",
 	104:  "Transport",
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// FacetCount is the number of syllabi matching a search which have a given value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// facet counts the values of an expression across the syllabi of a search, joined with the tables or arrays the values are in.
+// Param is the search parameter filtering on those values: it is left out when counting them, so that the other values
+// one could pick instead are still counted.
+type facet struct {
+	Expr  string
+	Join  string
+	Param string
+	Valid string
+}
+
+const joinInstitutions = "JOIN inst_syllabi ON inst_syllabi.syllabus_id = syllabi.id JOIN institutions ON institutions.id = inst_syllabi.institution_id AND institutions.deleted_at IS NULL"
+
+var FACETS = map[string]facet{
+	"languages":       {Expr: "syllabi.language", Param: "languages", Valid: "syllabi.language <> ''"},
+	"academic_levels": {Expr: "syllabi.academic_level::text", Param: "levels"},
+	"academic_fields": {Expr: "field::text", Join: "CROSS JOIN LATERAL unnest(syllabi.academic_fields) AS field", Param: "fields"},
+	"academic_years":  {Expr: "institutions.date_year::text", Join: joinInstitutions, Param: "years", Valid: "institutions.date_year > 0"},
+	"countries":       {Expr: "institutions.country::text", Join: joinInstitutions, Param: "countries", Valid: "institutions.country > 0"},
+	"institutions":    {Expr: "institutions.name", Join: joinInstitutions, Param: "institutions", Valid: "institutions.name <> ''"},
+	"terms":           {Expr: "lower(institutions.date_term)", Join: joinInstitutions, Param: "terms", Valid: "institutions.date_term <> ''"},
+	"tags":            {Expr: "lower(tag)", Join: "CROSS JOIN LATERAL unnest(syllabi.tags) AS tag", Param: "tags", Valid: "tag <> ''"},
+	"licenses":        {Expr: "syllabi.license", Param: "licenses", Valid: "syllabi.license <> ''"},
+}
+
+// GetSyllabiFacets counts, for each facet, how many of the syllabi visible to the user and matching the params have each value,
+// the most common values first
+func GetSyllabiFacets(params map[string]any, user_uuid uuid.UUID) (map[string][]FacetCount, error) {
+	facets := make(map[string][]FacetCount, len(FACETS))
+
+	for name, f := range FACETS {
+		scoped := params
+		if f.Param != "" {
+			scoped = make(map[string]any, len(params))
+			for k, v := range params {
+				scoped[k] = v
+			}
+			delete(scoped, f.Param)
+		}
+
+		query := filterSyllabi(db.Model(&Syllabus{}), scoped, user_uuid)
+		if f.Join != "" {
+			query = query.Joins(f.Join)
+		}
+
+		// -- the joined institution must also match the other institution filters, or a syllabus of several institutions
+		// would count the values of the ones which don't match
+		if f.Join == joinInstitutions {
+			if conditions, args := institutionFilters(scoped); len(conditions) > 0 {
+				query = query.Where(strings.Join(conditions, " AND "), args...)
+			}
+		}
+
+		if f.Valid != "" {
+			query = query.Where(f.Valid)
+		}
+
+		counts := make([]FacetCount, 0)
+		err := query.Select(f.Expr + " AS value, count(DISTINCT syllabi.id) AS count").
+			Where(f.Expr + " IS NOT NULL").
+			Group(f.Expr).
+			Order("count DESC, value ASC").
+			Scan(&counts).Error
+		if err != nil {
+			return facets, err
+		}
+
+		facets[name] = counts
+	}
+
+	return facets, nil
+}
+
+// filterSyllabi restricts a query to the syllabi visible to the user which match the filters, keywords and parsed query of the params.
+// Missing filters match every syllabus.
+func filterSyllabi(query *gorm.DB, params map[string]any, user_uuid uuid.UUID) *gorm.DB {
+	query = query.Where("(syllabi.status = 'listed' OR syllabi.user_uuid = ?)", user_uuid.String())
+
+	if langs, ok := params["languages"].([]string); ok && len(langs) > 0 {
+		query = query.Where("syllabi.language = ANY(?)", pq.StringArray(langs))
+	}
+
+	if levels, ok := params["levels"].([]int); ok && len(levels) > 0 {
+		query = query.Where("syllabi.academic_level = ANY(?)", fieldCodes(levels))
+	}
+
+	// -- tags are compared regardless of their case, the ones given being lowercased already
+	if tags, ok := params["tags"].([]string); ok && len(tags) > 0 {
+		query = query.Where("lower(syllabi.tags::text)::text[] && ?", pq.StringArray(tags))
+	}
+
+	if fields, ok := params["fields"].([]int); ok && len(fields) > 0 {
+		query = query.Where("syllabi.academic_fields && ?", fieldCodes(fields))
+	}
+
+	if licenses, ok := params["licenses"].([]string); ok && len(licenses) > 0 {
+		query = query.Where("syllabi.license IN ?", licenses)
+	}
+
+	// -- syllabi without a license, or with one which isn't supported, are left out of the ones permitting a use
+	if uses, ok := params["permits"].([]string); ok && len(uses) > 0 {
+		query = query.Where("syllabi.license IN ?", LicensesAllowing(uses))
+	}
+
+	// -- the institution filters must all hold for the same institution, such as a fall term in France in 2021
+	if conditions, args := institutionFilters(params); len(conditions) > 0 {
+		query = query.Where("EXISTS ("+institutionsOf+" AND "+strings.Join(conditions, " AND ")+")", args...)
+	}
+
+	if keywords := searchKeywords(params); keywords != "" {
+		query = query.Where("syllabi.search_vector @@ "+searchQuery(), map[string]interface{}{"q": keywords})
+	}
+
+	// -- digests of saved searches only list the syllabi listed since the previous one
+	if listed, ok := params["listed_between"].([2]time.Time); ok {
+		query = query.Where("syllabi.status = 'listed' AND syllabi.listed_at > ? AND syllabi.listed_at <= ?", listed[0], listed[1])
+	}
+
+	if q, ok := params["query"].(*Query); ok && q != nil {
+		query = query.Where(q.SQL, q.Args...)
+	}
+
+	return query
+}
+
+// institutionFilters lists the conditions the institution filters of the params set on a joined institutions row, with their args
+func institutionFilters(params map[string]any) ([]string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	if insts, ok := params["institutions"].([]string); ok && len(insts) > 0 {
+		ids, names := make([]string, 0), make([]string, 0)
+		for _, inst := range insts {
+			if id, err := uuid.Parse(inst); err == nil {
+				ids = append(ids, id.String())
+			} else {
+				names = append(names, strings.ToLower(inst))
+			}
+		}
+		conditions = append(conditions, "(institutions.uuid::text IN ? OR lower(institutions.name) IN ?)")
+		args = append(args, ids, names)
+	}
+
+	if cs, ok := params["countries"].([]int); ok && len(cs) > 0 {
+		conditions = append(conditions, "institutions.country IN ?")
+		args = append(args, cs)
+	}
+
+	if terms, ok := params["terms"].([]string); ok && len(terms) > 0 {
+		conditions = append(conditions, "lower(institutions.date_term) IN ?")
+		args = append(args, terms)
+	}
+
+	if years, ok := params["years"].([2]int); ok {
+		conditions = append(conditions, "institutions.date_year BETWEEN ? AND ?")
+		args = append(args, years[0], years[1])
+	}
+
+	return conditions, args
+}
+
+func searchKeywords(params map[string]any) string {
+	keywords, _ := params["keywords"].(string)
+	if strings.TrimSpace(keywords) == "" {
+		return ""
+	}
+	return keywords
+}
+
+func fieldCodes(fields []int) pq.Int32Array {
+	codes := make(pq.Int32Array, 0, len(fields))
+	for _, f := range fields {
+		codes = append(codes, int32(f))
+	}
+	return codes
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const MAX_LINEAGE_DEPTH = 50
+
+var ErrForkNotAllowed = errors.New("the license of the syllabus does not allow derivatives")
+
+// SyllabusLink is a summary of a syllabus in the lineage of another one, enough to credit it
+type SyllabusLink struct {
+	UUID     uuid.UUID `json:"uuid"`
+	Title    string    `json:"title"`
+	Slug     string    `json:"slug"`
+	License  string    `json:"license"`
+	UserUUID uuid.UUID `json:"user_uuid"`
+	UserName string    `json:"user_name"`
+}
+
+// ForkSyllabus copies a syllabus visible to the user into their account, along with copies of its institutions, attachments,
+// sessions and assessments, the attachments still pointing to the same files. The fork starts unlisted, under the license of the original, and keeps
+// the original it was derived from.
+func ForkSyllabus(syll_uuid uuid.UUID, user_uuid uuid.UUID) (Syllabus, error) {
+	var source Syllabus
+	source_id, err := visibleSyllabusID(syll_uuid, user_uuid)
+	if err != nil {
+		return source, err
+	}
+
+	result := db.Preload("Attachments").Preload("Institutions").Where("id = ?", source_id).First(&source)
+	if result.Error != nil {
+		return source, result.Error
+	}
+
+	if !LicenseAllows(source.License, LicenseUseFork) {
+		return source, fmt.Errorf("%w: %s", ErrForkNotAllowed, source.License)
+	}
+
+	snapshot := source.Snapshot()
+	fork := Syllabus{
+		UUID:             uuid.New(),
+		Status:           "unlisted",
+		DerivedFromUUID:  &source.UUID,
+		Title:            snapshot.Title,
+		Description:      snapshot.Description,
+		AcademicFields:   snapshot.AcademicFields,
+		AcademicField:    snapshot.AcademicField,
+		AcademicLevel:    snapshot.AcademicLevel,
+		Assignments:      snapshot.Assignments,
+		Duration:         snapshot.Duration,
+		GradingRubric:    snapshot.GradingRubric,
+		Language:         snapshot.Language,
+		LearningOutcomes: snapshot.LearningOutcomes,
+		License:          snapshot.License,
+		Other:            snapshot.Other,
+		Readings:         snapshot.Readings,
+		Tags:             snapshot.Tags,
+		Instructors:      snapshot.Instructors,
+		TopicOutlines:    snapshot.TopicOutlines,
+	}
+
+	for _, inst := range source.Institutions {
+		fork.Institutions = append(fork.Institutions, Institution{
+			UUID:     uuid.New(),
+			Name:     inst.Name,
+			Country:  inst.Country,
+			Date:     inst.Date,
+			URL:      inst.URL,
+			Position: inst.Position,
+		})
+	}
+
+	for _, att := range source.Attachments {
+		fork.Attachments = append(fork.Attachments, Attachment{
+			UUID:        uuid.New(),
+			Name:        att.Name,
+			Type:        att.Type,
+			Description: att.Description,
+			URL:         att.URL,
+		})
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := createSyllabus(tx, &fork, user_uuid)
+		if err != nil {
+			return err
+		}
+
+		// -- the sessions and assessments of the original replace the ones the fork got from its topic outlines and assignments
+		err = copySessions(tx, source.ID, fork.ID)
+		if err != nil {
+			return err
+		}
+
+		return copyAssessments(tx, source.ID, fork.ID)
+	})
+	if err != nil {
+		return fork, err
+	}
+
+	return GetSyllabus(fork.UUID, user_uuid)
+}
+
+// withLineage adds to a syllabus the ones it was derived from which the user can see, from the one it was forked from up to
+// the original, and the number of listed syllabi forked from it
+func withLineage(syll *Syllabus, user_uuid uuid.UUID) error {
+	syll.Lineage = make([]SyllabusLink, 0)
+
+	if syll.DerivedFromUUID != nil {
+		// -- the lineage goes through deleted and hidden syllabi, which are then left out
+		err := db.Raw(`
+			WITH RECURSIVE lineage AS (
+				SELECT ?::uuid AS uuid, 1 AS depth
+				UNION ALL
+				SELECT syllabi.derived_from_uuid, lineage.depth + 1 FROM lineage
+				JOIN syllabi ON syllabi.uuid = lineage.uuid
+				WHERE syllabi.derived_from_uuid IS NOT NULL AND lineage.depth < ?
+			)
+			SELECT syllabi.uuid, syllabi.title, syllabi.slug, syllabi.license, syllabi.user_uuid, users.name AS user_name
+			FROM lineage
+			JOIN syllabi ON syllabi.uuid = lineage.uuid AND syllabi.deleted_at IS NULL
+			JOIN users ON users.uuid = syllabi.user_uuid
+			WHERE syllabi.status = 'listed' OR syllabi.user_uuid = ?
+			ORDER BY lineage.depth ASC`,
+			syll.DerivedFromUUID.String(), MAX_LINEAGE_DEPTH, user_uuid.String()).
+			Scan(&syll.Lineage).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return db.Model(&Syllabus{}).
+		Where("derived_from_uuid = ? AND status = 'listed'", syll.UUID).
+		Count(&syll.DerivativesCount).Error
+}
+
+// GetSyllabusDerivatives lists the syllabi visible to the user which were forked from the syllabus
+func GetSyllabusDerivatives(syll_uuid uuid.UUID, page Page, user_uuid uuid.UUID) ([]Syllabus, PageMeta, error) {
+	syllabi := make([]Syllabus, 0)
+
+	_, err := visibleSyllabusID(syll_uuid, user_uuid)
+	if err != nil {
+		return syllabi, PageMeta{}, err
+	}
+
+	if page.Sort == "" {
+		page.Sort = SortCreated
+	} else if page.Sort == SortRelevance {
+		return syllabi, PageMeta{}, fmt.Errorf("%w: sorting by relevance requires keywords", ErrInvalidPage)
+	}
+
+	query := filterSyllabi(db.Model(&Syllabus{}), map[string]any{}, user_uuid).Where("syllabi.derived_from_uuid = ?", syll_uuid)
+
+	query, meta, err := paginate(query, "syllabi", page, SYLLABUS_SORTS, nil)
+	if err != nil {
+		return syllabi, meta, err
+	}
+
+	result := query.Preload("User").Preload("Institutions").Find(&syllabi)
+	if result.Error != nil {
+		return syllabi, meta, result.Error
+	}
+
+	fetched := len(syllabi)
+	if page.Size > 0 && fetched > page.Size {
+		syllabi = syllabi[:page.Size]
+		last := syllabi[len(syllabi)-1]
+		meta.nextCursor(fetched, sortValue(page.Sort, last.CreatedAt, last.UpdatedAt, last.Title, last.Rank), last.ID)
+	}
+
+	return syllabi, meta, nil
+}
@@ -0,0 +1,106 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowsDerivatives(t *testing.T) {
+	for license, allowed := range map[string]bool{
+		"":                                    false,
+		"CC BY-SA 4.0":                        true,
+		"cc-by-nc-4.0":                        true,
+		"MIT":                                 true,
+		"All rights reserved":                 false,
+		"CC BY-ND 4.0":                        false,
+		"cc_by_nc_nd_3.0":                     false,
+		"© 2021 Univ. X, all rights reserved": false,
+		"free to use":                         false,
+	} {
+		assert.Equal(t, allowed, models.AllowsDerivatives(license), license)
+	}
+}
+
+func TestForkModel(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	var fork models.Syllabus
+	t.Run("Test fork syllabus", func(t *testing.T) {
+		var err error
+		fork, err = models.ForkSyllabus(syllabusID, userDeleteID)
+		require.Nil(t, err)
+
+		assert.NotEqual(t, syllabusID, fork.UUID)
+		assert.Equal(t, userDeleteID, fork.UserUUID)
+		assert.Equal(t, syllabusTitle, fork.Title)
+		assert.Equal(t, "unlisted", fork.Status)
+		require.NotNil(t, fork.DerivedFromUUID)
+		assert.Equal(t, syllabusID, *fork.DerivedFromUUID)
+
+		// -- attachments are copied, pointing to the same files
+		require.Equal(t, 3, len(fork.Attachments))
+		assert.NotEqual(t, attachmentID, fork.Attachments[0].UUID)
+
+		require.Equal(t, 1, len(fork.Lineage))
+		assert.Equal(t, syllabusID, fork.Lineage[0].UUID)
+		assert.Equal(t, syllabusUserName, fork.Lineage[0].UserName)
+	})
+
+	t.Run("Test fork of fork", func(t *testing.T) {
+		second, err := models.ForkSyllabus(fork.UUID, userDeleteID)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(second.Lineage))
+		assert.Equal(t, fork.UUID, second.Lineage[0].UUID)
+		assert.Equal(t, syllabusID, second.Lineage[1].UUID)
+	})
+
+	t.Run("Test derivatives count", func(t *testing.T) {
+		original, err := models.GetSyllabus(syllabusID, userID)
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), original.DerivativesCount)
+
+		_, err = models.UpdateSyllabus(fork.UUID, &models.Syllabus{Status: "listed"}, userDeleteID)
+		require.Nil(t, err)
+
+		original, err = models.GetSyllabus(syllabusID, userID)
+		require.Nil(t, err)
+		assert.Equal(t, int64(1), original.DerivativesCount)
+
+		derivatives, _, err := models.GetSyllabusDerivatives(syllabusID, models.Page{Size: models.DEFAULT_PAGE_SIZE}, userID)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(derivatives))
+		assert.Equal(t, fork.UUID, derivatives[0].UUID)
+	})
+
+	t.Run("Test restore revision with a license the original no longer allows", func(t *testing.T) {
+		_, err := models.UpdateSyllabus(fork.UUID, &models.Syllabus{License: "CC BY-NC 4.0"}, userDeleteID)
+		require.Nil(t, err)
+
+		_, err = models.UpdateSyllabus(syllabusID, &models.Syllabus{License: "CC BY-NC 4.0"}, userID)
+		require.Nil(t, err)
+
+		_, err = models.RestoreSyllabusRevision(fork.UUID, 1, userDeleteID)
+		assert.True(t, errors.Is(err, models.ErrInvalidLicense))
+
+		_, err = models.UpdateSyllabus(syllabusID, &models.Syllabus{License: "CC BY 4.0"}, userID)
+		require.Nil(t, err)
+	})
+
+	t.Run("Test fork unlisted syllabus", func(t *testing.T) {
+		_, err := models.ForkSyllabus(syllabusUnlistedID, userDeleteID)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test fork without derivatives", func(t *testing.T) {
+		_, err := models.UpdateSyllabus(syllabusID, &models.Syllabus{License: "CC BY-ND 4.0"}, userID)
+		require.Nil(t, err)
+
+		_, err = models.ForkSyllabus(syllabusID, userDeleteID)
+		assert.True(t, errors.Is(err, models.ErrForkNotAllowed))
+	})
+}
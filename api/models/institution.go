@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/biter777/countries"
@@ -39,3 +42,23 @@ func (i *Institution) BeforeUpdate(tx *gorm.DB) (err error) {
 	}
 	return nil
 }
+
+// ParseCountry resolves the ISO 3166-1 numeric code of a country from its alpha-2, alpha-3 or numeric code
+func ParseCountry(code string) (int, error) {
+	code = strings.TrimSpace(code)
+	if n, err := strconv.Atoi(code); err == nil {
+		if countries.ByNumeric(n) == countries.Unknown {
+			return 0, fmt.Errorf("%d is not an ISO 3166-1 country code", n)
+		}
+		return n, nil
+	}
+
+	if len(code) != 2 && len(code) != 3 {
+		return 0, fmt.Errorf("%s is not an ISO 3166-1 country code", code)
+	}
+	c := countries.ByName(code)
+	if c == countries.Unknown {
+		return 0, fmt.Errorf("%s is not an ISO 3166-1 country code", code)
+	}
+	return int(c), nil
+}
@@ -0,0 +1,357 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	LicenseAllRightsReserved string = "LicenseRef-All-Rights-Reserved"
+	LicensePublicDomain      string = "CC0-1.0"
+)
+
+// -- the uses a license is checked for. There is no export nor embed of syllabi yet, so redistribution is only described
+// by the permissions of a license, and not enforced.
+const (
+	LicenseUseFork       string = "fork"
+	LicenseUseCommercial string = "commercial"
+)
+
+var LICENSE_USES = []string{LicenseUseFork, LicenseUseCommercial}
+
+var ErrInvalidLicense = errors.New("the license is invalid")
+
+// -- licenses are compared regardless of case, and of the spaces and underscores written instead of dashes
+var licenseSeparators = regexp.MustCompile(`[[:space:]_]+`)
+
+// LicensePermissions is what a license lets others do with a syllabus. Redistribution covers sharing it as it is, such as
+// exporting or embedding it, and derivatives covers adapting it, such as forking it.
+type LicensePermissions struct {
+	Redistribution bool `json:"redistribution"`
+	Derivatives    bool `json:"derivatives"`
+	Commercial     bool `json:"commercial"`
+	ShareAlike     bool `json:"share_alike"`
+	Attribution    bool `json:"attribution"`
+}
+
+// License is a license a syllabus can be published under, identified by its SPDX identifier
+type License struct {
+	ID          string             `json:"id"`
+	Label       string             `json:"label"`
+	Name        string             `json:"name"`
+	URL         string             `json:"url"`
+	Permissions LicensePermissions `json:"permissions"`
+}
+
+// LICENSES are the licenses a syllabus can be published under: the Creative Commons licenses, the common open source and
+// documentation licenses, and all rights reserved
+var LICENSES = append(append(
+	creativeCommons("4.0", "International"),
+	creativeCommons("3.0", "Unported")...),
+	License{
+		ID:          LicensePublicDomain,
+		Label:       "CC0 1.0",
+		Name:        "Creative Commons Zero v1.0 Universal",
+		URL:         "https://creativecommons.org/publicdomain/zero/1.0/",
+		Permissions: LicensePermissions{Redistribution: true, Derivatives: true, Commercial: true},
+	},
+	License{
+		ID:          "Unlicense",
+		Label:       "Unlicense",
+		Name:        "The Unlicense",
+		URL:         "https://unlicense.org/",
+		Permissions: LicensePermissions{Redistribution: true, Derivatives: true, Commercial: true},
+	},
+	License{
+		ID:          "MIT",
+		Label:       "MIT",
+		Name:        "MIT License",
+		URL:         "https://opensource.org/licenses/MIT",
+		Permissions: LicensePermissions{Redistribution: true, Derivatives: true, Commercial: true, Attribution: true},
+	},
+	License{
+		ID:          "Apache-2.0",
+		Label:       "Apache 2.0",
+		Name:        "Apache License 2.0",
+		URL:         "https://www.apache.org/licenses/LICENSE-2.0",
+		Permissions: LicensePermissions{Redistribution: true, Derivatives: true, Commercial: true, Attribution: true},
+	},
+	License{
+		ID:          "BSD-2-Clause",
+		Label:       "BSD 2-Clause",
+		Name:        `BSD 2-Clause "Simplified" License`,
+		URL:         "https://opensource.org/licenses/BSD-2-Clause",
+		Permissions: LicensePermissions{Redistribution: true, Derivatives: true, Commercial: true, Attribution: true},
+	},
+	License{
+		ID:          "BSD-3-Clause",
+		Label:       "BSD 3-Clause",
+		Name:        `BSD 3-Clause "New" or "Revised" License`,
+		URL:         "https://opensource.org/licenses/BSD-3-Clause",
+		Permissions: LicensePermissions{Redistribution: true, Derivatives: true, Commercial: true, Attribution: true},
+	},
+	License{
+		ID:          "MPL-2.0",
+		Label:       "MPL 2.0",
+		Name:        "Mozilla Public License 2.0",
+		URL:         "https://www.mozilla.org/en-US/MPL/2.0/",
+		Permissions: LicensePermissions{Redistribution: true, Derivatives: true, Commercial: true, ShareAlike: true, Attribution: true},
+	},
+	License{
+		ID:          "GPL-3.0-only",
+		Label:       "GPL 3.0",
+		Name:        "GNU General Public License v3.0 only",
+		URL:         "https://www.gnu.org/licenses/gpl-3.0.html",
+		Permissions: LicensePermissions{Redistribution: true, Derivatives: true, Commercial: true, ShareAlike: true, Attribution: true},
+	},
+	License{
+		ID:          "GPL-3.0-or-later",
+		Label:       "GPL 3.0 or later",
+		Name:        "GNU General Public License v3.0 or later",
+		URL:         "https://www.gnu.org/licenses/gpl-3.0.html",
+		Permissions: LicensePermissions{Redistribution: true, Derivatives: true, Commercial: true, ShareAlike: true, Attribution: true},
+	},
+	License{
+		ID:          "GFDL-1.3-or-later",
+		Label:       "GFDL 1.3 or later",
+		Name:        "GNU Free Documentation License v1.3 or later",
+		URL:         "https://www.gnu.org/licenses/fdl-1.3.html",
+		Permissions: LicensePermissions{Redistribution: true, Derivatives: true, Commercial: true, ShareAlike: true, Attribution: true},
+	},
+	License{
+		ID:    LicenseAllRightsReserved,
+		Label: "All rights reserved",
+		Name:  "All rights reserved",
+	},
+)
+
+// -- other ways licenses are commonly written, once normalized
+var licenseAliases = map[string]string{
+	"ALL-RIGHTS-RESERVED": LicenseAllRightsReserved,
+	"COPYRIGHT":           LicenseAllRightsReserved,
+	"PUBLIC-DOMAIN":       LicensePublicDomain,
+	"CC0":                 LicensePublicDomain,
+	"GPL-3.0":             "GPL-3.0-only",
+	"GPL-3.0+":            "GPL-3.0-or-later",
+	"GFDL-1.3":            "GFDL-1.3-or-later",
+	"GFDL-1.3+":           "GFDL-1.3-or-later",
+}
+
+var licenseIndex = make(map[string]License)
+
+func init() {
+	for _, l := range LICENSES {
+		// -- the first license listed under a URL is the one it stands for
+		for _, key := range []string{l.ID, l.Label, l.Name, l.URL} {
+			if _, found := licenseIndex[licenseKey(key)]; key != "" && !found {
+				licenseIndex[licenseKey(key)] = l
+			}
+		}
+	}
+
+	for alias, id := range licenseAliases {
+		l, _ := LookupLicense(id)
+		licenseIndex[alias] = l
+	}
+}
+
+// creativeCommons lists the six Creative Commons licenses of a version
+func creativeCommons(version string, port string) []License {
+	names := map[string]string{"BY": "Attribution", "SA": "ShareAlike", "NC": "NonCommercial", "ND": "NoDerivatives"}
+	if version == "3.0" {
+		names["ND"] = "NoDerivs"
+	}
+
+	licenses := make([]License, 0)
+	for _, elements := range [][]string{{"BY"}, {"BY", "SA"}, {"BY", "NC"}, {"BY", "NC", "SA"}, {"BY", "ND"}, {"BY", "NC", "ND"}} {
+		full := make([]string, 0, len(elements))
+		permissions := LicensePermissions{Redistribution: true, Derivatives: true, Commercial: true, Attribution: true}
+		for _, e := range elements {
+			full = append(full, names[e])
+			switch e {
+			case "SA":
+				permissions.ShareAlike = true
+			case "NC":
+				permissions.Commercial = false
+			case "ND":
+				permissions.Derivatives = false
+			}
+		}
+
+		code := strings.Join(elements, "-")
+		licenses = append(licenses, License{
+			ID:          fmt.Sprintf("CC-%s-%s", code, version),
+			Label:       fmt.Sprintf("CC %s %s", code, version),
+			Name:        fmt.Sprintf("Creative Commons %s %s %s", strings.Join(full, "-"), version, port),
+			URL:         fmt.Sprintf("https://creativecommons.org/licenses/%s/%s/", strings.ToLower(code), version),
+			Permissions: permissions,
+		})
+	}
+	return licenses
+}
+
+func licenseKey(raw string) string {
+	key := strings.TrimSpace(raw)
+	for _, prefix := range []string{"https://", "http://", "www."} {
+		key = strings.TrimPrefix(key, prefix)
+	}
+	key = strings.TrimSuffix(key, "/")
+	return strings.ToUpper(licenseSeparators.ReplaceAllString(key, "-"))
+}
+
+// LookupLicense finds a license by its SPDX identifier, label, name or URL, as in CC-BY-SA-4.0, CC BY-SA 4.0 or
+// https://creativecommons.org/licenses/by-sa/4.0/. Creative Commons licenses without a version are the latest one.
+func LookupLicense(raw string) (License, bool) {
+	key := licenseKey(raw)
+	if l, found := licenseIndex[key]; found {
+		return l, true
+	}
+
+	if strings.HasPrefix(key, "CC-") {
+		l, found := licenseIndex[key+"-4.0"]
+		return l, found
+	}
+	return License{}, false
+}
+
+// NormalizeLicense returns the SPDX identifier of a license, or an error if it isn't one of LICENSES. Syllabi can also
+// have no license.
+func NormalizeLicense(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+
+	l, found := LookupLicense(raw)
+	if !found {
+		return raw, fmt.Errorf("%w: %q is not a Creative Commons license nor one of the supported SPDX identifiers", ErrInvalidLicense, raw)
+	}
+	return l.ID, nil
+}
+
+// PermissionsOf tells what a license lets others do with a syllabus. The syllabi without a license, or with one which isn't
+// supported, such as one written before licenses were checked, have all rights reserved by default.
+func PermissionsOf(license string) LicensePermissions {
+	if l, found := LookupLicense(license); found {
+		return l.Permissions
+	}
+
+	l, _ := LookupLicense(LicenseAllRightsReserved)
+	return l.Permissions
+}
+
+// LicenseAllows tells whether a syllabus under the license can be put to a use, such as being forked or used commercially
+func LicenseAllows(license string, use string) bool {
+	permissions := PermissionsOf(license)
+	switch use {
+	case LicenseUseFork:
+		return permissions.Derivatives
+	case LicenseUseCommercial:
+		return permissions.Commercial
+	default:
+		return false
+	}
+}
+
+// LicensesAllowing lists the identifiers of the licenses which allow all of the uses
+func LicensesAllowing(uses []string) []string {
+	ids := make([]string, 0)
+	for _, l := range LICENSES {
+		allowed := true
+		for _, use := range uses {
+			allowed = allowed && LicenseAllows(l.ID, use)
+		}
+		if allowed {
+			ids = append(ids, l.ID)
+		}
+	}
+	return ids
+}
+
+// AllowsDerivatives tells whether a syllabus under the license can be forked
+func AllowsDerivatives(license string) bool {
+	return LicenseAllows(license, LicenseUseFork)
+}
+
+// checkDerivedLicense checks that a syllabus derived from another keeps to the terms of its license: a share-alike license
+// can't be changed, and a non-commercial one can't be changed to one allowing commercial use.
+func checkDerivedLicense(original string, derived string) error {
+	from := PermissionsOf(original)
+	to := PermissionsOf(derived)
+
+	if from.ShareAlike && licenseKey(original) != licenseKey(derived) {
+		return fmt.Errorf("%w: this syllabus was derived from one under %s, which it must keep", ErrInvalidLicense, original)
+	}
+	if !from.Commercial && to.Commercial {
+		return fmt.Errorf("%w: this syllabus was derived from one under %s, which doesn't allow commercial use", ErrInvalidLicense, original)
+	}
+	return nil
+}
+
+// Credit writes the attribution of a syllabus its license asks for, to its instructors or otherwise its owner, along with
+// the original it was forked from
+func (s *Syllabus) Credit() string {
+	credit := strings.Join(s.Instructors, ", ")
+	if credit == "" {
+		credit = s.User.Name
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q", s.Title)
+	if credit != "" {
+		fmt.Fprintf(&b, " by %s", credit)
+	}
+
+	l, found := LookupLicense(s.License)
+	switch {
+	case found && l.ID == LicenseAllRightsReserved:
+		b.WriteString(", all rights reserved.")
+	case found && l.ID == LicensePublicDomain:
+		fmt.Fprintf(&b, ", dedicated to the public domain under %s (%s).", l.Label, l.URL)
+	case found:
+		fmt.Fprintf(&b, ", licensed under %s (%s).", l.Label, l.URL)
+	case s.License != "":
+		fmt.Fprintf(&b, ", licensed under %s.", s.License)
+	default:
+		b.WriteString(".")
+	}
+
+	if len(s.Lineage) > 0 {
+		original := s.Lineage[0]
+		fmt.Fprintf(&b, " Adapted from %q by %s.", original.Title, original.UserName)
+	}
+	return b.String()
+}
+
+// withLicense adds to a syllabus what its license allows and how to credit it
+func withLicense(syll *Syllabus) {
+	permissions := PermissionsOf(syll.License)
+	syll.Permissions = &permissions
+	syll.Attribution = syll.Credit()
+}
+
+// normalizeLicenses writes the licenses of the syllabi created before licenses were checked as their SPDX identifiers,
+// leaving the ones which aren't supported as they are
+func normalizeLicenses(db *gorm.DB) error {
+	var licenses []string
+	err := db.Model(&Syllabus{}).Distinct("license").Where("license <> ''").Pluck("license", &licenses).Error
+	if err != nil {
+		return err
+	}
+
+	for _, raw := range licenses {
+		l, found := LookupLicense(raw)
+		if !found || l.ID == raw {
+			continue
+		}
+
+		err = db.Model(&Syllabus{}).Where("license = ?", raw).UpdateColumn("license", l.ID).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
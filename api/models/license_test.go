@@ -0,0 +1,140 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeLicense(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"":                "",
+		"CC BY-SA 4.0":    "CC-BY-SA-4.0",
+		"cc_by_nc_nd_3.0": "CC-BY-NC-ND-3.0",
+		"CC BY-NC":        "CC-BY-NC-4.0",
+		"https://creativecommons.org/licenses/by/4.0/":              "CC-BY-4.0",
+		"Creative Commons Attribution-ShareAlike 4.0 International": "CC-BY-SA-4.0",
+		"Public domain":       "CC0-1.0",
+		"mit":                 "MIT",
+		"GPL-3.0+":            "GPL-3.0-or-later",
+		"All rights reserved": models.LicenseAllRightsReserved,
+	} {
+		license, err := models.NormalizeLicense(raw)
+		require.Nil(t, err, raw)
+		assert.Equal(t, expected, license, raw)
+	}
+
+	for _, raw := range []string{"CC BY-XY 4.0", "free to use", "CC-BY-5.0"} {
+		_, err := models.NormalizeLicense(raw)
+		assert.True(t, errors.Is(err, models.ErrInvalidLicense), raw)
+	}
+}
+
+func TestPermissionsOfUnknownLicense(t *testing.T) {
+	for _, raw := range []string{"", "  ", "© 2021 Univ. X, all rights reserved", "free to use"} {
+		permissions := models.PermissionsOf(raw)
+		assert.Equal(t, models.PermissionsOf(models.LicenseAllRightsReserved), permissions, raw)
+		assert.False(t, permissions.Redistribution, raw)
+		assert.False(t, permissions.Derivatives, raw)
+		assert.False(t, permissions.Commercial, raw)
+	}
+}
+
+func TestLicenseAllows(t *testing.T) {
+	assert.True(t, models.LicenseAllows("CC-BY-NC-SA-4.0", models.LicenseUseFork))
+	assert.False(t, models.LicenseAllows("CC-BY-NC-SA-4.0", models.LicenseUseCommercial))
+	assert.True(t, models.LicenseAllows("CC-BY-ND-4.0", models.LicenseUseCommercial))
+	assert.False(t, models.LicenseAllows(models.LicenseAllRightsReserved, models.LicenseUseCommercial))
+	assert.False(t, models.LicenseAllows("", models.LicenseUseFork))
+	assert.False(t, models.LicenseAllows("CC-BY-4.0", "export"))
+	assert.False(t, models.LicenseAllows("CC BY-ND 2.0", models.LicenseUseFork))
+	assert.False(t, models.LicenseAllows("MIT", "sell"))
+
+	ids := models.LicensesAllowing([]string{models.LicenseUseFork, models.LicenseUseCommercial})
+	assert.Contains(t, ids, "CC-BY-SA-4.0")
+	assert.NotContains(t, ids, "CC-BY-NC-4.0")
+	assert.NotContains(t, ids, "CC-BY-ND-4.0")
+}
+
+func TestSyllabusCredit(t *testing.T) {
+	syll := models.Syllabus{Title: "Ungewohnt", License: "CC-BY-SA-4.0", User: models.User{Name: "Justyna Poplawska"}}
+	assert.Equal(t, `"Ungewohnt" by Justyna Poplawska, licensed under CC BY-SA 4.0 (https://creativecommons.org/licenses/by-sa/4.0/).`, syll.Credit())
+
+	syll.Instructors = []string{"Anna", "Bob"}
+	syll.License = models.LicenseAllRightsReserved
+	assert.Equal(t, `"Ungewohnt" by Anna, Bob, all rights reserved.`, syll.Credit())
+
+	syll.License = ""
+	syll.Lineage = []models.SyllabusLink{{Title: "Gewohnt", UserName: "Pierre"}}
+	assert.Equal(t, `"Ungewohnt" by Anna, Bob. Adapted from "Gewohnt" by Pierre.`, syll.Credit())
+}
+
+func TestLicenseModel(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	t.Run("Test create syllabus with license", func(t *testing.T) {
+		syll, err := models.CreateSyllabus(&models.Syllabus{
+			UUID:        uuid.New(),
+			Title:       "Licensed",
+			Description: "A syllabus under a Creative Commons license",
+			License:     "cc by-sa 4.0",
+		}, userID)
+		require.Nil(t, err)
+		assert.Equal(t, "CC-BY-SA-4.0", syll.License)
+		require.NotNil(t, syll.Permissions)
+		assert.True(t, syll.Permissions.ShareAlike)
+		assert.Contains(t, syll.Attribution, "CC BY-SA 4.0")
+	})
+
+	t.Run("Test create syllabus with invalid license", func(t *testing.T) {
+		_, err := models.CreateSyllabus(&models.Syllabus{
+			UUID:        uuid.New(),
+			Title:       "Unlicensed",
+			Description: "A syllabus under terms of its own",
+			License:     "free to use",
+		}, userID)
+		assert.True(t, errors.Is(err, models.ErrInvalidLicense))
+	})
+
+	t.Run("Test update syllabus with invalid license", func(t *testing.T) {
+		_, err := models.UpdateSyllabus(syllabusID, &models.Syllabus{License: "free to use"}, userID)
+		assert.True(t, errors.Is(err, models.ErrInvalidLicense))
+	})
+
+	t.Run("Test fork keeps share-alike license", func(t *testing.T) {
+		_, err := models.UpdateSyllabus(syllabusID, &models.Syllabus{License: "CC BY-NC-SA 4.0"}, userID)
+		require.Nil(t, err)
+
+		fork, err := models.ForkSyllabus(syllabusID, userDeleteID)
+		require.Nil(t, err)
+		assert.Equal(t, "CC-BY-NC-SA-4.0", fork.License)
+		assert.Contains(t, fork.Attribution, "Adapted from")
+
+		_, err = models.UpdateSyllabus(fork.UUID, &models.Syllabus{License: "CC BY 4.0"}, userDeleteID)
+		assert.True(t, errors.Is(err, models.ErrInvalidLicense))
+
+		_, err = models.UpdateSyllabus(fork.UUID, &models.Syllabus{License: "CC-BY-NC-SA-4.0", Title: "Forked and changed"}, userDeleteID)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Test get syllabi by license", func(t *testing.T) {
+		params := map[string]any{"keywords": "", "licenses": []string{"CC-BY-NC-SA-4.0"}}
+		sylls, _, err := models.GetSyllabi(params, models.Page{}, userID)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(sylls))
+		assert.Equal(t, syllabusID, sylls[0].UUID)
+
+		delete(params, "licenses")
+		params["permits"] = []string{models.LicenseUseCommercial}
+		sylls, _, err = models.GetSyllabi(params, models.Page{}, userID)
+		require.Nil(t, err)
+		for _, s := range sylls {
+			assert.NotEqual(t, syllabusID, s.UUID)
+		}
+	})
+}
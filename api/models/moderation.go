@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrModerated = errors.New("the status was set by a moderator")
+
+// Moderation records the moderator who unlisted a syllabus or a collection. While it is set, only admins can change its
+// status, so that its owner can't list it again right away.
+type Moderation struct {
+	ModeratedAt   *time.Time `json:"moderated_at"`
+	ModeratorUUID *uuid.UUID `gorm:"type:uuid" json:"-"`
+}
+
+func (m *Moderation) moderate(moderator_uuid uuid.UUID) {
+	now := time.Now()
+	m.ModeratedAt = &now
+	m.ModeratorUUID = &moderator_uuid
+}
+
+// checkModeration tells whether the user can change the status of moderated content, and whether doing so lifts the
+// moderation, which only admins do
+func (m *Moderation) checkModeration(current string, requested string, user_uuid uuid.UUID) (bool, error) {
+	if m.ModeratedAt == nil || requested == "" || requested == current {
+		return false, nil
+	}
+
+	var user User
+	err := db.Select("role").Where("uuid = ?", user_uuid).First(&user).Error
+	if err != nil || !HasRole(user.Role, RoleAdmin) {
+		return false, ErrModerated
+	}
+	return true, nil
+}
+
+// -- columns only set by moderating content, left out of the updates of its owner
+var moderationColumns = []string{"moderated_at", "moderator_uuid"}
+
+// GetAdminSyllabi returns a page of all the syllabi, whatever their owner and their status, including the ones unlisted by a moderator
+func GetAdminSyllabi(page Page) ([]Syllabus, PageMeta, error) {
+	if page.Sort == "" {
+		page.Sort = SortCreated
+	} else if page.Sort == SortRelevance {
+		return make([]Syllabus, 0), PageMeta{}, fmt.Errorf("%w: sorting by relevance requires keywords", ErrInvalidPage)
+	}
+
+	query, meta, err := paginate(db.Model(&Syllabus{}), "syllabi", page, SYLLABUS_SORTS, nil)
+	if err != nil {
+		return make([]Syllabus, 0), meta, err
+	}
+
+	syllabi, err := findSyllabi(query, page, &meta)
+	return syllabi, meta, err
+}
+
+// GetAdminCollections returns a page of all the collections, whatever their owner and their status, including the ones unlisted by a moderator
+func GetAdminCollections(page Page) ([]Collection, PageMeta, error) {
+	if page.Sort == "" {
+		page.Sort = SortCreated
+	}
+
+	query, meta, err := paginate(db.Model(&Collection{}), "collections", page, COLLECTION_SORTS, nil)
+	if err != nil {
+		return make([]Collection, 0), meta, err
+	}
+
+	coll, err := findCollections(query, page, &meta)
+	return coll, meta, err
+}
@@ -0,0 +1,215 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOIDCStateInvalid = errors.New("the login request is invalid, expired or already used")
+	ErrAccountSuspended = errors.New("the account is suspended")
+)
+
+// OIDCState is a login started with an identity provider and not yet completed. The state sent to the provider is only stored hashed,
+// along with the nonce expected in the ID token and the PKCE verifier to redeem the authorization code.
+type OIDCState struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UUID      uuid.UUID `gorm:"uniqueIndex;type:uuid;primaryKey;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
+	Provider  string    `gorm:"not null" json:"provider"`
+	Hash      string    `gorm:"uniqueIndex;not null" json:"-"`
+	Nonce     string    `gorm:"not null" json:"-"`
+	Verifier  string    `gorm:"not null" json:"-"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+}
+
+// UserIdentity links an account to the subject of an identity provider, so that the user can log in through it
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UUID      uuid.UUID `gorm:"uniqueIndex;type:uuid;primaryKey;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
+	UserUUID  uuid.UUID `gorm:"type:uuid;index;not null" json:"user_uuid"`
+	Provider  string    `gorm:"not null;uniqueIndex:idx_identity_subject" json:"provider"`
+	Subject   string    `gorm:"not null;uniqueIndex:idx_identity_subject" json:"subject"`
+	Email     string    `json:"email"`
+}
+
+// OIDCProfile is what an identity provider vouches for about a user
+type OIDCProfile struct {
+	Provider     string
+	Subject      string
+	Email        string
+	Name         string
+	Institutions []string
+}
+
+// CreateOIDCState stores a pending login, and returns the state to send to the identity provider
+func CreateOIDCState(provider string, nonce string, verifier string, ttl time.Duration) (string, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	state := OIDCState{
+		UUID:      uuid.New(),
+		Provider:  provider,
+		Hash:      hashSecret(secret),
+		Nonce:     nonce,
+		Verifier:  verifier,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	result := db.Create(&state)
+	return secret, result.Error
+}
+
+// RedeemOIDCState deletes the pending login and returns it. The lookup and the deletion happen in a single statement,
+// so that a state can't be redeemed twice.
+func RedeemOIDCState(secret string, provider string) (OIDCState, error) {
+	var state OIDCState
+	result := db.Raw("DELETE FROM oidc_states WHERE hash = ? AND provider = ? AND expires_at > ? RETURNING *",
+		hashSecret(secret), provider, time.Now()).Scan(&state)
+	if result.Error != nil {
+		return state, result.Error
+	}
+
+	if result.RowsAffected == 0 || state.Verifier == "" {
+		return state, ErrOIDCStateInvalid
+	}
+
+	return state, nil
+}
+
+// LoginOIDCUser returns the account linked to the identity of the profile. If there is none, the account with the same email is linked,
+// or a new one is created. The email must have been verified by the provider: a pending account is confirmed and its password reset,
+// since whoever registered it never proved they own the address. The institutions of the profile are added to the account if missing.
+func LoginOIDCUser(profile OIDCProfile) (User, error) {
+	var user User
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var identity UserIdentity
+		result := tx.Where("provider = ? AND subject = ?", profile.Provider, profile.Subject).Limit(1).Find(&identity)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected > 0 {
+			return tx.Where("uuid = ?", identity.UserUUID).First(&user).Error
+		}
+
+		result = tx.Where("email = ?", profile.Email).Limit(1).Find(&user)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			password, err := unusablePassword()
+			if err != nil {
+				return err
+			}
+
+			user = User{
+				UUID:     uuid.New(),
+				Email:    profile.Email,
+				Name:     profile.Name,
+				Password: password,
+				Status:   UserConfirmed,
+			}
+			if user.Name == "" {
+				user.Name = "Anonymous User"
+			}
+
+			err = tx.Create(&user).Error
+			if err != nil {
+				return err
+			}
+		} else if user.Status != UserPending && user.Status != UserConfirmed {
+			return ErrAccountSuspended
+		} else if user.Status == UserPending {
+			password, err := unusablePassword()
+			if err != nil {
+				return err
+			}
+
+			err = tx.Model(&user).Updates(User{Status: UserConfirmed, Password: password}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&UserIdentity{
+			UUID:     uuid.New(),
+			UserUUID: user.UUID,
+			Provider: profile.Provider,
+			Subject:  profile.Subject,
+			Email:    profile.Email,
+		}).Error
+	})
+	if err != nil {
+		return user, err
+	}
+
+	if user.Status != UserConfirmed && user.Status != UserPending {
+		return user, ErrAccountSuspended
+	}
+
+	err = addMissingInstitutions(user, profile.Institutions)
+	if err != nil {
+		return user, err
+	}
+
+	return GetUser(user.UUID, user.UUID)
+}
+
+// addMissingInstitutions adds institutions by name, skipping the ones the user is already affiliated with
+func addMissingInstitutions(user User, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	var existing []Institution
+	err := db.Model(&user).Association("Institutions").Find(&existing)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool)
+	for _, inst := range existing {
+		known[inst.Name] = true
+	}
+
+	for _, name := range names {
+		if name == "" || known[name] {
+			continue
+		}
+
+		_, err = AddInstitutionToUser(user.UUID, &Institution{UUID: uuid.New(), Name: name})
+		if err != nil {
+			return err
+		}
+		known[name] = true
+	}
+
+	return nil
+}
+
+// unusablePassword returns the hash of a random secret, for accounts which only log in through an identity provider.
+// The password can still be set later through account recovery.
+func unusablePassword() ([]byte, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	return bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+}
+
+// PurgeExpiredOIDCStates deletes all logins which were never completed
+func PurgeExpiredOIDCStates() (int64, error) {
+	result := db.Where("expires_at < ?", time.Now()).Delete(&OIDCState{})
+	return result.RowsAffected, result.Error
+}
@@ -56,6 +56,9 @@ type OpenSyllabus struct {
 		} `json:"extracted_sections"`
 		URLs      []string `json:"urls"`
 		Citations []struct {
+			Span struct {
+				Text string `json:"text"`
+			} `json:"doc_span"`
 			Parsed struct {
 				Title []struct {
 					Text            string  `json:"text"`
@@ -92,10 +95,12 @@ type OpenSyllabusParsed struct {
 	LearningOutcomes []string                        `json:"learning_outcomes"`
 	TopicOutline     string                          `json:"topic_outlines"`
 	Readings         []string                        `json:"readings"`
+	Citations        []Reading                       `json:"citations"`
 	GradingRubric    []string                        `json:"grading_rubric"`
 	Schedule         []string                        `json:"other"`
 	Attachments      []string                        `json:"attachments"`
-	Assessments      []string                        `json:"assignments"`
+	Assignments      []string                        `json:"assignments"`
+	Assessments      []Assessment                    `json:"assessments"`
 	URLs             []string                        `json:"urls"`
 }
 
@@ -188,6 +193,36 @@ func (os *OpenSyllabus) GetReadings() []string {
 	return readings
 }
 
+// GetCitations returns the readings of the citations OpenSyllabus parsed, with all their authors and the year found in
+// the text of the citation, and the citations without a title left out
+func (os *OpenSyllabus) GetCitations() []Reading {
+	readings := make([]Reading, 0)
+
+	for _, c := range os.Data.Citations {
+		if len(c.Parsed.Title) == 0 {
+			continue
+		}
+
+		r := Reading{Title: cleanCitationPart(c.Parsed.Title[0].Text)}
+		for _, a := range c.Parsed.Author {
+			if author := cleanCitationPart(a.Text); author != "" {
+				r.Authors = append(r.Authors, author)
+			}
+		}
+
+		if r.Title == "" {
+			continue
+		}
+		if span, err := ParseReading(c.Span.Text); err == nil {
+			r.Year = span.Year
+		}
+		r.Key = ReadingKey(r)
+		readings = append(readings, r)
+	}
+
+	return readings
+}
+
 func (os *OpenSyllabus) GetGradingRubric() []string {
 	var rubric []string
 
@@ -235,3 +270,8 @@ func (os *OpenSyllabus) GetAssignments() []string {
 
 	return assignments
 }
+
+// GetAssessments reads the assessment strategy into structured assessments, keeping their weights only if they add up to 100
+func (os *OpenSyllabus) GetAssessments() []Assessment {
+	return ParseAssessments(os.GetAssignments())
+}
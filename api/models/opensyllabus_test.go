@@ -20,3 +20,51 @@ func TestOpenSyllabus_GetTitle(t *testing.T) {
 		}
 	})
 }
+
+func TestOpenSyllabus_GetCitations(t *testing.T) {
+	var raw OpenSyllabus
+	var citation struct {
+		Span struct {
+			Text string `json:"text"`
+		} `json:"doc_span"`
+		Parsed struct {
+			Title []struct {
+				Text            string  `json:"text"`
+				MeanProbability float64 `json:"mean_proba"`
+			} `json:"title"`
+			Author []struct {
+				Text            string  `json:"text"`
+				MeanProbability float64 `json:"mean_proba"`
+			} `json:"author"`
+		} `json:"parsed_citation"`
+	}
+	citation.Parsed.Title = append(citation.Parsed.Title, struct {
+		Text            string  `json:"text"`
+		MeanProbability float64 `json:"mean_proba"`
+	}{Text: "The Death and Life of Great American Cities.", MeanProbability: 0.9})
+	citation.Parsed.Author = append(citation.Parsed.Author, struct {
+		Text            string  `json:"text"`
+		MeanProbability float64 `json:"mean_proba"`
+	}{Text: "Jane Jacobs", MeanProbability: 0.9})
+
+	citation.Span.Text = "Jane Jacobs, The Death and Life of Great American Cities. New York: Random House, 1961."
+
+	raw.Data.Citations = append(raw.Data.Citations, citation)
+	raw.Data.Citations = append(raw.Data.Citations, raw.Data.Citations[0])
+	raw.Data.Citations[1].Parsed.Title = nil
+
+	t.Run("test get citations", func(t *testing.T) {
+		got := raw.GetCitations()
+		if len(got) != 1 {
+			t.Fatalf("OpenSyllabus.GetCitations() = %v, want 1 citation", got)
+		}
+
+		parsed, _ := ParseReading(raw.GetReadings()[0])
+		if got[0].Title != "The Death and Life of Great American Cities" || got[0].Key != parsed.Key {
+			t.Errorf("OpenSyllabus.GetCitations() = %v, want the key of %v", got[0], parsed)
+		}
+		if got[0].Year != 1961 {
+			t.Errorf("OpenSyllabus.GetCitations() = %v, want the year of its text", got[0])
+		}
+	})
+}
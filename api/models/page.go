@@ -0,0 +1,192 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DEFAULT_PAGE_SIZE = 15
+	MAX_PAGE_SIZE     = 100
+)
+
+const (
+	SortRelevance string = "relevance"
+	SortCreated   string = "created"
+	SortUpdated   string = "updated"
+	SortTitle     string = "title"
+	SortName      string = "name"
+)
+
+var ErrInvalidPage = errors.New("the pagination parameters are invalid")
+
+// Page asks for the results following the cursor, in the order of one of the sorts of the resource.
+// Order is either asc or desc, and defaults to the natural order of the sort. A size of 0 asks for all results.
+type Page struct {
+	Size   int
+	Cursor string
+	Sort   string
+	Order  string
+}
+
+// PageMeta describes a page of results, Total being the number of results across all pages. NextCursor is empty on the last page.
+type PageMeta struct {
+	Total      int64  `json:"total"`
+	PageSize   int    `json:"page_size"`
+	NextCursor string `json:"next_cursor"`
+	Sort       string `json:"sort"`
+	Order      string `json:"order"`
+}
+
+// sortKey orders results by an SQL expression, Cast being the type to compare the values of cursors with
+type sortKey struct {
+	Expr string
+	Cast string
+	Desc bool
+}
+
+// cursor points at the last result of a page, with the value it was sorted by and its ID to break ties
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// SYLLABUS_SORTS, COLLECTION_SORTS, USER_SORTS and ATTACHMENT_SORTS are the orders each kind of resource can be listed in.
+// Sorting syllabi by relevance is only possible when searching them.
+var (
+	SYLLABUS_SORTS = map[string]sortKey{
+		SortRelevance: {Expr: "ts_rank_cd(syllabi.search_vector, " + searchQuery() + ")", Cast: "real", Desc: true},
+		SortCreated:   {Expr: "syllabi.created_at", Cast: "timestamptz", Desc: true},
+		SortUpdated:   {Expr: "syllabi.updated_at", Cast: "timestamptz", Desc: true},
+		SortTitle:     {Expr: "syllabi.title", Cast: "text"},
+	}
+	COLLECTION_SORTS = map[string]sortKey{
+		SortCreated: {Expr: "collections.created_at", Cast: "timestamptz", Desc: true},
+		SortUpdated: {Expr: "collections.updated_at", Cast: "timestamptz", Desc: true},
+		SortName:    {Expr: "collections.name", Cast: "text"},
+	}
+	USER_SORTS = map[string]sortKey{
+		SortCreated: {Expr: "users.created_at", Cast: "timestamptz", Desc: true},
+		SortUpdated: {Expr: "users.updated_at", Cast: "timestamptz", Desc: true},
+		SortName:    {Expr: "users.name", Cast: "text"},
+	}
+	ATTACHMENT_SORTS = map[string]sortKey{
+		SortCreated: {Expr: "attachments.created_at", Cast: "timestamptz", Desc: true},
+		SortUpdated: {Expr: "attachments.updated_at", Cast: "timestamptz", Desc: true},
+		SortName:    {Expr: "attachments.name", Cast: "text"},
+	}
+)
+
+// paginate counts the results of the query, then restricts it to the page, sorted by the key of the page and by the ID of the table
+// so that no result is skipped or repeated across pages. One more result than the size of the page is fetched, to know whether
+// there is a next page. Args are the named arguments of the query, if the sort expression uses any.
+func paginate(query *gorm.DB, table string, page Page, sorts map[string]sortKey, args map[string]interface{}) (*gorm.DB, PageMeta, error) {
+	meta := PageMeta{PageSize: page.Size, Sort: page.Sort}
+
+	key, found := sorts[page.Sort]
+	if !found {
+		return query, meta, fmt.Errorf("%w: unknown sort %s", ErrInvalidPage, page.Sort)
+	}
+
+	desc := key.Desc
+	switch page.Order {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		return query, meta, fmt.Errorf("%w: unknown order %s", ErrInvalidPage, page.Order)
+	}
+
+	meta.Order = "asc"
+	direction, comparison := "ASC", ">"
+	if desc {
+		meta.Order = "desc"
+		direction, comparison = "DESC", "<"
+	}
+
+	err := query.Session(&gorm.Session{}).Count(&meta.Total).Error
+	if err != nil {
+		return query, meta, err
+	}
+
+	named := map[string]interface{}{}
+	for k, v := range args {
+		named[k] = v
+	}
+
+	if page.Cursor != "" {
+		cur, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return query, meta, err
+		}
+
+		if cur.Sort != page.Sort {
+			return query, meta, fmt.Errorf("%w: the cursor was given for another sort", ErrInvalidPage)
+		}
+
+		named["cursor_value"] = cur.Value
+		named["cursor_id"] = cur.ID
+		query = query.Where(fmt.Sprintf("(%s, %s.id) %s (CAST(@cursor_value AS %s), @cursor_id)", key.Expr, table, comparison, key.Cast), named)
+	}
+
+	query = query.Clauses(clause.OrderBy{Expression: clause.NamedExpr{
+		SQL:  fmt.Sprintf("%s %s, %s.id %s", key.Expr, direction, table, direction),
+		Vars: []interface{}{named},
+	}})
+
+	if page.Size > 0 {
+		query = query.Limit(page.Size + 1)
+	}
+
+	return query, meta, nil
+}
+
+// nextCursor points at the last result of a page, when there are more results than fit in it
+func (m *PageMeta) nextCursor(fetched int, value string, id uint) {
+	if m.PageSize <= 0 || fetched <= m.PageSize {
+		return
+	}
+
+	raw, _ := json.Marshal(cursor{Sort: m.Sort, Value: value, ID: id})
+	m.NextCursor = base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var cur cursor
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cur, fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+	}
+
+	err = json.Unmarshal(raw, &cur)
+	if err != nil {
+		return cur, fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+	}
+
+	return cur, nil
+}
+
+// sortValue formats the value a result was sorted by, as it is compared against in the next page
+func sortValue(sort string, created time.Time, updated time.Time, title string, rank float64) string {
+	switch sort {
+	case SortRelevance:
+		return strconv.FormatFloat(rank, 'g', -1, 64)
+	case SortCreated:
+		return created.Format(time.RFC3339Nano)
+	case SortUpdated:
+		return updated.Format(time.RFC3339Nano)
+	default:
+		return title
+	}
+}
@@ -0,0 +1,519 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/language"
+)
+
+// A query combines terms with AND, OR and NOT, and groups them with parentheses. Terms next to each other must all match,
+// and a term starting with - must not. A term is either a word or a "quoted phrase", searched for in the full text of the
+// syllabi, or a field:value pair, which only matches syllabi with that value. Fields which are numbers also take a range,
+// such as year:2019..2022, year:2019.. or year:..2022, and a field followed by * also matches its subfields, such as field:54*.
+//
+//	query  = or
+//	or     = and { "OR" and }
+//	and    = not { [ "AND" ] not }
+//	not    = ( "NOT" | "-" ) not | group
+//	group  = "(" or ")" | term
+//	term   = [ field ":" ] ( word | phrase )
+//	field  = "title" | "description" | "instructor" | "tag" | "field" | "level" | "language" | "year" | "institution" | "country" | "term" | "license"
+//
+// Operators are written in capitals: lowercase and, or and not are searched for as words.
+
+const (
+	MAX_QUERY_LENGTH = 1000
+	MAX_QUERY_DEPTH  = 20
+)
+
+var ErrInvalidQuery = errors.New("the query is invalid")
+
+// QueryError is the reason a query could not be parsed, at a position counted in characters from 1
+type QueryError struct {
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s at position %d: %s", ErrInvalidQuery, e.Pos, e.Msg)
+}
+
+func (e *QueryError) Unwrap() error {
+	return ErrInvalidQuery
+}
+
+// Query is a condition on syllabi, with the arguments of its placeholders
+type Query struct {
+	SQL  string
+	Args []interface{}
+}
+
+// ParseQuery parses a query and compiles it to a condition on syllabi. A blank query is nil.
+func ParseQuery(s string) (*Query, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	if n := len([]rune(s)); n > MAX_QUERY_LENGTH {
+		return nil, &QueryError{Pos: MAX_QUERY_LENGTH + 1, Msg: fmt.Sprintf("the query is longer than %d characters", MAX_QUERY_LENGTH)}
+	}
+
+	tokens, err := lexQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := queryParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.Kind != tokEnd {
+		return nil, &QueryError{Pos: t.Pos, Msg: "this closing parenthesis has no opening one"}
+	}
+
+	sql, args, err := node.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Query{SQL: sql, Args: args}, nil
+}
+
+const (
+	tokEnd = iota
+	tokWord
+	tokPhrase
+	tokField
+	tokOpen
+	tokClose
+	tokAnd
+	tokOr
+	tokNot
+)
+
+// token is a part of a query. Fields hold their name as Text, and their value as Value, which starts at ValuePos.
+type token struct {
+	Kind     int
+	Pos      int
+	Text     string
+	Value    string
+	ValuePos int
+	Phrase   bool
+}
+
+func lexQuery(s string) ([]token, error) {
+	runes := []rune(s)
+	tokens := make([]token, 0)
+
+	// -- words run until a space, a parenthesis or a quote
+	word := func(i int) int {
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+			i++
+		}
+		return i
+	}
+
+	// -- phrases run until the next quote, starting with the one at i
+	phrase := func(i int) (string, int, error) {
+		end := i + 1
+		for end < len(runes) && runes[end] != '"' {
+			end++
+		}
+		if end == len(runes) {
+			return "", end, &QueryError{Pos: i + 1, Msg: "this phrase has no closing quote"}
+		}
+		text := string(runes[i+1 : end])
+		if strings.TrimSpace(text) == "" {
+			return "", end, &QueryError{Pos: i + 1, Msg: "this phrase is empty"}
+		}
+		return text, end + 1, nil
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{Kind: tokOpen, Pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{Kind: tokClose, Pos: pos})
+			i++
+		case r == '-':
+			if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) || runes[i+1] == ')' {
+				return tokens, &QueryError{Pos: pos, Msg: "- should be followed by the term to exclude"}
+			}
+			tokens = append(tokens, token{Kind: tokNot, Pos: pos})
+			i++
+		case r == '"':
+			text, end, err := phrase(i)
+			if err != nil {
+				return tokens, err
+			}
+			tokens = append(tokens, token{Kind: tokPhrase, Pos: pos, Text: text, Phrase: true})
+			i = end
+		default:
+			end := word(i)
+			text := string(runes[i:end])
+
+			colon := strings.IndexRune(text, ':')
+			if colon < 0 {
+				switch text {
+				case "AND":
+					tokens = append(tokens, token{Kind: tokAnd, Pos: pos})
+				case "OR":
+					tokens = append(tokens, token{Kind: tokOr, Pos: pos})
+				case "NOT":
+					tokens = append(tokens, token{Kind: tokNot, Pos: pos})
+				default:
+					tokens = append(tokens, token{Kind: tokWord, Pos: pos, Text: text})
+				}
+				i = end
+				continue
+			}
+
+			name := text[:colon]
+			if name == "" {
+				return tokens, &QueryError{Pos: pos, Msg: "a field name should come before :"}
+			}
+
+			field := strings.ToLower(name)
+			t := token{Kind: tokField, Pos: pos, Text: field}
+			i += len([]rune(name)) + 1
+			t.ValuePos = i + 1
+
+			if i < len(runes) && runes[i] == '"' {
+				value, end, err := phrase(i)
+				if err != nil {
+					return tokens, err
+				}
+				t.Value, t.Phrase, i = value, true, end
+			} else {
+				end = word(i)
+				t.Value, i = string(runes[i:end]), end
+			}
+
+			if t.Value == "" {
+				return tokens, &QueryError{Pos: pos, Msg: fmt.Sprintf("the field %s has no value", field)}
+			}
+			tokens = append(tokens, t)
+		}
+	}
+
+	tokens = append(tokens, token{Kind: tokEnd, Pos: len(runes) + 1})
+	return tokens, nil
+}
+
+const (
+	opTerm = iota
+	opAnd
+	opOr
+	opNot
+)
+
+type queryNode struct {
+	Op       int
+	Term     token
+	Children []*queryNode
+}
+
+type queryParser struct {
+	tokens []token
+	i      int
+	depth  int
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *queryParser) next() token {
+	t := p.tokens[p.i]
+	if t.Kind != tokEnd {
+		p.i++
+	}
+	return t
+}
+
+func (p *queryParser) parseOr() (*queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().Kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &queryNode{Op: opOr, Children: []*queryNode{left, right}}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (*queryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek().Kind {
+		case tokAnd:
+			p.next()
+		case tokWord, tokPhrase, tokField, tokOpen, tokNot:
+		default:
+			return left, nil
+		}
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &queryNode{Op: opAnd, Children: []*queryNode{left, right}}
+	}
+}
+
+func (p *queryParser) parseNot() (*queryNode, error) {
+	t := p.next()
+
+	switch t.Kind {
+	case tokNot:
+		if err := p.enter(t); err != nil {
+			return nil, err
+		}
+		child, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		p.depth--
+		return &queryNode{Op: opNot, Children: []*queryNode{child}}, nil
+	case tokOpen:
+		if err := p.enter(t); err != nil {
+			return nil, err
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().Kind != tokClose {
+			return nil, &QueryError{Pos: t.Pos, Msg: "this parenthesis is never closed"}
+		}
+		p.next()
+		p.depth--
+		return node, nil
+	case tokWord, tokPhrase, tokField:
+		return &queryNode{Op: opTerm, Term: t}, nil
+	case tokAnd:
+		return nil, &QueryError{Pos: t.Pos, Msg: "AND should be between two terms"}
+	case tokOr:
+		return nil, &QueryError{Pos: t.Pos, Msg: "OR should be between two terms"}
+	case tokClose:
+		return nil, &QueryError{Pos: t.Pos, Msg: "a term was expected before this closing parenthesis"}
+	default:
+		return nil, &QueryError{Pos: t.Pos, Msg: "the query ends where a term was expected"}
+	}
+}
+
+func (p *queryParser) enter(t token) error {
+	p.depth++
+	if p.depth > MAX_QUERY_DEPTH {
+		return &QueryError{Pos: t.Pos, Msg: fmt.Sprintf("the query nests more than %d groups", MAX_QUERY_DEPTH)}
+	}
+	return nil
+}
+
+func (n *queryNode) compile() (string, []interface{}, error) {
+	switch n.Op {
+	case opTerm:
+		if n.Term.Kind != tokField {
+			return compileSearch(n.Term)
+		}
+		compile, found := queryFields[n.Term.Text]
+		if !found {
+			return "", nil, &QueryError{Pos: n.Term.Pos, Msg: fmt.Sprintf("there is no field %s", n.Term.Text)}
+		}
+		return compile(n.Term)
+	case opNot:
+		sql, args, err := n.Children[0].compile()
+		return "NOT " + sql, args, err
+	default:
+		left, args, err := n.Children[0].compile()
+		if err != nil {
+			return "", nil, err
+		}
+		right, more, err := n.Children[1].compile()
+		if err != nil {
+			return "", nil, err
+		}
+
+		op := " AND "
+		if n.Op == opOr {
+			op = " OR "
+		}
+		return "(" + left + op + right + ")", append(args, more...), nil
+	}
+}
+
+// compileSearch searches the full text of the syllabi for a word or a phrase, with every configuration, as searchQuery does
+func compileSearch(t token) (string, []interface{}, error) {
+	text := t.Text
+	if t.Phrase {
+		text = `"` + text + `"`
+	}
+
+	configs := searchConfigs()
+	queries := make([]string, 0, len(configs))
+	args := make([]interface{}, 0, len(configs))
+	for _, c := range configs {
+		queries = append(queries, fmt.Sprintf("websearch_to_tsquery('%s', ?)", c))
+		args = append(args, text)
+	}
+	return "syllabi.search_vector @@ (" + strings.Join(queries, " || ") + ")", args, nil
+}
+
+const institutionsOf = "SELECT 1 FROM inst_syllabi JOIN institutions ON institutions.id = inst_syllabi.institution_id AND institutions.deleted_at IS NULL WHERE inst_syllabi.syllabus_id = syllabi.id"
+
+// queryFields compile the value of each field to a condition on syllabi
+var queryFields = map[string]func(t token) (string, []interface{}, error){
+	"title": func(t token) (string, []interface{}, error) {
+		return "syllabi.title ILIKE ?", []interface{}{containing(t.Value)}, nil
+	},
+	"description": func(t token) (string, []interface{}, error) {
+		return "syllabi.description ILIKE ?", []interface{}{containing(t.Value)}, nil
+	},
+	"instructor": func(t token) (string, []interface{}, error) {
+		return "EXISTS (SELECT 1 FROM unnest(syllabi.instructors) AS instructor WHERE instructor ILIKE ?)", []interface{}{containing(t.Value)}, nil
+	},
+	"tag": func(t token) (string, []interface{}, error) {
+		return "EXISTS (SELECT 1 FROM unnest(syllabi.tags) AS tag WHERE lower(tag) = lower(?))", []interface{}{t.Value}, nil
+	},
+	// -- field:54* also matches the fields 54 is divided into
+	"field": func(t token) (string, []interface{}, error) {
+		code, subfields := strings.CutSuffix(t.Value, "*")
+		f, err := strconv.Atoi(code)
+		if err != nil {
+			return "", nil, &QueryError{Pos: t.ValuePos, Msg: "the field should be an ISCED-F 2013 code"}
+		}
+		if _, found := ACADEMIC_FIELDS[f]; !found {
+			return "", nil, &QueryError{Pos: t.ValuePos, Msg: fmt.Sprintf("%d is not an ISCED-F 2013 code", f)}
+		}
+		if subfields {
+			return "syllabi.academic_fields && ?", []interface{}{fieldCodes(FieldDescendants(f))}, nil
+		}
+		return "? = ANY(syllabi.academic_fields)", []interface{}{f}, nil
+	},
+	"level": func(t token) (string, []interface{}, error) {
+		from, to, err := parseRange(t)
+		if err != nil {
+			return "", nil, err
+		}
+		return "syllabi.academic_level BETWEEN ? AND ?", []interface{}{from, to}, nil
+	},
+	"language": func(t token) (string, []interface{}, error) {
+		base, err := language.ParseBase(strings.ToLower(t.Value))
+		if err != nil {
+			return "", nil, &QueryError{Pos: t.ValuePos, Msg: fmt.Sprintf("%s is not a BCP 47 language", t.Value)}
+		}
+		return "syllabi.language = ?", []interface{}{base.String()}, nil
+	},
+	"year": func(t token) (string, []interface{}, error) {
+		from, to, err := parseRange(t)
+		if err != nil {
+			return "", nil, err
+		}
+		return "EXISTS (" + institutionsOf + " AND institutions.date_year BETWEEN ? AND ?)", []interface{}{from, to}, nil
+	},
+	"institution": func(t token) (string, []interface{}, error) {
+		return "EXISTS (" + institutionsOf + " AND institutions.name ILIKE ?)", []interface{}{containing(t.Value)}, nil
+	},
+	"country": func(t token) (string, []interface{}, error) {
+		country, err := ParseCountry(t.Value)
+		if err != nil {
+			return "", nil, &QueryError{Pos: t.ValuePos, Msg: err.Error()}
+		}
+		return "EXISTS (" + institutionsOf + " AND institutions.country = ?)", []interface{}{country}, nil
+	},
+	"term": func(t token) (string, []interface{}, error) {
+		return "EXISTS (" + institutionsOf + " AND lower(institutions.date_term) = lower(?))", []interface{}{t.Value}, nil
+	},
+	"license": func(t token) (string, []interface{}, error) {
+		l, found := LookupLicense(t.Value)
+		if !found {
+			return "", nil, &QueryError{Pos: t.ValuePos, Msg: fmt.Sprintf("%s is not a supported license", t.Value)}
+		}
+		return "syllabi.license = ?", []interface{}{l.ID}, nil
+	},
+}
+
+// containing is a LIKE pattern matching any text which contains s
+func containing(s string) string {
+	return "%" + escapeLike(s) + "%"
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, so that the value is matched as is
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+var (
+	ErrInvalidRange  = errors.New("should be a number or a range such as 2019..2022")
+	ErrReversedRange = errors.New("is a range which starts after it ends")
+)
+
+// ParseRange parses a number, or a range of numbers whose bounds are both optional, such as 2019..2022, 2019.. or ..2022
+func ParseRange(s string) (int, int, error) {
+	bounds := strings.SplitN(strings.TrimSpace(s), "..", 2)
+	if len(bounds) == 1 {
+		n, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return 0, 0, ErrInvalidRange
+		}
+		return n, n, nil
+	}
+
+	if bounds[0] == "" && bounds[1] == "" {
+		return 0, 0, ErrInvalidRange
+	}
+
+	from, to := math.MinInt32, math.MaxInt32
+	var err error
+	if bounds[0] != "" {
+		if from, err = strconv.Atoi(bounds[0]); err != nil {
+			return 0, 0, ErrInvalidRange
+		}
+	}
+	if bounds[1] != "" {
+		if to, err = strconv.Atoi(bounds[1]); err != nil {
+			return 0, 0, ErrInvalidRange
+		}
+	}
+	if from > to {
+		return 0, 0, ErrReversedRange
+	}
+
+	return from, to, nil
+}
+
+func parseRange(t token) (int, int, error) {
+	if t.Phrase {
+		return 0, 0, &QueryError{Pos: t.ValuePos, Msg: fmt.Sprintf("the %s %s", t.Text, ErrInvalidRange)}
+	}
+
+	from, to, err := ParseRange(t.Value)
+	if err != nil {
+		return 0, 0, &QueryError{Pos: t.ValuePos, Msg: fmt.Sprintf("the %s %s", t.Text, err)}
+	}
+	return from, to, nil
+}
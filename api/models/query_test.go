@@ -0,0 +1,122 @@
+package models_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	t.Run("Test parse blank query", func(t *testing.T) {
+		q, err := models.ParseQuery("   ")
+		require.Nil(t, err)
+		assert.Nil(t, q)
+	})
+
+	t.Run("Test parse fielded query", func(t *testing.T) {
+		q, err := models.ParseQuery(`instructor:"Smith" field:541 year:2019..2022 -tag:intro`)
+		require.Nil(t, err)
+		assert.Equal(t, "(((EXISTS (SELECT 1 FROM unnest(syllabi.instructors) AS instructor WHERE instructor ILIKE ?) AND ? = ANY(syllabi.academic_fields)) AND EXISTS (SELECT 1 FROM inst_syllabi JOIN institutions ON institutions.id = inst_syllabi.institution_id AND institutions.deleted_at IS NULL WHERE inst_syllabi.syllabus_id = syllabi.id AND institutions.date_year BETWEEN ? AND ?)) AND NOT EXISTS (SELECT 1 FROM unnest(syllabi.tags) AS tag WHERE lower(tag) = lower(?)))", q.SQL)
+		assert.Equal(t, []interface{}{"%Smith%", 541, 2019, 2022, "intro"}, q.Args)
+	})
+
+	t.Run("Test parse boolean operators", func(t *testing.T) {
+		q, err := models.ParseQuery(`(language:de OR language:FR) AND NOT level:2`)
+		require.Nil(t, err)
+		assert.Equal(t, "((syllabi.language = ? OR syllabi.language = ?) AND NOT syllabi.academic_level BETWEEN ? AND ?)", q.SQL)
+		assert.Equal(t, []interface{}{"de", "fr", 2, 2}, q.Args)
+
+		q, err = models.ParseQuery(`title:code OR title:media tag:art`)
+		require.Nil(t, err)
+		assert.Equal(t, "(syllabi.title ILIKE ? OR (syllabi.title ILIKE ? AND EXISTS (SELECT 1 FROM unnest(syllabi.tags) AS tag WHERE lower(tag) = lower(?))))", q.SQL)
+	})
+
+	t.Run("Test parse institution fields", func(t *testing.T) {
+		q, err := models.ParseQuery(`country:fr term:Fall`)
+		require.Nil(t, err)
+		assert.Contains(t, q.SQL, "institutions.country = ?")
+		assert.Contains(t, q.SQL, "lower(institutions.date_term) = lower(?)")
+		assert.Equal(t, []interface{}{250, "Fall"}, q.Args)
+
+		_, err = models.ParseQuery(`country:atlantis`)
+		assert.True(t, errors.Is(err, models.ErrInvalidQuery))
+	})
+
+	t.Run("Test parse open ranges", func(t *testing.T) {
+		q, err := models.ParseQuery(`year:2019..`)
+		require.Nil(t, err)
+		assert.Equal(t, 2019, q.Args[0])
+
+		q, err = models.ParseQuery(`level:..1`)
+		require.Nil(t, err)
+		assert.Equal(t, 1, q.Args[1])
+	})
+
+	t.Run("Test parse full text terms", func(t *testing.T) {
+		q, err := models.ParseQuery(`"machine learning" -ethics`)
+		require.Nil(t, err)
+		assert.True(t, strings.HasPrefix(q.SQL, "(syllabi.search_vector @@ (websearch_to_tsquery("))
+		assert.Contains(t, q.SQL, "AND NOT syllabi.search_vector @@")
+		assert.Contains(t, q.Args, `"machine learning"`)
+		assert.Contains(t, q.Args, "ethics")
+	})
+
+	t.Run("Test parse query keeps values out of the SQL", func(t *testing.T) {
+		q, err := models.ParseQuery(`title:"'; DROP TABLE syllabi; --" institution:100%_`)
+		require.Nil(t, err)
+		assert.NotContains(t, q.SQL, "DROP")
+		assert.Equal(t, []interface{}{"%'; DROP TABLE syllabi; --%", `%100\%\_%`}, q.Args)
+	})
+
+	t.Run("Test parse malformed queries", func(t *testing.T) {
+		cases := []struct {
+			query string
+			pos   int
+		}{
+			{`title:"open`, 7},
+			{`(tag:art OR tag:code`, 1},
+			{`tag:art)`, 8},
+			{`tag:art OR`, 11},
+			{`AND tag:art`, 1},
+			{`tag:art - tag:code`, 9},
+			{`author:smith`, 1},
+			{`year:2022..2019`, 6},
+			{`year:soon`, 6},
+			{`field:999`, 7},
+			{`language:klingon`, 10},
+			{`tag: art`, 1},
+			{`:art`, 1},
+			{`""`, 1},
+			{`()`, 2},
+			{strings.Repeat("(", models.MAX_QUERY_DEPTH+1) + "art" + strings.Repeat(")", models.MAX_QUERY_DEPTH+1), models.MAX_QUERY_DEPTH + 1},
+			{strings.Repeat("a", models.MAX_QUERY_LENGTH+1), models.MAX_QUERY_LENGTH + 1},
+		}
+
+		for _, c := range cases {
+			_, err := models.ParseQuery(c.query)
+			require.NotNil(t, err, c.query)
+			assert.True(t, errors.Is(err, models.ErrInvalidQuery), c.query)
+
+			var qerr *models.QueryError
+			require.True(t, errors.As(err, &qerr), c.query)
+			assert.Equal(t, c.pos, qerr.Pos, "%s: %s", c.query, qerr.Msg)
+		}
+	})
+}
+
+func TestParseCountry(t *testing.T) {
+	for _, code := range []string{"FR", "fra", "250"} {
+		country, err := models.ParseCountry(code)
+		require.Nil(t, err, code)
+		assert.Equal(t, 250, country)
+	}
+
+	_, err := models.ParseCountry("France")
+	assert.NotNil(t, err)
+	_, err = models.ParseCountry("1234")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// RateLimit counts the hits on a key, such as an IP address or an account, within a window
+type RateLimit struct {
+	Key     string    `gorm:"primaryKey" json:"key"`
+	Count   int       `gorm:"not null;default:0" json:"count"`
+	LastAt  time.Time `json:"last_at"`
+	ResetAt time.Time `gorm:"not null;index" json:"reset_at"`
+}
+
+// HitRateLimit counts a hit on the key, and starts a new window if the previous one is over.
+// The count is updated in a single statement, so that concurrent hits from several instances are all counted.
+func HitRateLimit(key string, window time.Duration) (RateLimit, error) {
+	var limit RateLimit
+	now := time.Now()
+
+	result := db.Raw(`INSERT INTO rate_limits (key, count, last_at, reset_at) VALUES (?, 1, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN rate_limits.reset_at <= ? THEN 1 ELSE rate_limits.count + 1 END,
+			reset_at = CASE WHEN rate_limits.reset_at <= ? THEN EXCLUDED.reset_at ELSE rate_limits.reset_at END,
+			last_at = EXCLUDED.last_at
+		RETURNING *`, key, now, now.Add(window), now, now).Scan(&limit)
+
+	return limit, result.Error
+}
+
+// GetRateLimit returns the count of the key, which is empty once its window is over
+func GetRateLimit(key string) (RateLimit, error) {
+	var limit RateLimit
+	result := db.Where("key = ? AND reset_at > ?", key, time.Now()).Limit(1).Find(&limit)
+	return limit, result.Error
+}
+
+// ResetRateLimit forgets all hits on the key
+func ResetRateLimit(key string) error {
+	return db.Where("key = ?", key).Delete(&RateLimit{}).Error
+}
+
+// PurgeExpiredRateLimits deletes all counts whose window is over
+func PurgeExpiredRateLimits() (int64, error) {
+	result := db.Where("reset_at < ?", time.Now()).Delete(&RateLimit{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,245 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DEFAULT_READINGS_LIMIT = 20
+	MAX_READINGS_LIMIT     = 100
+)
+
+// Reading is a book, article or any other text assigned in syllabi. Readings are parsed from the readings of each syllabus,
+// and the same reading assigned in several syllabi is only stored once, under its Key.
+type Reading struct {
+	ID        uint           `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	UUID      uuid.UUID      `gorm:"uniqueIndex;type:uuid;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
+	Title     string         `json:"title"`
+	Authors   pq.StringArray `gorm:"type:text[]" json:"authors"`
+	Year      int            `json:"year"`
+	ISBN      string         `gorm:"index" json:"isbn"`
+	DOI       string         `gorm:"index" json:"doi"`
+	Key       string         `gorm:"uniqueIndex;not null" json:"key"`
+}
+
+// SyllabusReading ties a reading to a syllabus which assigns it, Position being its place among the readings of the syllabus
+type SyllabusReading struct {
+	SyllabusID uint `gorm:"primaryKey;autoIncrement:false"`
+	ReadingID  uint `gorm:"primaryKey;autoIncrement:false;index"`
+	Position   int  `gorm:"not null;default:0"`
+}
+
+// ReadingCount is a reading along with the number of listed syllabi which assign it
+type ReadingCount struct {
+	Reading
+	Count int64 `json:"count"`
+}
+
+// indexReadings parses the readings of a syllabus, and ties it to them instead of the ones it was tied to so far, untying
+// its sessions from the readings it doesn't assign anymore. Readings which can't be parsed are left out of the index, but
+// stay on the syllabus. Citations are indexed with their own fields instead of the reading they share a key with, if any,
+// and after the readings otherwise.
+func indexReadings(tx *gorm.DB, syll_id uint, readings []string, citations []Reading) error {
+	structured := make(map[string]Reading, len(citations))
+	keys := make([]string, 0, len(citations))
+	for _, c := range citations {
+		c, err := normalizeCitation(c)
+		if err != nil {
+			continue
+		}
+		if _, found := structured[c.Key]; !found {
+			structured[c.Key] = c
+			keys = append(keys, c.Key)
+		}
+	}
+
+	parsed := make([]Reading, 0, len(readings)+len(keys))
+	positions := make([]int, 0, len(readings)+len(keys))
+	matched := make(map[string]bool)
+	for i, text := range readings {
+		r, err := ParseReading(text)
+		if err != nil {
+			continue
+		}
+
+		if c, found := structured[r.Key]; found {
+			r = c
+			matched[r.Key] = true
+		}
+		parsed = append(parsed, r)
+		positions = append(positions, i)
+	}
+
+	next := len(readings)
+	for _, k := range keys {
+		if !matched[k] {
+			parsed = append(parsed, structured[k])
+			positions = append(positions, next)
+			next++
+		}
+	}
+
+	return tx.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("syllabus_id = ?", syll_id).Delete(&SyllabusReading{}).Error
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[uint]bool)
+		for i, p := range parsed {
+			reading, err := upsertReading(tx, p)
+			if err != nil {
+				return err
+			}
+
+			if seen[reading.ID] {
+				continue
+			}
+			seen[reading.ID] = true
+
+			err = tx.Create(&SyllabusReading{SyllabusID: syll_id, ReadingID: reading.ID, Position: positions[i]}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return pruneSessionReadings(tx, syll_id)
+	})
+}
+
+// upsertReading returns the reading stored under the same key, completed with what the parsed one knows and it didn't,
+// or stores the parsed one if there is none
+func upsertReading(tx *gorm.DB, parsed Reading) (Reading, error) {
+	parsed.UUID = uuid.New()
+	err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, DoNothing: true}).Create(&parsed).Error
+	if err != nil {
+		return parsed, err
+	}
+
+	var existing Reading
+	err = tx.Where("key = ?", parsed.Key).First(&existing).Error
+	if err != nil {
+		return existing, err
+	}
+
+	updates := map[string]interface{}{}
+	if existing.Year == 0 && parsed.Year != 0 {
+		updates["year"] = parsed.Year
+	}
+	if len(existing.Authors) == 0 && len(parsed.Authors) > 0 {
+		updates["authors"] = parsed.Authors
+	}
+	if existing.Title == "" && parsed.Title != "" {
+		updates["title"] = parsed.Title
+	}
+	if existing.ISBN == "" && parsed.ISBN != "" {
+		updates["isbn"] = parsed.ISBN
+	}
+
+	if len(updates) > 0 {
+		err = tx.Model(&existing).Updates(updates).Error
+	}
+	return existing, err
+}
+
+// indexMissingReadings indexes the readings of the syllabi which have some, but aren't tied to any yet,
+// such as the ones created before readings were indexed
+func indexMissingReadings(db *gorm.DB) error {
+	var sylls []Syllabus
+	err := db.Select("id", "readings").
+		Where("cardinality(readings) > 0 AND NOT EXISTS (SELECT 1 FROM syllabus_readings WHERE syllabus_readings.syllabus_id = syllabi.id)").
+		Find(&sylls).Error
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sylls {
+		if err := indexReadings(db, s.ID, s.Readings, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTopReadings lists the readings assigned by the most listed syllabi, in any of the given fields if there are any
+func GetTopReadings(fields []int, limit int) ([]ReadingCount, error) {
+	readings := make([]ReadingCount, 0)
+
+	query := db.Model(&Reading{}).
+		Select("readings.*, count(DISTINCT syllabi.id) AS count").
+		Joins("JOIN syllabus_readings ON syllabus_readings.reading_id = readings.id").
+		Joins("JOIN syllabi ON syllabi.id = syllabus_readings.syllabus_id AND syllabi.deleted_at IS NULL AND syllabi.status = 'listed'")
+
+	if len(fields) > 0 {
+		query = query.Where("syllabi.academic_fields && ?", fieldCodes(fields))
+	}
+
+	err := query.Group("readings.id").
+		Order("count DESC, readings.title ASC, readings.id ASC").
+		Limit(limit).
+		Scan(&readings).Error
+	return readings, err
+}
+
+// GetReading returns a reading along with the number of listed syllabi which assign it
+func GetReading(reading_uuid uuid.UUID) (ReadingCount, error) {
+	var reading ReadingCount
+
+	result := db.Where("uuid = ?", reading_uuid).First(&reading.Reading)
+	if result.Error != nil {
+		return reading, result.Error
+	}
+
+	result = db.Model(&SyllabusReading{}).
+		Joins("JOIN syllabi ON syllabi.id = syllabus_readings.syllabus_id AND syllabi.deleted_at IS NULL AND syllabi.status = 'listed'").
+		Where("syllabus_readings.reading_id = ?", reading.ID).
+		Count(&reading.Count)
+	return reading, result.Error
+}
+
+// GetReadingSyllabi lists the syllabi visible to the user which assign the reading
+func GetReadingSyllabi(reading_uuid uuid.UUID, page Page, user_uuid uuid.UUID) ([]Syllabus, PageMeta, error) {
+	syllabi := make([]Syllabus, 0)
+
+	var reading Reading
+	result := db.Select("id").Where("uuid = ?", reading_uuid).First(&reading)
+	if result.Error != nil {
+		return syllabi, PageMeta{}, result.Error
+	}
+
+	if page.Sort == "" {
+		page.Sort = SortCreated
+	} else if page.Sort == SortRelevance {
+		return syllabi, PageMeta{}, fmt.Errorf("%w: sorting by relevance requires keywords", ErrInvalidPage)
+	}
+
+	query := filterSyllabi(db.Model(&Syllabus{}), map[string]any{}, user_uuid).
+		Where("EXISTS (SELECT 1 FROM syllabus_readings WHERE syllabus_readings.syllabus_id = syllabi.id AND syllabus_readings.reading_id = ?)", reading.ID)
+
+	query, meta, err := paginate(query, "syllabi", page, SYLLABUS_SORTS, nil)
+	if err != nil {
+		return syllabi, meta, err
+	}
+
+	result = query.Preload("User").Preload("Institutions").Find(&syllabi)
+	if result.Error != nil {
+		return syllabi, meta, result.Error
+	}
+
+	fetched := len(syllabi)
+	if page.Size > 0 && fetched > page.Size {
+		syllabi = syllabi[:page.Size]
+		last := syllabi[len(syllabi)-1]
+		meta.nextCursor(fetched, sortValue(page.Sort, last.CreatedAt, last.UpdatedAt, last.Title, last.Rank), last.ID)
+	}
+
+	return syllabi, meta, nil
+}
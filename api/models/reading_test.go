@@ -0,0 +1,104 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadingModel(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	var top []models.ReadingCount
+	t.Run("Test get top readings", func(t *testing.T) {
+		var err error
+		top, err = models.GetTopReadings(nil, models.DEFAULT_READINGS_LIMIT)
+		require.Nil(t, err)
+		require.Equal(t, 3, len(top))
+
+		// -- the fixtures cite each of the first two readings in two different shapes
+		assert.Equal(t, "Code and Other Laws of Cyberspace", top[0].Title)
+		assert.Equal(t, "9780465039135", top[0].ISBN)
+		assert.Equal(t, int64(2), top[0].Count)
+		assert.Equal(t, "The Death and Life of Great American Cities", top[1].Title)
+		assert.Equal(t, int64(2), top[1].Count)
+		assert.Equal(t, 1961, top[1].Year)
+		assert.Equal(t, int64(1), top[2].Count)
+	})
+
+	t.Run("Test get top readings per field", func(t *testing.T) {
+		readings, err := models.GetTopReadings([]int{100}, 1)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(readings))
+		assert.Equal(t, top[0].UUID, readings[0].UUID)
+
+		readings, err = models.GetTopReadings([]int{541}, models.DEFAULT_READINGS_LIMIT)
+		require.Nil(t, err)
+		assert.Equal(t, 0, len(readings))
+	})
+
+	t.Run("Test get reading", func(t *testing.T) {
+		reading, err := models.GetReading(top[1].UUID)
+		require.Nil(t, err)
+		assert.Equal(t, int64(2), reading.Count)
+
+		_, err = models.GetReading(uuid.New())
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test get reading syllabi", func(t *testing.T) {
+		syllabi, meta, err := models.GetReadingSyllabi(top[1].UUID, models.Page{Sort: models.SortTitle}, userID)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(syllabi))
+		assert.Equal(t, int64(2), meta.Total)
+		assert.Equal(t, "Politics of Code", syllabi[0].Title)
+		assert.Equal(t, syllabusTitle, syllabi[1].Title)
+	})
+
+	t.Run("Test update syllabus readings", func(t *testing.T) {
+		_, err := models.UpdateSyllabus(syllabusID, &models.Syllabus{Readings: []string{"Chun, W. H. K. Programmed Visions. doi:10.7551/mitpress/9780262015424.001.0001"}}, userID)
+		require.Nil(t, err)
+
+		readings, err := models.GetTopReadings(nil, 1)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(readings))
+		assert.Equal(t, "10.7551/mitpress/9780262015424.001.0001", readings[0].DOI)
+		assert.Equal(t, int64(2), readings[0].Count)
+
+		reading, err := models.GetReading(top[1].UUID)
+		require.Nil(t, err)
+		assert.Equal(t, int64(1), reading.Count)
+	})
+	t.Run("Test create syllabus with parsed citations", func(t *testing.T) {
+		syll := models.Syllabus{
+			Title:    "Seeing the State",
+			Status:   "listed",
+			Readings: []string{"Seeing Like a State, James C. Scott"},
+			Citations: []models.Reading{
+				{Title: "Seeing Like a State.", Authors: []string{"James C. Scott"}, Year: 1998},
+				{Title: "Weapons of the Weak", Authors: []string{"James C. Scott", "Unknown author"}, Year: 1985},
+				{Title: "Unknown title"},
+			},
+		}
+		_, err := models.CreateSyllabus(&syll, userID)
+		require.Nil(t, err)
+
+		readings, err := models.GetTopReadings(nil, models.MAX_READINGS_LIMIT)
+		require.Nil(t, err)
+
+		found := make(map[string]models.ReadingCount)
+		for _, r := range readings {
+			found[r.Title] = r
+		}
+		require.Contains(t, found, "Seeing Like a State")
+		assert.Equal(t, 1998, found["Seeing Like a State"].Year)
+		assert.Equal(t, int64(1), found["Seeing Like a State"].Count)
+		require.Contains(t, found, "Weapons of the Weak")
+		assert.Equal(t, 1985, found["Weapons of the Weak"].Year)
+		assert.Equal(t, 1, len(found["Weapons of the Weak"].Authors))
+	})
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("the refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("the refresh token has already been used")
+)
+
+// RefreshToken is a long-lived, single-use credential exchanged for a new access token.
+// Only the hash of the token is stored; the token itself is only ever known to the client.
+type RefreshToken struct {
+	ID         uint       `gorm:"primaryKey"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UUID       uuid.UUID  `gorm:"uniqueIndex;type:uuid;primaryKey;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
+	UserUUID   uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_uuid"`
+	Hash       string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	ReplacedBy uuid.UUID  `gorm:"type:uuid" json:"replaced_by"`
+}
+
+// generateSecret returns a random, url-safe string suitable to be handed out as a bearer credential
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashSecret is used to store and look up credentials without keeping them in clear
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateRefreshToken creates a new refresh token for the user, and returns the clear token along with its record
+func CreateRefreshToken(user_uuid uuid.UUID, ttl time.Duration) (string, RefreshToken, error) {
+	return createRefreshToken(db, user_uuid, ttl)
+}
+
+func createRefreshToken(tx *gorm.DB, user_uuid uuid.UUID, ttl time.Duration) (string, RefreshToken, error) {
+	var token RefreshToken
+	secret, err := generateSecret()
+	if err != nil {
+		return secret, token, err
+	}
+
+	token = RefreshToken{
+		UUID:      uuid.New(),
+		UserUUID:  user_uuid,
+		Hash:      hashSecret(secret),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	err = tx.Create(&token).Error
+	return secret, token, err
+}
+
+// RotateRefreshToken revokes the given refresh token and issues a new one for the same user.
+// Presenting a token which was already revoked means it has leaked, so all of the user's sessions are revoked.
+func RotateRefreshToken(secret string, ttl time.Duration) (User, string, error) {
+	var user User
+	var next string
+	var reused uuid.UUID
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing RefreshToken
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("hash = ?", hashSecret(secret)).First(&existing)
+		if result.Error != nil {
+			return ErrRefreshTokenInvalid
+		}
+
+		if existing.RevokedAt != nil {
+			reused = existing.UserUUID
+			return nil
+		}
+
+		if time.Now().After(existing.ExpiresAt) {
+			return ErrRefreshTokenInvalid
+		}
+
+		result = tx.Where("uuid = ? AND status = ?", existing.UserUUID, UserConfirmed).First(&user)
+		if result.Error != nil {
+			return ErrRefreshTokenInvalid
+		}
+
+		s, created, err := createRefreshToken(tx, existing.UserUUID, ttl)
+		if err != nil {
+			return err
+		}
+		next = s
+
+		now := time.Now()
+		return tx.Model(&existing).Updates(RefreshToken{RevokedAt: &now, ReplacedBy: created.UUID}).Error
+	})
+	if err != nil {
+		return user, next, err
+	}
+
+	if reused != uuid.Nil {
+		err = RevokeSessions(reused)
+		if err != nil {
+			return user, "", err
+		}
+		return user, "", ErrRefreshTokenReused
+	}
+
+	return user, next, nil
+}
+
+// RevokeRefreshToken revokes a single refresh token, e.g. when logging out of one device
+func RevokeRefreshToken(secret string) error {
+	result := db.Model(&RefreshToken{}).Where("hash = ? AND revoked_at IS NULL", hashSecret(secret)).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrRefreshTokenInvalid
+	}
+	return nil
+}
+
+// RevokeSessions revokes all refresh tokens of a user, and invalidates all access tokens issued until now
+func RevokeSessions(user_uuid uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return revokeSessions(tx, user_uuid)
+	})
+}
+
+func revokeSessions(tx *gorm.DB, user_uuid uuid.UUID) error {
+	err := tx.Model(&User{}).Where("uuid = ?", user_uuid).Update("session_version", gorm.Expr("session_version + 1")).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&RefreshToken{}).Where("user_uuid = ? AND revoked_at IS NULL", user_uuid).Update("revoked_at", time.Now()).Error
+}
+
+// GetSessionVersion returns the current session version of an active user. Access tokens carrying an older version have been revoked.
+func GetSessionVersion(user_uuid uuid.UUID) (int, error) {
+	var user User
+	result := db.Select("uuid", "session_version").Where("uuid = ?", user_uuid).First(&user)
+	return user.SessionVersion, result.Error
+}
+
+// PurgeExpiredRefreshTokens deletes all refresh tokens past their expiry date
+func PurgeExpiredRefreshTokens() (int64, error) {
+	result := db.Where("expires_at < ?", time.Now()).Delete(&RefreshToken{})
+	return result.RowsAffected, result.Error
+}
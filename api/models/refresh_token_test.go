@@ -0,0 +1,96 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshTokenModel(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	var first string
+	t.Run("Test create refresh token", func(t *testing.T) {
+		secret, token, err := models.CreateRefreshToken(userID, time.Hour)
+		require.Nil(t, err)
+		assert.NotEmpty(t, secret)
+		assert.NotEqual(t, secret, token.Hash)
+		assert.Equal(t, userID, token.UserUUID)
+		first = secret
+	})
+
+	var second string
+	t.Run("Test rotate refresh token", func(t *testing.T) {
+		user, next, err := models.RotateRefreshToken(first, time.Hour)
+		require.Nil(t, err)
+		assert.Equal(t, userID, user.UUID)
+		assert.NotEqual(t, first, next)
+		second = next
+	})
+
+	t.Run("Test rotate unknown refresh token", func(t *testing.T) {
+		_, _, err := models.RotateRefreshToken("not-a-token", time.Hour)
+		assert.ErrorIs(t, err, models.ErrRefreshTokenInvalid)
+	})
+
+	t.Run("Test rotate expired refresh token", func(t *testing.T) {
+		secret, _, err := models.CreateRefreshToken(userID, -time.Minute)
+		require.Nil(t, err)
+
+		_, _, err = models.RotateRefreshToken(secret, time.Hour)
+		assert.ErrorIs(t, err, models.ErrRefreshTokenInvalid)
+	})
+
+	t.Run("Test reuse rotated refresh token revokes all sessions", func(t *testing.T) {
+		before, err := models.GetSessionVersion(userID)
+		require.Nil(t, err)
+
+		_, _, err = models.RotateRefreshToken(first, time.Hour)
+		assert.ErrorIs(t, err, models.ErrRefreshTokenReused)
+
+		after, err := models.GetSessionVersion(userID)
+		require.Nil(t, err)
+		assert.Greater(t, after, before)
+
+		_, _, err = models.RotateRefreshToken(second, time.Hour)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test revoke refresh token", func(t *testing.T) {
+		secret, _, err := models.CreateRefreshToken(userID, time.Hour)
+		require.Nil(t, err)
+
+		err = models.RevokeRefreshToken(secret)
+		require.Nil(t, err)
+
+		err = models.RevokeRefreshToken(secret)
+		assert.ErrorIs(t, err, models.ErrRefreshTokenInvalid)
+	})
+
+	t.Run("Test revoke sessions", func(t *testing.T) {
+		secret, _, err := models.CreateRefreshToken(userID, time.Hour)
+		require.Nil(t, err)
+
+		before, err := models.GetSessionVersion(userID)
+		require.Nil(t, err)
+
+		err = models.RevokeSessions(userID)
+		require.Nil(t, err)
+
+		after, err := models.GetSessionVersion(userID)
+		require.Nil(t, err)
+		assert.Equal(t, before+1, after)
+
+		_, _, err = models.RotateRefreshToken(secret, time.Hour)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test session version of unknown user", func(t *testing.T) {
+		_, err := models.GetSessionVersion(userUnknownID)
+		assert.NotNil(t, err)
+	})
+}
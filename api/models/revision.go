@@ -0,0 +1,374 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidRevision = errors.New("the revision is invalid")
+
+// SyllabusSnapshot holds the fields of a syllabus as they were at a revision. Its status is left out, since listing or unlisting
+// a syllabus isn't a change to the course itself, and restoring an earlier revision shouldn't list it again.
+type SyllabusSnapshot struct {
+	Title            string         `json:"title"`
+	Description      string         `json:"description"`
+	AcademicFields   pq.Int32Array  `json:"academic_fields"`
+	AcademicField    string         `json:"academic_field"`
+	AcademicLevel    int            `json:"academic_level"`
+	Assignments      pq.StringArray `json:"assignments"`
+	Duration         int            `json:"duration"`
+	GradingRubric    string         `json:"grading_rubric"`
+	Language         string         `json:"language"`
+	LearningOutcomes pq.StringArray `json:"learning_outcomes"`
+	License          string         `json:"license"`
+	Other            string         `json:"other"`
+	Readings         pq.StringArray `json:"readings"`
+	Tags             pq.StringArray `json:"tags"`
+	Instructors      pq.StringArray `json:"instructors"`
+	TopicOutlines    pq.StringArray `json:"topic_outlines"`
+}
+
+// SyllabusRevision is the state of a syllabus after one of its updates. Revisions are numbered from 1 for each syllabus, and
+// never change once stored: restoring one stores its snapshot again as a new revision.
+type SyllabusRevision struct {
+	ID           uint              `gorm:"primaryKey" json:"-"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UUID         uuid.UUID         `gorm:"uniqueIndex;type:uuid;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
+	SyllabusID   uint              `gorm:"uniqueIndex:idx_syllabus_revision;not null" json:"-"`
+	Number       int               `gorm:"uniqueIndex:idx_syllabus_revision;not null" json:"number"`
+	AuthorUUID   uuid.UUID         `gorm:"type:uuid;index" json:"author_uuid"`
+	Author       User              `gorm:"foreignKey:AuthorUUID;references:UUID" json:"author"`
+	RestoredFrom int               `gorm:"not null;default:0" json:"restored_from,omitempty"`
+	Snapshot     *SyllabusSnapshot `gorm:"type:jsonb;not null" json:"snapshot,omitempty"`
+}
+
+// FieldChange is a field of a syllabus which differs between two revisions. The items added to and removed from list fields,
+// such as tags or readings, are also given apart.
+type FieldChange struct {
+	Field   string        `json:"field"`
+	From    interface{}   `json:"from"`
+	To      interface{}   `json:"to"`
+	Added   []interface{} `json:"added,omitempty"`
+	Removed []interface{} `json:"removed,omitempty"`
+}
+
+// RevisionDiff lists the fields which changed from a revision to another
+type RevisionDiff struct {
+	From    int           `json:"from"`
+	To      int           `json:"to"`
+	Changes []FieldChange `json:"changes"`
+}
+
+func (s SyllabusSnapshot) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+func (s *SyllabusSnapshot) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("cannot scan %T into a syllabus snapshot", value)
+	}
+}
+
+// initRevisions keeps stored revisions from being rewritten, their author aside
+func initRevisions(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE OR REPLACE FUNCTION syllabus_revisions_immutable() RETURNS trigger AS $$
+		BEGIN
+			IF NEW.snapshot IS DISTINCT FROM OLD.snapshot OR NEW.number <> OLD.number OR NEW.syllabus_id <> OLD.syllabus_id THEN
+				RAISE EXCEPTION 'syllabus revisions cannot be changed';
+			END IF;
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS syllabus_revisions_immutable ON syllabus_revisions;
+		CREATE TRIGGER syllabus_revisions_immutable BEFORE UPDATE ON syllabus_revisions
+			FOR EACH ROW EXECUTE FUNCTION syllabus_revisions_immutable();
+	`).Error
+}
+
+// Snapshot returns the fields of the syllabus kept in its revisions
+func (s *Syllabus) Snapshot() SyllabusSnapshot {
+	return SyllabusSnapshot{
+		Title:            s.Title,
+		Description:      s.Description,
+		AcademicFields:   s.AcademicFields,
+		AcademicField:    s.AcademicField,
+		AcademicLevel:    s.AcademicLevel,
+		Assignments:      s.Assignments,
+		Duration:         s.Duration,
+		GradingRubric:    s.GradingRubric,
+		Language:         s.Language,
+		LearningOutcomes: s.LearningOutcomes,
+		License:          s.License,
+		Other:            s.Other,
+		Readings:         s.Readings,
+		Tags:             s.Tags,
+		Instructors:      s.Instructors,
+		TopicOutlines:    s.TopicOutlines,
+	}
+}
+
+// columns lists every field of the snapshot, empty ones included, so that restoring it also clears what was added since
+func (s SyllabusSnapshot) columns() map[string]interface{} {
+	return map[string]interface{}{
+		"title":             s.Title,
+		"description":       s.Description,
+		"academic_fields":   s.AcademicFields,
+		"academic_field":    s.AcademicField,
+		"academic_level":    s.AcademicLevel,
+		"assignments":       s.Assignments,
+		"duration":          s.Duration,
+		"grading_rubric":    s.GradingRubric,
+		"language":          s.Language,
+		"learning_outcomes": s.LearningOutcomes,
+		"license":           s.License,
+		"other":             s.Other,
+		"readings":          s.Readings,
+		"tags":              s.Tags,
+		"instructors":       s.Instructors,
+		"topic_outlines":    s.TopicOutlines,
+	}
+}
+
+// recordRevision stores the current state of a syllabus as its next revision, unless it is the same as its latest one
+func recordRevision(tx *gorm.DB, syll_id uint, author_uuid uuid.UUID, restored_from int) error {
+	var syll Syllabus
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", syll_id).First(&syll).Error
+	if err != nil {
+		return err
+	}
+
+	var latest SyllabusRevision
+	result := tx.Where("syllabus_id = ?", syll_id).Order("number DESC").Limit(1).Find(&latest)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	snapshot := syll.Snapshot()
+	if result.RowsAffected > 0 && len(DiffSnapshots(*latest.Snapshot, snapshot)) == 0 {
+		return nil
+	}
+
+	revision := SyllabusRevision{
+		UUID:         uuid.New(),
+		SyllabusID:   syll_id,
+		Number:       latest.Number + 1,
+		AuthorUUID:   author_uuid,
+		RestoredFrom: restored_from,
+		Snapshot:     &snapshot,
+	}
+	return tx.Omit("Author").Create(&revision).Error
+}
+
+// recordMissingRevisions stores a first revision for the syllabi which have none, such as the ones created before revisions
+// were kept, as authored by their owner
+func recordMissingRevisions(db *gorm.DB) error {
+	var sylls []Syllabus
+	err := db.Unscoped().
+		Where("NOT EXISTS (SELECT 1 FROM syllabus_revisions WHERE syllabus_revisions.syllabus_id = syllabi.id)").
+		Find(&sylls).Error
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sylls {
+		snapshot := s.Snapshot()
+		revision := SyllabusRevision{
+			CreatedAt:  s.UpdatedAt,
+			UUID:       uuid.New(),
+			SyllabusID: s.ID,
+			Number:     1,
+			AuthorUUID: s.UserUUID,
+			Snapshot:   &snapshot,
+		}
+		if err := db.Omit("Author").Create(&revision).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visibleSyllabusID returns the ID of the syllabus, if the user can see it
+func visibleSyllabusID(syll_uuid uuid.UUID, user_uuid uuid.UUID) (uint, error) {
+	var syll Syllabus
+	result := db.Select("id").Where("uuid = ? AND (status = 'listed' OR user_uuid = ? OR uuid IN (SELECT syllabus_uuid FROM syllabi_collaborators WHERE collaborator_uuid = ?))", syll_uuid, user_uuid, user_uuid).First(&syll)
+	return syll.ID, result.Error
+}
+
+// GetSyllabusRevisions lists the revisions of a syllabus visible to the user, latest first and without their snapshots
+func GetSyllabusRevisions(syll_uuid uuid.UUID, user_uuid uuid.UUID) ([]SyllabusRevision, error) {
+	revisions := make([]SyllabusRevision, 0)
+
+	syll_id, err := visibleSyllabusID(syll_uuid, user_uuid)
+	if err != nil {
+		return revisions, err
+	}
+
+	err = db.Omit("snapshot").Preload("Author").Where("syllabus_id = ?", syll_id).Order("number DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+// GetSyllabusRevision returns a revision of a syllabus visible to the user, by its number
+func GetSyllabusRevision(syll_uuid uuid.UUID, number int, user_uuid uuid.UUID) (SyllabusRevision, error) {
+	var revision SyllabusRevision
+
+	syll_id, err := visibleSyllabusID(syll_uuid, user_uuid)
+	if err != nil {
+		return revision, err
+	}
+
+	err = db.Preload("Author").Where("syllabus_id = ? AND number = ?", syll_id, number).First(&revision).Error
+	return revision, err
+}
+
+// DiffSyllabusRevisions compares two revisions of a syllabus visible to the user. to is the latest revision when it is 0.
+func DiffSyllabusRevisions(syll_uuid uuid.UUID, from int, to int, user_uuid uuid.UUID) (RevisionDiff, error) {
+	diff := RevisionDiff{From: from, To: to, Changes: make([]FieldChange, 0)}
+
+	syll_id, err := visibleSyllabusID(syll_uuid, user_uuid)
+	if err != nil {
+		return diff, err
+	}
+
+	if to == 0 {
+		var latest SyllabusRevision
+		err = db.Select("number").Where("syllabus_id = ?", syll_id).Order("number DESC").First(&latest).Error
+		if err != nil {
+			return diff, err
+		}
+		diff.To = latest.Number
+	}
+
+	var revisions []SyllabusRevision
+	err = db.Where("syllabus_id = ? AND number IN ?", syll_id, []int{diff.From, diff.To}).Find(&revisions).Error
+	if err != nil {
+		return diff, err
+	}
+
+	snapshots := make(map[int]SyllabusSnapshot)
+	for _, r := range revisions {
+		snapshots[r.Number] = *r.Snapshot
+	}
+	for _, n := range []int{diff.From, diff.To} {
+		if _, found := snapshots[n]; !found {
+			return diff, fmt.Errorf("%w: the syllabus has no revision %d", ErrInvalidRevision, n)
+		}
+	}
+
+	diff.Changes = DiffSnapshots(snapshots[diff.From], snapshots[diff.To])
+	return diff, nil
+}
+
+// RestoreSyllabusRevision sets the fields of a syllabus back to the ones of an earlier revision, which is stored as its next revision.
+// A revision whose license is no longer valid for the syllabus can't be restored.
+func RestoreSyllabusRevision(syll_uuid uuid.UUID, number int, author_uuid uuid.UUID) (Syllabus, error) {
+	var syll Syllabus
+	err := db.Where("uuid = ?", syll_uuid).First(&syll).Error
+	if err != nil {
+		return syll, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var revision SyllabusRevision
+		err := tx.Where("syllabus_id = ? AND number = ?", syll.ID, number).First(&revision).Error
+		if err != nil {
+			return err
+		}
+
+		// -- the license is checked again, since the original of a fork may have changed its own since the revision
+		columns := revision.Snapshot.columns()
+		columns["license"], err = syll.checkLicense(tx, revision.Snapshot.License)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&syll).Updates(columns).Error
+		if err != nil {
+			return err
+		}
+
+		err = indexReadings(tx, syll.ID, revision.Snapshot.Readings, nil)
+		if err != nil {
+			return err
+		}
+
+		return recordRevision(tx, syll.ID, author_uuid, number)
+	})
+	if err != nil {
+		return syll, err
+	}
+
+	err = db.Where("id = ?", syll.ID).First(&syll).Error
+	return syll, err
+}
+
+// DiffSnapshots lists the fields which differ between two snapshots, in the order of their declaration
+func DiffSnapshots(from SyllabusSnapshot, to SyllabusSnapshot) []FieldChange {
+	changes := make([]FieldChange, 0)
+
+	a, b := reflect.ValueOf(from), reflect.ValueOf(to)
+	for i := 0; i < a.NumField(); i++ {
+		before, after := a.Field(i), b.Field(i)
+
+		change := FieldChange{
+			Field: a.Type().Field(i).Tag.Get("json"),
+			From:  before.Interface(),
+			To:    after.Interface(),
+		}
+
+		if before.Kind() != reflect.Slice {
+			if !reflect.DeepEqual(change.From, change.To) {
+				changes = append(changes, change)
+			}
+			continue
+		}
+
+		// -- lists which only differ by being empty or missing are the same
+		if before.Len() == 0 && after.Len() == 0 {
+			continue
+		}
+		if reflect.DeepEqual(change.From, change.To) {
+			continue
+		}
+
+		change.Added = missingItems(after, before)
+		change.Removed = missingItems(before, after)
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// missingItems lists the items of the slice a which aren't in the slice b
+func missingItems(a reflect.Value, b reflect.Value) []interface{} {
+	present := make(map[interface{}]int)
+	for i := 0; i < b.Len(); i++ {
+		present[b.Index(i).Interface()]++
+	}
+
+	missing := make([]interface{}, 0)
+	for i := 0; i < a.Len(); i++ {
+		item := a.Index(i).Interface()
+		if present[item] > 0 {
+			present[item]--
+			continue
+		}
+		missing = append(missing, item)
+	}
+	return missing
+}
@@ -0,0 +1,115 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	from := models.SyllabusSnapshot{
+		Title:         "Ungewohnt",
+		AcademicLevel: 1,
+		Tags:          []string{"natur", "architektur", "vorkurs"},
+	}
+	to := models.SyllabusSnapshot{
+		Title:         "Ungewohnt (WS 22/23)",
+		AcademicLevel: 1,
+		Tags:          []string{"natur", "vorkurs", "stadt"},
+		Readings:      []string{},
+	}
+
+	changes := models.DiffSnapshots(from, to)
+	require.Equal(t, 2, len(changes))
+
+	assert.Equal(t, "title", changes[0].Field)
+	assert.Equal(t, "Ungewohnt", changes[0].From)
+	assert.Equal(t, "Ungewohnt (WS 22/23)", changes[0].To)
+
+	assert.Equal(t, "tags", changes[1].Field)
+	assert.Equal(t, []interface{}{"stadt"}, changes[1].Added)
+	assert.Equal(t, []interface{}{"architektur"}, changes[1].Removed)
+
+	assert.Empty(t, models.DiffSnapshots(to, to))
+}
+
+func TestRevisionModel(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	t.Run("Test fixtures have a first revision", func(t *testing.T) {
+		revisions, err := models.GetSyllabusRevisions(syllabusID, uuid.Nil)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(revisions))
+		assert.Equal(t, 1, revisions[0].Number)
+		assert.Equal(t, userID, revisions[0].AuthorUUID)
+		assert.Nil(t, revisions[0].Snapshot)
+	})
+
+	t.Run("Test update syllabus records a revision", func(t *testing.T) {
+		_, err := models.UpdateSyllabus(syllabusID, &models.Syllabus{Title: "Ungewohnt (revised)", Tags: []string{"natur", "stadt"}}, userID)
+		require.Nil(t, err)
+
+		// -- an update which changes nothing isn't a revision
+		_, err = models.UpdateSyllabus(syllabusID, &models.Syllabus{Title: "Ungewohnt (revised)"}, userID)
+		require.Nil(t, err)
+
+		revisions, err := models.GetSyllabusRevisions(syllabusID, uuid.Nil)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(revisions))
+		assert.Equal(t, 2, revisions[0].Number)
+
+		revision, err := models.GetSyllabusRevision(syllabusID, 2, uuid.Nil)
+		require.Nil(t, err)
+		assert.Equal(t, "Ungewohnt (revised)", revision.Snapshot.Title)
+		assert.Equal(t, userName, revision.Author.Name)
+	})
+
+	t.Run("Test diff revisions", func(t *testing.T) {
+		diff, err := models.DiffSyllabusRevisions(syllabusID, 1, 0, uuid.Nil)
+		require.Nil(t, err)
+		assert.Equal(t, 2, diff.To)
+		require.Equal(t, 2, len(diff.Changes))
+		assert.Equal(t, "title", diff.Changes[0].Field)
+		assert.Equal(t, "tags", diff.Changes[1].Field)
+
+		_, err = models.DiffSyllabusRevisions(syllabusID, 1, 42, uuid.Nil)
+		assert.True(t, errors.Is(err, models.ErrInvalidRevision))
+	})
+
+	t.Run("Test restore revision", func(t *testing.T) {
+		syll, err := models.RestoreSyllabusRevision(syllabusID, 1, userID)
+		require.Nil(t, err)
+		assert.Equal(t, syllabusTitle, syll.Title)
+		assert.Equal(t, 3, len(syll.Tags))
+
+		revision, err := models.GetSyllabusRevision(syllabusID, 3, uuid.Nil)
+		require.Nil(t, err)
+		assert.Equal(t, 1, revision.RestoredFrom)
+
+		diff, err := models.DiffSyllabusRevisions(syllabusID, 1, 3, uuid.Nil)
+		require.Nil(t, err)
+		assert.Empty(t, diff.Changes)
+	})
+
+	t.Run("Test revisions of unlisted syllabus", func(t *testing.T) {
+		_, err := models.GetSyllabusRevisions(syllabusUnlistedID, uuid.Nil)
+		assert.NotNil(t, err)
+
+		revisions, err := models.GetSyllabusRevisions(syllabusUnlistedID, userID)
+		require.Nil(t, err)
+		assert.Equal(t, 1, len(revisions))
+	})
+
+	t.Run("Test restore unknown revision", func(t *testing.T) {
+		_, err := models.RestoreSyllabusRevision(syllabusID, 42, userID)
+		assert.NotNil(t, err)
+
+		_, err = models.RestoreSyllabusRevision(syllabusUnknownID, 1, userID)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,261 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DigestNone   string = "none"
+	DigestDaily  string = "daily"
+	DigestWeekly string = "weekly"
+)
+
+// DIGEST_FREQUENCIES is how long to wait between two digests of a saved search. Searches set to none never send any.
+var DIGEST_FREQUENCIES = map[string]time.Duration{
+	DigestNone:   0,
+	DigestDaily:  24 * time.Hour,
+	DigestWeekly: 7 * 24 * time.Hour,
+}
+
+const (
+	MAX_SAVED_SEARCHES = 50
+	// -- digests list at most this many new syllabi, and tell how many more there are
+	MAX_DIGEST_SYLLABI = 20
+)
+
+var (
+	ErrInvalidSavedSearch   = errors.New("the saved search is invalid")
+	ErrSavedSearchNotFound  = errors.New("the saved search is unknown")
+	ErrTooManySavedSearches = errors.New("the user has too many saved searches")
+)
+
+// SavedSearch is a search of syllabi that a user keeps, to run it again later or to be emailed the syllabi newly listed
+// which match it. Query holds the parameters of the search as given to GET /syllabi/, url-encoded.
+// The unsubscribe token is kept in clear, since it goes in every digest, and only allows turning the digests off.
+type SavedSearch struct {
+	ID               uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	UUID             uuid.UUID `gorm:"uniqueIndex;type:uuid;primaryKey;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
+	UserUUID         uuid.UUID `gorm:"type:uuid;index;not null" json:"user_uuid"`
+	User             User      `gorm:"foreignKey:UserUUID;references:UUID" json:"-"`
+	Name             string    `gorm:"not null" json:"name"`
+	Query            string    `gorm:"not null;default:''" json:"query"`
+	Frequency        string    `gorm:"not null;default:weekly" json:"frequency"`
+	LastSentAt       time.Time `gorm:"not null" json:"last_sent_at"`
+	UnsubscribeToken string    `gorm:"uniqueIndex;not null" json:"-"`
+}
+
+// initListing records when each syllabus was last listed, so that digests only mention the syllabi listed since the previous one,
+// including those which were created unlisted
+func initListing(db *gorm.DB) error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION syllabi_listed_at() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'INSERT' THEN
+				IF NEW.status = 'listed' THEN
+					NEW.listed_at := now();
+				END IF;
+			ELSIF NEW.status = 'listed' AND OLD.status IS DISTINCT FROM 'listed' THEN
+				NEW.listed_at := now();
+			END IF;
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS syllabi_listed_at ON syllabi`,
+		`CREATE TRIGGER syllabi_listed_at BEFORE INSERT OR UPDATE ON syllabi FOR EACH ROW EXECUTE FUNCTION syllabi_listed_at()`,
+		`CREATE INDEX IF NOT EXISTS idx_syllabi_listed_at ON syllabi (listed_at)`,
+		// -- syllabi listed before the trigger existed are considered listed when they were created
+		`UPDATE syllabi SET listed_at = created_at WHERE status = 'listed' AND listed_at IS NULL`,
+	}
+
+	for _, s := range statements {
+		if err := db.Exec(s).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateSavedSearch saves a search for the user. Its first digest only lists the syllabi listed after it was saved.
+func CreateSavedSearch(user_uuid uuid.UUID, name string, query string, frequency string) (SavedSearch, error) {
+	var search SavedSearch
+
+	if frequency == "" {
+		frequency = DigestWeekly
+	}
+
+	name, err := checkSavedSearch(name, frequency)
+	if err != nil {
+		return search, err
+	}
+
+	var count int64
+	result := db.Model(&SavedSearch{}).Where("user_uuid = ?", user_uuid).Count(&count)
+	if result.Error != nil {
+		return search, result.Error
+	}
+
+	if count >= MAX_SAVED_SEARCHES {
+		return search, fmt.Errorf("%w: at most %d searches can be saved", ErrTooManySavedSearches, MAX_SAVED_SEARCHES)
+	}
+
+	token, err := generateSecret()
+	if err != nil {
+		return search, err
+	}
+
+	search = SavedSearch{
+		UUID:             uuid.New(),
+		UserUUID:         user_uuid,
+		Name:             name,
+		Query:            query,
+		Frequency:        frequency,
+		LastSentAt:       time.Now(),
+		UnsubscribeToken: token,
+	}
+	result = db.Create(&search)
+	return search, result.Error
+}
+
+// GetSavedSearches lists the saved searches of the user, the most recent first
+func GetSavedSearches(user_uuid uuid.UUID) ([]SavedSearch, error) {
+	searches := make([]SavedSearch, 0)
+	result := db.Where("user_uuid = ?", user_uuid).Order("created_at DESC").Find(&searches)
+	return searches, result.Error
+}
+
+func GetSavedSearch(search_uuid uuid.UUID) (SavedSearch, error) {
+	var search SavedSearch
+	result := db.Where("uuid = ?", search_uuid).First(&search)
+	return search, result.Error
+}
+
+// UpdateSavedSearch changes the name, parameters or frequency of a saved search, leaving the empty ones as they are
+func UpdateSavedSearch(search_uuid uuid.UUID, name string, query *string, frequency string) (SavedSearch, error) {
+	search, err := GetSavedSearch(search_uuid)
+	if err != nil {
+		return search, err
+	}
+
+	if name == "" {
+		name = search.Name
+	}
+	if frequency == "" {
+		frequency = search.Frequency
+	}
+
+	name, err = checkSavedSearch(name, frequency)
+	if err != nil {
+		return search, err
+	}
+
+	updates := map[string]interface{}{"name": name, "frequency": frequency}
+	if query != nil {
+		updates["query"] = *query
+	}
+
+	// -- turning the digests back on starts from now, rather than sending all that was missed in the meantime
+	if search.Frequency == DigestNone && frequency != DigestNone {
+		updates["last_sent_at"] = time.Now()
+	}
+
+	result := db.Model(&search).Updates(updates)
+	if result.Error != nil {
+		return search, result.Error
+	}
+
+	return GetSavedSearch(search_uuid)
+}
+
+func DeleteSavedSearch(search_uuid uuid.UUID) error {
+	result := db.Where("uuid = ?", search_uuid).Delete(&SavedSearch{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+// UnsubscribeSavedSearch stops the digests of the saved search with the token, without deleting the search itself
+func UnsubscribeSavedSearch(token string) (SavedSearch, error) {
+	var search SavedSearch
+	if token == "" {
+		return search, ErrSavedSearchNotFound
+	}
+
+	result := db.Where("unsubscribe_token = ?", token).Limit(1).Find(&search)
+	if result.Error != nil {
+		return search, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return search, ErrSavedSearchNotFound
+	}
+
+	result = db.Model(&search).Update("frequency", DigestNone)
+	return search, result.Error
+}
+
+// GetDueSavedSearches lists the saved searches whose digest is due, along with their user. Only confirmed users get digests.
+func GetDueSavedSearches(now time.Time) ([]SavedSearch, error) {
+	searches := make([]SavedSearch, 0)
+
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	for frequency, every := range DIGEST_FREQUENCIES {
+		if every == 0 {
+			continue
+		}
+		conditions = append(conditions, "(saved_searches.frequency = ? AND saved_searches.last_sent_at <= ?)")
+		args = append(args, frequency, now.Add(-every))
+	}
+
+	result := db.Joins("User").
+		Where("("+strings.Join(conditions, " OR ")+")", args...).
+		Where("\"User\".status = ?", UserConfirmed).
+		Order("saved_searches.id ASC").
+		Find(&searches)
+	return searches, result.Error
+}
+
+// ClaimSavedSearch records that the digest of a due saved search is being sent, so that the next one starts from sent_at.
+// Only one of the instances running the digests at the same time claims a search: the others find its last digest changed
+// since they listed it as due, and leave it.
+func ClaimSavedSearch(search SavedSearch, sent_at time.Time) (bool, error) {
+	// -- postgres keeps microseconds, which the claim is compared to when released
+	result := db.Model(&SavedSearch{}).
+		Where("id = ? AND last_sent_at = ?", search.ID, search.LastSentAt).
+		UpdateColumn("last_sent_at", sent_at.Truncate(time.Microsecond))
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReleaseSavedSearch gives back a claimed saved search whose digest couldn't be sent, so that it is tried again on the next run
+func ReleaseSavedSearch(search SavedSearch, sent_at time.Time) error {
+	result := db.Model(&SavedSearch{}).
+		Where("id = ? AND last_sent_at = ?", search.ID, sent_at.Truncate(time.Microsecond)).
+		UpdateColumn("last_sent_at", search.LastSentAt)
+	return result.Error
+}
+
+func checkSavedSearch(name string, frequency string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return name, fmt.Errorf("%w: the name can't be empty", ErrInvalidSavedSearch)
+	}
+
+	if _, found := DIGEST_FREQUENCIES[frequency]; !found {
+		return name, fmt.Errorf("%w: unknown frequency %s", ErrInvalidSavedSearch, frequency)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,125 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedSearchModel(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	var search models.SavedSearch
+	t.Run("Test create saved search", func(t *testing.T) {
+		var err error
+		search, err = models.CreateSavedSearch(userID, " Media theory ", "tags=media", "")
+		require.Nil(t, err)
+		assert.Equal(t, "Media theory", search.Name)
+		assert.Equal(t, models.DigestWeekly, search.Frequency)
+		assert.NotEmpty(t, search.UnsubscribeToken)
+		assert.WithinDuration(t, time.Now(), search.LastSentAt, time.Minute)
+	})
+
+	t.Run("Test create invalid saved search", func(t *testing.T) {
+		_, err := models.CreateSavedSearch(userID, "", "tags=media", models.DigestDaily)
+		assert.ErrorIs(t, err, models.ErrInvalidSavedSearch)
+
+		_, err = models.CreateSavedSearch(userID, "Media theory", "tags=media", "hourly")
+		assert.ErrorIs(t, err, models.ErrInvalidSavedSearch)
+	})
+
+	t.Run("Test get saved searches", func(t *testing.T) {
+		searches, err := models.GetSavedSearches(userID)
+		require.Nil(t, err)
+		assert.Equal(t, 1, len(searches))
+		assert.Equal(t, search.UUID, searches[0].UUID)
+	})
+
+	t.Run("Test update saved search", func(t *testing.T) {
+		query := "tags=code"
+		updated, err := models.UpdateSavedSearch(search.UUID, "", &query, models.DigestDaily)
+		require.Nil(t, err)
+		assert.Equal(t, "Media theory", updated.Name)
+		assert.Equal(t, "tags=code", updated.Query)
+		assert.Equal(t, models.DigestDaily, updated.Frequency)
+	})
+
+	t.Run("Test get due saved searches", func(t *testing.T) {
+		due, err := models.GetDueSavedSearches(time.Now())
+		require.Nil(t, err)
+		assert.Equal(t, 0, len(due))
+
+		due, err = models.GetDueSavedSearches(time.Now().Add(25 * time.Hour))
+		require.Nil(t, err)
+		require.Equal(t, 1, len(due))
+		assert.Equal(t, search.UUID, due[0].UUID)
+		assert.Equal(t, userID, due[0].User.UUID)
+
+		sent_at := time.Now().Add(25 * time.Hour)
+		claimed, err := models.ClaimSavedSearch(due[0], sent_at)
+		require.Nil(t, err)
+		assert.True(t, claimed)
+
+		// -- another instance which listed the search as due at the same time can't claim it anymore
+		claimed, err = models.ClaimSavedSearch(due[0], sent_at)
+		require.Nil(t, err)
+		assert.False(t, claimed)
+
+		later, err := models.GetDueSavedSearches(time.Now().Add(25 * time.Hour))
+		require.Nil(t, err)
+		assert.Equal(t, 0, len(later))
+
+		err = models.ReleaseSavedSearch(due[0], sent_at)
+		require.Nil(t, err)
+
+		later, err = models.GetDueSavedSearches(time.Now().Add(25 * time.Hour))
+		require.Nil(t, err)
+		assert.Equal(t, 1, len(later))
+
+		claimed, err = models.ClaimSavedSearch(later[0], sent_at)
+		require.Nil(t, err)
+		assert.True(t, claimed)
+	})
+
+	t.Run("Test unsubscribe saved search", func(t *testing.T) {
+		unsubscribed, err := models.UnsubscribeSavedSearch(search.UnsubscribeToken)
+		require.Nil(t, err)
+		assert.Equal(t, search.UUID, unsubscribed.UUID)
+
+		updated, err := models.GetSavedSearch(search.UUID)
+		require.Nil(t, err)
+		assert.Equal(t, models.DigestNone, updated.Frequency)
+
+		due, err := models.GetDueSavedSearches(time.Now().Add(30 * 24 * time.Hour))
+		require.Nil(t, err)
+		assert.Equal(t, 0, len(due))
+
+		_, err = models.UnsubscribeSavedSearch("not-a-token")
+		assert.ErrorIs(t, err, models.ErrSavedSearchNotFound)
+	})
+
+	t.Run("Test get syllabi listed between", func(t *testing.T) {
+		before := time.Now().Add(-time.Second)
+		_, err := models.UpdateSyllabus(syllabusUnlistedID, &models.Syllabus{Status: "listed"}, userID)
+		require.Nil(t, err)
+
+		params := map[string]any{"listed_between": [2]time.Time{before, time.Now().Add(time.Second)}}
+		syllabi, _, err := models.GetSyllabi(params, models.Page{}, userID)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(syllabi))
+		assert.Equal(t, syllabusUnlistedID, syllabi[0].UUID)
+		assert.NotNil(t, syllabi[0].ListedAt)
+	})
+
+	t.Run("Test delete saved search", func(t *testing.T) {
+		err := models.DeleteSavedSearch(search.UUID)
+		require.Nil(t, err)
+
+		err = models.DeleteSavedSearch(search.UUID)
+		assert.ErrorIs(t, err, models.ErrSavedSearchNotFound)
+	})
+}
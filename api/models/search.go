@@ -0,0 +1,102 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// SEARCH_CONFIGS maps the language of a syllabus to the Postgres text search configuration which stems its words.
+// Syllabi in any other language are indexed without stemming.
+var SEARCH_CONFIGS = map[string]string{
+	"da": "danish",
+	"de": "german",
+	"en": "english",
+	"es": "spanish",
+	"fi": "finnish",
+	"fr": "french",
+	"hu": "hungarian",
+	"it": "italian",
+	"nl": "dutch",
+	"no": "norwegian",
+	"pt": "portuguese",
+	"ro": "romanian",
+	"ru": "russian",
+	"sv": "swedish",
+	"tr": "turkish",
+}
+
+// SNIPPET_OPTIONS are given to ts_headline to build the highlighted snippet of each search hit
+const SNIPPET_OPTIONS = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=\" ... \""
+
+// initSearch keeps a weighted tsvector of each syllabus up to date with a trigger, stemmed according to its language.
+// Titles weigh the most, then tags and instructors, then descriptions and learning outcomes, then readings.
+func initSearch(db *gorm.DB) error {
+	statements := []string{
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION search_config(lang text) RETURNS regconfig AS $$
+			SELECT CASE lower(split_part(coalesce(lang, ''), '-', 1)) %s ELSE 'simple'::regconfig END
+		$$ LANGUAGE sql IMMUTABLE`, searchConfigCases()),
+		`ALTER TABLE syllabi ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+		`CREATE OR REPLACE FUNCTION syllabi_search_vector() RETURNS trigger AS $$
+		BEGIN
+			NEW.search_vector :=
+				setweight(to_tsvector(search_config(NEW.language), coalesce(NEW.title, '')), 'A') ||
+				setweight(to_tsvector(search_config(NEW.language), coalesce(array_to_string(NEW.tags, ' '), '')), 'B') ||
+				setweight(to_tsvector(search_config(NEW.language), coalesce(array_to_string(NEW.instructors, ' '), '')), 'B') ||
+				setweight(to_tsvector(search_config(NEW.language), coalesce(NEW.description, '')), 'C') ||
+				setweight(to_tsvector(search_config(NEW.language), coalesce(array_to_string(NEW.learning_outcomes, ' '), '')), 'C') ||
+				setweight(to_tsvector(search_config(NEW.language), coalesce(array_to_string(NEW.readings, ' '), '')), 'D');
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS syllabi_search_vector ON syllabi`,
+		`CREATE TRIGGER syllabi_search_vector BEFORE INSERT OR UPDATE ON syllabi FOR EACH ROW EXECUTE FUNCTION syllabi_search_vector()`,
+		`CREATE INDEX IF NOT EXISTS idx_syllabi_search ON syllabi USING GIN (search_vector)`,
+		// -- the trigger fills in the vector of syllabi created before it existed
+		`UPDATE syllabi SET title = title WHERE search_vector IS NULL`,
+	}
+
+	for _, s := range statements {
+		if err := db.Exec(s).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func searchConfigCases() string {
+	langs := make([]string, 0, len(SEARCH_CONFIGS))
+	for l := range SEARCH_CONFIGS {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+
+	cases := make([]string, 0, len(langs))
+	for _, l := range langs {
+		cases = append(cases, fmt.Sprintf("WHEN '%s' THEN '%s'::regconfig", l, SEARCH_CONFIGS[l]))
+	}
+	return strings.Join(cases, " ")
+}
+
+// searchQuery parses the keywords of the named argument q with every configuration, so that a single query matches
+// syllabi in all languages while still using the index. Keywords follow the web search syntax: quoted phrases, or, and -.
+func searchQuery() string {
+	configs := searchConfigs()
+	queries := make([]string, 0, len(configs))
+	for _, c := range configs {
+		queries = append(queries, fmt.Sprintf("websearch_to_tsquery('%s', @q)", c))
+	}
+	return "(" + strings.Join(queries, " || ") + ")"
+}
+
+// searchConfigs lists every configuration keywords are parsed with, including the one which does not stem them
+func searchConfigs() []string {
+	configs := []string{"simple"}
+	for _, c := range SEARCH_CONFIGS {
+		configs = append(configs, c)
+	}
+	sort.Strings(configs)
+	return configs
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// ParseSearchParams turns the parameters of a search of syllabi into the filters the models expect. Saved searches
+// keep those parameters as they were given, and are parsed again each time they are run.
+func ParseSearchParams(values url.Values) (map[string]any, error) {
+	params := make(map[string]any, 0)
+	params["keywords"] = ""
+
+	fields, err := ParseFields(values)
+	if err != nil {
+		return params, err
+	}
+	if len(fields) > 0 {
+		params["fields"] = fields
+	}
+
+	// -- comma-separated keywords match any of them, and each keyword is passed on as is to the full-text search
+	kws := values.Get("keywords")
+	kws = strings.Trim(kws, " ")
+	all_kws := make([]string, 0)
+	for _, kw := range strings.Split(kws, ",") {
+		kw = strings.Trim(kw, " ")
+		if kw != "" {
+			all_kws = append(all_kws, kw)
+		}
+	}
+	params["keywords"] = strings.Join(all_kws, " or ")
+
+	// -- q is a structured query, which comes on top of the other parameters
+	query, err := ParseQuery(values.Get("q"))
+	if err != nil {
+		return params, err
+	}
+	params["query"] = query
+
+	// -- tags, languages and levels match any of the ones given, exactly
+	all_tags := make([]string, 0)
+	for _, tag := range strings.Split(values.Get("tags"), ",") {
+		tag = strings.ToLower(strings.Trim(tag, " "))
+		if tag != "" {
+			all_tags = append(all_tags, tag)
+		}
+	}
+	if len(all_tags) > 0 {
+		params["tags"] = all_tags
+	}
+
+	all_langs := make([]string, 0)
+	for _, lang := range strings.Split(values.Get("languages"), ",") {
+		lang = strings.ToLower(strings.Trim(lang, " "))
+		if lang == "" {
+			continue
+		}
+		_, err := language.ParseBase(lang)
+		if err != nil {
+			return params, fmt.Errorf("language is not bcp-47 compliant: %v", err)
+		}
+		all_langs = append(all_langs, lang)
+	}
+	if len(all_langs) > 0 {
+		params["languages"] = all_langs
+	}
+
+	all_levels := make([]int, 0)
+	for _, raw := range strings.Split(values.Get("levels"), ",") {
+		raw = strings.Trim(raw, " ")
+		if raw == "" {
+			continue
+		}
+		l, err := strconv.Atoi(raw)
+		if err != nil {
+			return params, fmt.Errorf("the level of the syllabus should be between 0 and 3: %v", err)
+		}
+		if _, found := LEVELS[l]; !found {
+			return params, fmt.Errorf("the level of the syllabus should be between 0 and 3: %d", l)
+		}
+		all_levels = append(all_levels, l)
+	}
+	if len(all_levels) > 0 {
+		params["levels"] = all_levels
+	}
+
+	// -- institutions are given by name or by UUID
+	all_insts := make([]string, 0)
+	for _, inst := range strings.Split(values.Get("institutions"), ",") {
+		inst = strings.Trim(inst, " ")
+		if inst != "" {
+			all_insts = append(all_insts, inst)
+		}
+	}
+	if len(all_insts) > 0 {
+		params["institutions"] = all_insts
+	}
+
+	all_countries := make([]int, 0)
+	for _, code := range strings.Split(values.Get("countries"), ",") {
+		code = strings.Trim(code, " ")
+		if code != "" {
+			country, err := ParseCountry(code)
+			if err != nil {
+				return params, fmt.Errorf("country is not ISO 3166-1 compliant: %v", err)
+			}
+			all_countries = append(all_countries, country)
+		}
+	}
+	if len(all_countries) > 0 {
+		params["countries"] = all_countries
+	}
+
+	all_terms := make([]string, 0)
+	for _, term := range strings.Split(values.Get("terms"), ",") {
+		term = strings.ToLower(strings.Trim(term, " "))
+		if term != "" {
+			all_terms = append(all_terms, term)
+		}
+	}
+	if len(all_terms) > 0 {
+		params["terms"] = all_terms
+	}
+
+	// -- licenses are given by their SPDX identifier, or as they are commonly written
+	all_licenses := make([]string, 0)
+	for _, raw := range strings.Split(values.Get("licenses"), ",") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		l, found := LookupLicense(raw)
+		if !found {
+			return params, fmt.Errorf("the license %q is not supported", strings.TrimSpace(raw))
+		}
+		all_licenses = append(all_licenses, l.ID)
+	}
+	if len(all_licenses) > 0 {
+		params["licenses"] = all_licenses
+	}
+
+	all_uses := make([]string, 0)
+	for _, use := range strings.Split(values.Get("permits"), ",") {
+		use = strings.ToLower(strings.TrimSpace(use))
+		if use == "" {
+			continue
+		}
+		if !slices.Contains(LICENSE_USES, use) {
+			return params, fmt.Errorf("the use %q should be one of %s", use, strings.Join(LICENSE_USES, ", "))
+		}
+		all_uses = append(all_uses, use)
+	}
+	if len(all_uses) > 0 {
+		params["permits"] = all_uses
+	}
+
+	// -- years are a single year, or a range such as 2019..2022 whose bounds are optional
+	years := strings.Trim(values.Get("years"), " ")
+	if years != "" {
+		from, to, err := ParseRange(years)
+		if err != nil {
+			return params, fmt.Errorf("the years %v", err)
+		}
+		params["years"] = [2]int{from, to}
+	}
+
+	return params, nil
+}
+
+// ParseFields reads the comma-separated ISCED-F 2013 codes, which match exactly unless subfields is set, in which case
+// they also match all the fields they are divided into
+func ParseFields(values url.Values) ([]int, error) {
+	codes := make([]int, 0)
+	subfields := values.Get("subfields") == "true"
+
+	for _, raw := range strings.Split(values.Get("fields"), ",") {
+		raw = strings.Trim(raw, " ")
+		if raw == "" {
+			continue
+		}
+
+		f, err := strconv.Atoi(raw)
+		if err != nil {
+			return codes, fmt.Errorf("field is not compliant integer: %v", err)
+		}
+		if _, found := ACADEMIC_FIELDS[f]; !found {
+			return codes, fmt.Errorf("field is not ISCED-F 2013 compliant: %d", f)
+		}
+
+		if subfields {
+			codes = append(codes, FieldDescendants(f)...)
+		} else {
+			codes = append(codes, f)
+		}
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,427 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	MAX_SESSIONS              = 200
+	MAX_SESSION_TITLE_LENGTH  = 150
+	MAX_SESSION_WEEK          = 60
+	MAX_SESSION_TOPICS_LENGTH = 50
+)
+
+var ErrInvalidSession = errors.New("the session is invalid")
+
+// -- topic outlines often start with the week or session they are taught in, such as "Week 3: Sound theory"
+var outlinePrefix = regexp.MustCompile(`(?i)^\s*(week|woche|semaine|semana|session|class|lecture)\s+(\d{1,2})\s*[:.\-–—]?\s*`)
+
+// -- only the number of a week is kept, the one of a session being its position
+var weekKeywords = map[string]bool{"week": true, "woche": true, "semaine": true, "semana": true}
+
+// Session is a class meeting of a syllabus, in the order they are taught. It is set on a date, or on a week counted from
+// the start of the course, and ties the topics covered to the readings and assignments of the syllabus.
+type Session struct {
+	ID          uint           `gorm:"primaryKey" json:"-"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	UUID        uuid.UUID      `gorm:"uniqueIndex;type:uuid;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
+	SyllabusID  uint           `gorm:"index;not null" json:"-"`
+	Position    int            `gorm:"not null;default:0" json:"position"`
+	Title       string         `gorm:"not null" json:"title"`
+	Date        *time.Time     `gorm:"type:date" json:"date"`
+	Week        *int           `json:"week"`
+	Topics      pq.StringArray `gorm:"type:text[]" json:"topics"`
+	Readings    []Reading      `gorm:"many2many:session_readings;" json:"readings"`
+	Assignments pq.StringArray `gorm:"type:text[]" json:"assignments"`
+}
+
+// SessionInput holds the fields of a session to create or update, the nil ones being left as they are. A zero date or week
+// clears it, and readings are given by their UUID.
+type SessionInput struct {
+	Title       *string
+	Date        *time.Time
+	Week        *int
+	Topics      []string
+	Readings    []uuid.UUID
+	Assignments []string
+	Position    *int
+}
+
+// seedSessions sets the sessions of a syllabus which never had any from its topic outlines, one per outline
+func seedSessions(tx *gorm.DB, syll_id uint, outlines []string) error {
+	var count int64
+	err := tx.Unscoped().Model(&Session{}).Where("syllabus_id = ?", syll_id).Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+
+	sessions := make([]Session, 0)
+	for _, outline := range outlines {
+		if len(sessions) == MAX_SESSIONS {
+			break
+		}
+
+		title := strings.Join(strings.Fields(outline), " ")
+		session := Session{UUID: uuid.New(), SyllabusID: syll_id, Position: len(sessions)}
+
+		if m := outlinePrefix.FindStringSubmatch(title); m != nil {
+			if week, _ := strconv.Atoi(m[2]); weekKeywords[strings.ToLower(m[1])] && week >= 1 && week <= MAX_SESSION_WEEK {
+				session.Week = &week
+			}
+			title = title[len(m[0]):]
+		}
+
+		if title == "" {
+			continue
+		}
+
+		// -- outlines too long for a title are kept whole as the topic of the session
+		if runes := []rune(title); len(runes) > MAX_SESSION_TITLE_LENGTH {
+			session.Topics = []string{title}
+			title = strings.TrimSpace(string(runes[:MAX_SESSION_TITLE_LENGTH-1])) + "…"
+		}
+		session.Title = title
+
+		sessions = append(sessions, session)
+	}
+
+	if len(sessions) == 0 {
+		return nil
+	}
+	return tx.Omit(clause.Associations).Create(&sessions).Error
+}
+
+// seedMissingSessions sets the sessions of the syllabi which never had any from their topic outlines, such as the ones
+// created before sessions
+func seedMissingSessions(db *gorm.DB) error {
+	var sylls []Syllabus
+	err := db.Select("id", "topic_outlines").
+		Where("cardinality(topic_outlines) > 0 AND NOT EXISTS (SELECT 1 FROM sessions WHERE sessions.syllabus_id = syllabi.id)").
+		Find(&sylls).Error
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sylls {
+		if err := seedSessions(db, s.ID, s.TopicOutlines); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copySessions replaces the sessions of a syllabus with copies of the ones of another, such as the one it was forked from
+func copySessions(tx *gorm.DB, from_id uint, to_id uint) error {
+	var sessions []Session
+	err := tx.Preload("Readings").Where("syllabus_id = ?", from_id).Order("position ASC, id ASC").Find(&sessions).Error
+	if err != nil || len(sessions) == 0 {
+		return err
+	}
+
+	return tx.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Where("syllabus_id = ?", to_id).Delete(&Session{}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, s := range sessions {
+			s.ID, s.UUID, s.SyllabusID = 0, uuid.New(), to_id
+			s.CreatedAt, s.UpdatedAt = time.Time{}, time.Time{}
+			if err := tx.Omit("Readings.*").Create(&s).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetSessions lists the sessions of a syllabus visible to the user, in order
+func GetSessions(syll_uuid uuid.UUID, user_uuid uuid.UUID) ([]Session, error) {
+	sessions := make([]Session, 0)
+
+	syll_id, err := visibleSyllabusID(syll_uuid, user_uuid)
+	if err != nil {
+		return sessions, err
+	}
+
+	err = db.Preload("Readings").Where("syllabus_id = ?", syll_id).Order("position ASC, id ASC").Find(&sessions).Error
+	return sessions, err
+}
+
+func GetSession(syll_uuid uuid.UUID, session_uuid uuid.UUID) (Session, error) {
+	var session Session
+	err := db.Preload("Readings").
+		Joins("JOIN syllabi ON syllabi.id = sessions.syllabus_id AND syllabi.deleted_at IS NULL").
+		Where("syllabi.uuid = ? AND sessions.uuid = ?", syll_uuid, session_uuid).
+		First(&session).Error
+	return session, err
+}
+
+// CreateSession adds a session to a syllabus, last unless given a position
+func CreateSession(syll_uuid uuid.UUID, input SessionInput) (Session, error) {
+	var session Session
+
+	var syll Syllabus
+	err := db.Where("uuid = ?", syll_uuid).First(&syll).Error
+	if err != nil {
+		return session, err
+	}
+
+	if input.Title == nil {
+		return session, fmt.Errorf("%w: the title can't be empty", ErrInvalidSession)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// -- the syllabus is locked so that concurrent sessions don't take the same position
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", syll.ID).First(&syll).Error
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		err = tx.Model(&Session{}).Where("syllabus_id = ?", syll.ID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count >= MAX_SESSIONS {
+			return fmt.Errorf("%w: a syllabus can't have more than %d sessions", ErrInvalidSession, MAX_SESSIONS)
+		}
+
+		session = Session{UUID: uuid.New(), SyllabusID: syll.ID, Position: int(count)}
+		err = applySessionInput(tx, &syll, &session, input)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Omit("Readings.*").Create(&session).Error
+		if err != nil {
+			return err
+		}
+
+		if input.Position != nil {
+			return moveSession(tx, syll.ID, session.ID, *input.Position)
+		}
+		return nil
+	})
+	if err != nil {
+		return session, err
+	}
+
+	return GetSession(syll_uuid, session.UUID)
+}
+
+// UpdateSession changes the fields of a session given in the input, moving it to its new position if there is one
+func UpdateSession(syll_uuid uuid.UUID, session_uuid uuid.UUID, input SessionInput) (Session, error) {
+	session, err := GetSession(syll_uuid, session_uuid)
+	if err != nil {
+		return session, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// -- the syllabus is locked so that concurrent moves don't leave sessions at the same position
+		var syll Syllabus
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", session.SyllabusID).First(&syll).Error
+		if err != nil {
+			return err
+		}
+
+		err = applySessionInput(tx, &syll, &session, input)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Omit("Readings.*", "Position").Save(&session).Error
+		if err != nil {
+			return err
+		}
+
+		if input.Readings != nil {
+			err = tx.Model(&session).Omit("Readings.*").Association("Readings").Replace(session.Readings)
+			if err != nil {
+				return err
+			}
+		}
+
+		if input.Position != nil {
+			return moveSession(tx, syll.ID, session.ID, *input.Position)
+		}
+		return nil
+	})
+	if err != nil {
+		return session, err
+	}
+
+	return GetSession(syll_uuid, session_uuid)
+}
+
+// DeleteSession removes a session from a syllabus, the following ones moving up
+func DeleteSession(syll_uuid uuid.UUID, session_uuid uuid.UUID) (Session, error) {
+	session, err := GetSession(syll_uuid, session_uuid)
+	if err != nil {
+		return session, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// -- the syllabus is locked, and the position of the session read again, so that concurrent moves don't leave
+		// sessions at the same position
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", session.SyllabusID).First(&Syllabus{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Select("position").Where("id = ?", session.ID).First(&session).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete(&session).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&Session{}).
+			Where("syllabus_id = ? AND position > ?", session.SyllabusID, session.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+	})
+	return session, err
+}
+
+// pruneSessionReadings unties the sessions of a syllabus from the readings the syllabus doesn't assign anymore
+func pruneSessionReadings(tx *gorm.DB, syll_id uint) error {
+	return tx.Exec(`
+		DELETE FROM session_readings
+		WHERE session_id IN (SELECT id FROM sessions WHERE syllabus_id = ?)
+		AND reading_id NOT IN (SELECT reading_id FROM syllabus_readings WHERE syllabus_id = ?)`,
+		syll_id, syll_id).Error
+}
+
+// applySessionInput checks the input and sets it on the session. Its readings must be assigned by the syllabus,
+// and its assignments be among the ones of the syllabus.
+func applySessionInput(tx *gorm.DB, syll *Syllabus, session *Session, input SessionInput) error {
+	if input.Title != nil {
+		title := strings.Join(strings.Fields(*input.Title), " ")
+		if title == "" || len([]rune(title)) > MAX_SESSION_TITLE_LENGTH {
+			return fmt.Errorf("%w: the title should be between 1 and %d characters", ErrInvalidSession, MAX_SESSION_TITLE_LENGTH)
+		}
+		session.Title = title
+	}
+
+	if input.Date != nil {
+		if input.Date.IsZero() {
+			session.Date = nil
+		} else {
+			date := *input.Date
+			session.Date = &date
+		}
+	}
+
+	if input.Week != nil {
+		switch week := *input.Week; {
+		case week == 0:
+			session.Week = nil
+		case week < 1 || week > MAX_SESSION_WEEK:
+			return fmt.Errorf("%w: the week should be between 1 and %d", ErrInvalidSession, MAX_SESSION_WEEK)
+		default:
+			session.Week = &week
+		}
+	}
+
+	if input.Topics != nil {
+		topics := make([]string, 0)
+		for _, t := range input.Topics {
+			if t = strings.TrimSpace(t); t != "" {
+				topics = append(topics, t)
+			}
+		}
+		if len(topics) > MAX_SESSION_TOPICS_LENGTH {
+			return fmt.Errorf("%w: a session can't have more than %d topics", ErrInvalidSession, MAX_SESSION_TOPICS_LENGTH)
+		}
+		session.Topics = topics
+	}
+
+	if input.Assignments != nil {
+		assigned := make(map[string]bool)
+		for _, a := range syll.Assignments {
+			assigned[strings.TrimSpace(a)] = true
+		}
+
+		assignments := make([]string, 0)
+		for _, a := range input.Assignments {
+			a = strings.TrimSpace(a)
+			if !assigned[a] {
+				return fmt.Errorf("%w: the syllabus has no assignment %q", ErrInvalidSession, a)
+			}
+			assignments = append(assignments, a)
+		}
+		session.Assignments = assignments
+	}
+
+	if input.Readings != nil {
+		readings := make([]Reading, 0)
+		if len(input.Readings) > 0 {
+			err := tx.Joins("JOIN syllabus_readings ON syllabus_readings.reading_id = readings.id AND syllabus_readings.syllabus_id = ?", syll.ID).
+				Where("readings.uuid IN ?", input.Readings).
+				Find(&readings).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		found := make(map[uuid.UUID]bool)
+		for _, r := range readings {
+			found[r.UUID] = true
+		}
+		for _, r := range input.Readings {
+			if !found[r] {
+				return fmt.Errorf("%w: the syllabus doesn't assign the reading %s", ErrInvalidSession, r)
+			}
+		}
+		session.Readings = readings
+	}
+
+	return nil
+}
+
+// moveSession puts a session at a position among the sessions of its syllabus, the last one if the position is past the end
+func moveSession(tx *gorm.DB, syll_id uint, session_id uint, position int) error {
+	if position < 0 {
+		return fmt.Errorf("%w: the position can't be negative", ErrInvalidSession)
+	}
+
+	var ids []uint
+	err := tx.Model(&Session{}).Where("syllabus_id = ?", syll_id).Order("position ASC, id ASC").Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+
+	ordered := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id != session_id {
+			ordered = append(ordered, id)
+		}
+	}
+	if position > len(ordered) {
+		position = len(ordered)
+	}
+	ordered = append(ordered[:position], append([]uint{session_id}, ordered[position:]...)...)
+
+	for i, id := range ordered {
+		err := tx.Model(&Session{}).Where("id = ?", id).Update("position", i).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionModel(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	t.Run("Test sessions seeded from topic outlines", func(t *testing.T) {
+		sessions, err := models.GetSessions(uuid.MustParse("46de6a2b-666b-4c24-b1e1-3595821f8469"), uuid.Nil)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(sessions))
+		assert.Equal(t, "Sound theory", sessions[0].Title)
+		assert.Equal(t, "Recording practice", sessions[1].Title)
+		assert.Equal(t, 1, sessions[1].Position)
+	})
+
+	t.Run("Test sessions seeded on create", func(t *testing.T) {
+		syll, err := models.CreateSyllabus(&models.Syllabus{
+			UUID:          uuid.New(),
+			Title:         "Sessions",
+			Description:   "A syllabus with its weeks in its outlines",
+			TopicOutlines: []string{"Week 1: Introduction", "", "week 3 - Methods"},
+		}, userID)
+		require.Nil(t, err)
+
+		sessions, err := models.GetSessions(syll.UUID, userID)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(sessions))
+		assert.Equal(t, "Introduction", sessions[0].Title)
+		require.NotNil(t, sessions[0].Week)
+		assert.Equal(t, 1, *sessions[0].Week)
+		assert.Equal(t, "Methods", sessions[1].Title)
+		assert.Equal(t, 3, *sessions[1].Week)
+	})
+
+	var session models.Session
+	t.Run("Test create session", func(t *testing.T) {
+		_, err := models.UpdateSyllabus(syllabusID, &models.Syllabus{Assignments: []string{"City analysis", "Final presentation"}}, userID)
+		require.Nil(t, err)
+
+		top, err := models.GetTopReadings(nil, 1)
+		require.Nil(t, err)
+
+		title, week := "Reading the city", 3
+		session, err = models.CreateSession(syllabusID, models.SessionInput{
+			Title:       &title,
+			Week:        &week,
+			Topics:      []string{"Density", " ", "Sidewalks"},
+			Readings:    []uuid.UUID{top[0].UUID},
+			Assignments: []string{"City analysis"},
+		})
+		require.Nil(t, err)
+		assert.Equal(t, title, session.Title)
+		assert.Equal(t, 0, session.Position)
+		assert.Equal(t, 2, len(session.Topics))
+		require.Equal(t, 1, len(session.Readings))
+		assert.Equal(t, top[0].UUID, session.Readings[0].UUID)
+	})
+
+	t.Run("Test create session first", func(t *testing.T) {
+		title, position := "Introduction", 0
+		first, err := models.CreateSession(syllabusID, models.SessionInput{Title: &title, Position: &position})
+		require.Nil(t, err)
+		assert.Equal(t, 0, first.Position)
+
+		sessions, err := models.GetSessions(syllabusID, uuid.Nil)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(sessions))
+		assert.Equal(t, first.UUID, sessions[0].UUID)
+		assert.Equal(t, session.UUID, sessions[1].UUID)
+		assert.Equal(t, 1, sessions[1].Position)
+	})
+
+	t.Run("Test create session malformed", func(t *testing.T) {
+		title, week := "Wrong", 99
+		_, err := models.CreateSession(syllabusID, models.SessionInput{Title: &title, Week: &week})
+		assert.True(t, errors.Is(err, models.ErrInvalidSession))
+
+		_, err = models.CreateSession(syllabusID, models.SessionInput{Title: &title, Assignments: []string{"Unknown"}})
+		assert.True(t, errors.Is(err, models.ErrInvalidSession))
+
+		_, err = models.CreateSession(syllabusID, models.SessionInput{Title: &title, Readings: []uuid.UUID{uuid.New()}})
+		assert.True(t, errors.Is(err, models.ErrInvalidSession))
+
+		_, err = models.CreateSession(syllabusID, models.SessionInput{})
+		assert.True(t, errors.Is(err, models.ErrInvalidSession))
+	})
+
+	t.Run("Test update session", func(t *testing.T) {
+		date, week := time.Date(2022, 10, 17, 0, 0, 0, 0, time.UTC), 0
+		updated, err := models.UpdateSession(syllabusID, session.UUID, models.SessionInput{Date: &date, Week: &week, Readings: []uuid.UUID{}})
+		require.Nil(t, err)
+		assert.Equal(t, session.Title, updated.Title)
+		assert.Nil(t, updated.Week)
+		require.NotNil(t, updated.Date)
+		assert.Equal(t, "2022-10-17", updated.Date.Format("2006-01-02"))
+		assert.Empty(t, updated.Readings)
+		assert.Equal(t, 1, len(updated.Assignments))
+	})
+
+	t.Run("Test session readings dropped with the syllabus readings", func(t *testing.T) {
+		top, err := models.GetTopReadings(nil, 1)
+		require.Nil(t, err)
+
+		linked, err := models.UpdateSession(syllabusID, session.UUID, models.SessionInput{Readings: []uuid.UUID{top[0].UUID}})
+		require.Nil(t, err)
+		require.Equal(t, 1, len(linked.Readings))
+
+		_, err = models.UpdateSyllabus(syllabusID, &models.Syllabus{Readings: []string{"Invisible Cities, Italo Calvino"}}, userID)
+		require.Nil(t, err)
+
+		updated, err := models.GetSession(syllabusID, session.UUID)
+		require.Nil(t, err)
+		assert.Empty(t, updated.Readings)
+	})
+
+	t.Run("Test delete session", func(t *testing.T) {
+		sessions, err := models.GetSessions(syllabusID, uuid.Nil)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(sessions))
+
+		_, err = models.DeleteSession(syllabusID, sessions[0].UUID)
+		require.Nil(t, err)
+
+		sessions, err = models.GetSessions(syllabusID, uuid.Nil)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(sessions))
+		assert.Equal(t, 0, sessions[0].Position)
+
+		_, err = models.DeleteSession(syllabusID, uuid.New())
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+const (
+	DEFAULT_SIMILAR_LIMIT = 5
+	MAX_SIMILAR_LIMIT     = 20
+
+	// -- descriptions which are at least this similar are given as a reason for a match
+	similarDescriptionThreshold = 0.2
+)
+
+// SIMILARITY_WEIGHTS is how much each kind of overlap between two syllabi counts towards their similarity. Tags, fields,
+// readings and learning outcomes are compared by the share of their values in common, and descriptions by their trigrams.
+var SIMILARITY_WEIGHTS = map[string]float64{
+	"tags":              3,
+	"academic_fields":   2,
+	"readings":          3,
+	"learning_outcomes": 1,
+	"description":       2,
+}
+
+// SimilarSyllabus is a syllabus recommended alongside another one, with its score and the reasons it matched
+type SimilarSyllabus struct {
+	Syllabus Syllabus `json:"syllabus"`
+	Score    float64  `json:"score"`
+	Reasons  []string `json:"reasons"`
+}
+
+// initSimilar sets up trigram matching, and the overlap of two arrays: the number of values they have in common, regardless of
+// case and surrounding spaces, over the number of distinct values they have
+func initSimilar(db *gorm.DB) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE OR REPLACE FUNCTION array_overlap(a text[], b text[]) RETURNS real AS $$
+			SELECT coalesce(
+				(SELECT count(*) FROM (SELECT lower(trim(x)) FROM unnest(a) x INTERSECT SELECT lower(trim(y)) FROM unnest(b) y) shared)::real /
+				nullif((SELECT count(*) FROM (SELECT lower(trim(x)) FROM unnest(a) x UNION SELECT lower(trim(y)) FROM unnest(b) y) all_values), 0),
+			0)
+		$$ LANGUAGE sql IMMUTABLE`,
+	}
+
+	for _, s := range statements {
+		if err := db.Exec(s).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSimilarSyllabi returns the listed syllabi most similar to the given one, the most similar first
+func GetSimilarSyllabi(syll_uuid uuid.UUID, limit int, user_uuid uuid.UUID) ([]SimilarSyllabus, error) {
+	similar := make([]SimilarSyllabus, 0)
+
+	syll, err := GetSyllabus(syll_uuid, user_uuid)
+	if err != nil {
+		return similar, err
+	}
+
+	// -- the generic field says nothing about a syllabus, so it isn't compared
+	fields := make(pq.StringArray, 0, len(syll.AcademicFields))
+	for _, f := range syll.AcademicFields {
+		if f == 0 {
+			continue
+		}
+		fields = append(fields, strconv.Itoa(int(f)))
+	}
+
+	args := map[string]interface{}{
+		"id":          syll.ID,
+		"tags":        syll.Tags,
+		"fields":      fields,
+		"readings":    syll.Readings,
+		"outcomes":    syll.LearningOutcomes,
+		"description": syll.Description,
+	}
+
+	score := fmt.Sprintf(`(%g * array_overlap(syllabi.tags, CAST(@tags AS text[])) +
+		%g * array_overlap(array_remove(CAST(syllabi.academic_fields AS text[]), '0'), CAST(@fields AS text[])) +
+		%g * array_overlap(syllabi.readings, CAST(@readings AS text[])) +
+		%g * array_overlap(syllabi.learning_outcomes, CAST(@outcomes AS text[])) +
+		%g * similarity(coalesce(syllabi.description, ''), @description))`,
+		SIMILARITY_WEIGHTS["tags"], SIMILARITY_WEIGHTS["academic_fields"], SIMILARITY_WEIGHTS["readings"], SIMILARITY_WEIGHTS["learning_outcomes"], SIMILARITY_WEIGHTS["description"])
+
+	var scores []struct {
+		ID                    uint
+		Score                 float64
+		DescriptionSimilarity float64
+	}
+	err = db.Model(&Syllabus{}).
+		Select("syllabi.id, "+score+" AS score, similarity(coalesce(syllabi.description, ''), @description) AS description_similarity", args).
+		Where("syllabi.status = 'listed' AND syllabi.id <> @id AND "+score+" > 0", args).
+		Order("score DESC, syllabi.id ASC").
+		Limit(limit).
+		Scan(&scores).Error
+	if err != nil {
+		return similar, err
+	}
+
+	ids := make([]uint, 0, len(scores))
+	for _, s := range scores {
+		ids = append(ids, s.ID)
+	}
+
+	var sylls []Syllabus
+	err = db.Preload("User").Preload("Institutions").Where("id IN ?", ids).Find(&sylls).Error
+	if err != nil {
+		return similar, err
+	}
+
+	byID := make(map[uint]Syllabus, len(sylls))
+	for _, s := range sylls {
+		byID[s.ID] = s
+	}
+
+	for _, s := range scores {
+		other, found := byID[s.ID]
+		if !found {
+			continue
+		}
+		similar = append(similar, SimilarSyllabus{
+			Syllabus: other,
+			Score:    s.Score,
+			Reasons:  similarReasons(syll, other, s.DescriptionSimilarity),
+		})
+	}
+
+	return similar, nil
+}
+
+// similarReasons explains in a few words what two syllabi have in common
+func similarReasons(syll Syllabus, other Syllabus, description float64) []string {
+	reasons := make([]string, 0)
+
+	if tags := sharedValues(syll.Tags, other.Tags); len(tags) > 0 {
+		reasons = append(reasons, fmt.Sprintf("Also tagged %s", strings.Join(tags, ", ")))
+	}
+
+	names := make([]string, 0)
+	for _, f := range other.AcademicFields {
+		if f == 0 {
+			continue
+		}
+		for _, g := range syll.AcademicFields {
+			if f == g {
+				names = append(names, ACADEMIC_FIELDS[int(f)])
+				break
+			}
+		}
+	}
+	if len(names) > 0 {
+		reasons = append(reasons, fmt.Sprintf("Also in %s", strings.Join(names, ", ")))
+	}
+
+	if readings := sharedValues(syll.Readings, other.Readings); len(readings) > 0 {
+		reasons = append(reasons, fmt.Sprintf("Shares %s", plural(len(readings), "reading")))
+	}
+
+	if outcomes := sharedValues(syll.LearningOutcomes, other.LearningOutcomes); len(outcomes) > 0 {
+		reasons = append(reasons, fmt.Sprintf("Shares %s", plural(len(outcomes), "learning outcome")))
+	}
+
+	if description >= similarDescriptionThreshold {
+		reasons = append(reasons, "Has a similar description")
+	}
+
+	return reasons
+}
+
+// sharedValues lists the values of b which are also in a, regardless of case and surrounding spaces, as array_overlap compares them
+func sharedValues(a []string, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	for _, v := range a {
+		seen[strings.ToLower(strings.TrimSpace(v))] = true
+	}
+
+	shared := make([]string, 0)
+	for _, v := range b {
+		key := strings.ToLower(strings.TrimSpace(v))
+		if seen[key] {
+			shared = append(shared, strings.TrimSpace(v))
+			delete(seen, key)
+		}
+	}
+	return shared
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
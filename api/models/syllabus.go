@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/gosimple/slug"
 	"github.com/lib/pq"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Syllabus struct {
@@ -19,12 +21,17 @@ type Syllabus struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 	UUID      uuid.UUID      `gorm:"uniqueIndex;type:uuid;primaryKey;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
 	Status    string         `gorm:"default:unlisted" json:"status" form:"status"`
+	Moderation
+	// -- ListedAt is set by a trigger whenever the syllabus becomes listed
+	ListedAt *time.Time `gorm:"->" json:"listed_at"`
 
 	UserUUID     uuid.UUID     `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"user_uuid" yaml:"user_uuid"`
 	User         User          `gorm:"foreignKey:UserUUID;references:UUID" json:"user"`
 	Collections  []*Collection `gorm:"many2many:collections_syllabi;" json:"collections"`
 	Attachments  []Attachment  `gorm:"foreignKey:SyllabusUUID;references:UUID" json:"attachments"`
 	Institutions []Institution `gorm:"many2many:inst_syllabi;" json:"institutions"`
+	// -- collaborators can edit the syllabus alongside its owner
+	Collaborators []*User `gorm:"many2many:syllabi_collaborators;foreignKey:UUID;joinForeignKey:SyllabusUUID;references:UUID;joinReferences:CollaboratorUUID" json:"collaborators"`
 
 	AcademicFields   pq.Int32Array  `gorm:"type:integer[];" json:"academic_fields" yaml:"academic_fields" form:"academic_fields[]"`
 	AcademicField    string         `gorm:"" json:"academic_field" yaml:"academic_field" form:"academic_field"`
@@ -43,6 +50,22 @@ type Syllabus struct {
 	Title            string         `gorm:"not null" form:"title" json:"title"`
 	Instructors      pq.StringArray `gorm:"type:text[]" form:"instructors[]" json:"instructors"`
 	TopicOutlines    pq.StringArray `gorm:"type:text[]" json:"topic_outlines" form:"topic_outlines[]"`
+
+	// -- citations are readings already split into their fields, such as the ones parsed by OpenSyllabus, and are only indexed
+	Citations []Reading `gorm:"-" json:"citations,omitempty"`
+
+	// -- a fork keeps the syllabus it was derived from, to credit it along with the ones before
+	DerivedFromUUID  *uuid.UUID     `gorm:"type:uuid;index" json:"derived_from_uuid"`
+	Lineage          []SyllabusLink `gorm:"-" json:"lineage,omitempty"`
+	DerivativesCount int64          `gorm:"-" json:"derivatives_count"`
+
+	// -- what the license allows and how to credit the syllabus, from its license
+	Permissions *LicensePermissions `gorm:"-" json:"permissions,omitempty"`
+	Attribution string              `gorm:"-" json:"attribution,omitempty"`
+
+	// -- search hits carry their relevance and a snippet of their description, with the matches in <mark> tags
+	Rank    float64 `gorm:"->;-:migration" json:"rank,omitempty"`
+	Snippet string  `gorm:"->;-:migration" json:"snippet,omitempty"`
 }
 
 type IFormData struct {
@@ -86,16 +109,15 @@ func (s *Syllabus) BeforeCreate(tx *gorm.DB) (err error) {
 }
 
 func (s *Syllabus) IsEmpty() bool {
-	return (len(s.AcademicFields) == 0) && s.AcademicLevel == 0 && len(s.Assignments) == 0 && s.Description == "" && s.Duration == 0 && s.GradingRubric == "" && s.Language == "" && len(s.LearningOutcomes) == 0 && s.Other == "" && len(s.Readings) == 0 && len(s.Tags) == 0 && s.Title == "" && len(s.TopicOutlines) == 0 && len(s.Instructors) == 0 && s.AcademicField == ""
+	return (len(s.AcademicFields) == 0) && s.AcademicLevel == 0 && len(s.Assignments) == 0 && s.Description == "" && s.Duration == 0 && s.GradingRubric == "" && s.Language == "" && len(s.LearningOutcomes) == 0 && s.Other == "" && len(s.Readings) == 0 && len(s.Tags) == 0 && s.Title == "" && len(s.TopicOutlines) == 0 && len(s.Instructors) == 0 && s.AcademicField == "" && s.License == ""
 }
 
+// CreateSyllabus adds a syllabus to the account of the user, along with its first revision, the index of its readings,
+// and the sessions and assessments seeded from its topic outlines and assignments. Either all of them are created or none is.
 func CreateSyllabus(syll *Syllabus, user_uuid uuid.UUID) (Syllabus, error) {
-	user, err := GetUser(user_uuid, user_uuid)
-	if err != nil {
-		return *syll, err
-	}
-
-	err = db.Model(&user).Association("Syllabi").Append(syll)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return createSyllabus(tx, syll, user_uuid)
+	})
 	if err != nil {
 		return *syll, err
 	}
@@ -104,11 +126,47 @@ func CreateSyllabus(syll *Syllabus, user_uuid uuid.UUID) (Syllabus, error) {
 	return created, err
 }
 
-const PAGINATION_LIMIT = 15
+func createSyllabus(tx *gorm.DB, syll *Syllabus, user_uuid uuid.UUID) error {
+	var user User
+	err := tx.Where("uuid = ?", user_uuid).First(&user).Error
+	if err != nil {
+		return err
+	}
+
+	// -- forks keep the license of their original, even one written before licenses were checked
+	if syll.DerivedFromUUID == nil {
+		syll.License, err = NormalizeLicense(syll.License)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Model(&user).Association("Syllabi").Append(syll)
+	if err != nil {
+		return err
+	}
+
+	err = indexReadings(tx, syll.ID, syll.Readings, syll.Citations)
+	if err != nil {
+		return err
+	}
+
+	err = recordRevision(tx, syll.ID, user_uuid, 0)
+	if err != nil {
+		return err
+	}
+
+	err = seedSessions(tx, syll.ID, syll.TopicOutlines)
+	if err != nil {
+		return err
+	}
+
+	return seedAssessments(tx, syll.ID, syll.Assignments)
+}
 
 func GetSyllabus(uuid uuid.UUID, user_uuid uuid.UUID) (Syllabus, error) {
 	var syll Syllabus
-	result := db.Preload("User").Preload("Attachments").Preload("Institutions").Where("uuid = ? AND (status = 'listed' OR user_uuid = ?)", uuid, user_uuid).First(&syll)
+	result := db.Preload("User").Preload("Attachments").Preload("Institutions").Preload("Collaborators").Where("uuid = ? AND (status = 'listed' OR user_uuid = ? OR uuid IN (SELECT syllabus_uuid FROM syllabi_collaborators WHERE collaborator_uuid = ?))", uuid, user_uuid, user_uuid).First(&syll)
 	if result.Error != nil {
 		return syll, result.Error
 	}
@@ -125,12 +183,18 @@ func GetSyllabus(uuid uuid.UUID, user_uuid uuid.UUID) (Syllabus, error) {
 		}
 	}
 
+	err = withLineage(&syll, user_uuid)
+	if err != nil {
+		return syll, err
+	}
+
+	withLicense(&syll)
 	return syll, nil
 }
 
 func GetSyllabusBySlug(slug string, user_uuid uuid.UUID) (Syllabus, error) {
 	var syll Syllabus
-	result := db.Preload("User").Preload("Attachments").Where("slug = ? AND (status = 'listed' OR user_uuid = ?)", slug, user_uuid).First(&syll)
+	result := db.Preload("User").Preload("Attachments").Where("slug = ? AND (status = 'listed' OR user_uuid = ? OR uuid IN (SELECT syllabus_uuid FROM syllabi_collaborators WHERE collaborator_uuid = ?))", slug, user_uuid, user_uuid).First(&syll)
 	if result.Error != nil {
 		return syll, result.Error
 	}
@@ -155,119 +219,169 @@ func GetSyllabusBySlug(slug string, user_uuid uuid.UUID) (Syllabus, error) {
 		}
 	}
 
+	err = withLineage(&syll, user_uuid)
+	if err != nil {
+		return syll, err
+	}
+
+	withLicense(&syll)
 	return syll, nil
 }
 
-// -- GetSyllabiFilters returns the total number of listed syllabi, and the number of available pages
-// -- along with necessary filter params to further query
-func GetSyllabiFilters() (map[string]interface{}, error) {
-	var filters = make(map[string]interface{})
+// GetSyllabi returns a page of the syllabi matching the filters of the params. When params has keywords, only the syllabi
+// matching them are returned, by default ranked by relevance and with a highlighted snippet of their description.
+func GetSyllabi(params map[string]any, page Page, user_uuid uuid.UUID) ([]Syllabus, PageMeta, error) {
+	syllabi := make([]Syllabus, 0)
+
+	query := filterSyllabi(db.Model(&Syllabus{}), params, user_uuid)
 
-	// select distinct from
-	var syllabi []Syllabus
-	res := db.Where("status = 'listed'").Find(&syllabi)
-	if res.Error != nil {
-		return filters, res.Error
+	keywords := searchKeywords(params)
+	searching := keywords != ""
+	args := map[string]interface{}{"q": keywords, "options": SNIPPET_OPTIONS}
+
+	if page.Sort == "" && searching {
+		page.Sort = SortRelevance
+	} else if page.Sort == "" {
+		page.Sort = SortCreated
+	} else if page.Sort == SortRelevance && !searching {
+		return syllabi, PageMeta{}, fmt.Errorf("%w: sorting by relevance requires keywords", ErrInvalidPage)
 	}
-	total_syllabi := int(res.RowsAffected)
-	pages := (total_syllabi / PAGINATION_LIMIT) + 1
 
-	var languages []string
-	res = db.Model(Syllabus{}).Distinct("language").Find(&languages)
-	if res.Error != nil {
-		return filters, res.Error
+	query, meta, err := paginate(query, "syllabi", page, SYLLABUS_SORTS, args)
+	if err != nil {
+		return syllabi, meta, err
 	}
 
-	var levels []string
-	res = db.Model(Syllabus{}).Distinct("academic_level").Find(&levels)
-	if res.Error != nil {
-		return filters, res.Error
+	if searching {
+		q := searchQuery()
+		query = query.Select("syllabi.*, ts_rank_cd(search_vector, "+q+") AS rank, ts_headline(search_config(language), coalesce(description, ''), "+q+", @options) AS snippet", args)
 	}
 
-	var rawFields []string
-	res = db.Model(Syllabus{}).Distinct("academic_fields").Find(&rawFields)
-	if res.Error != nil {
-		return filters, res.Error
+	syllabi, err = findSyllabi(query, page, &meta)
+	return syllabi, meta, err
+}
+
+// findSyllabi fetches a paginated query of syllabi, and sets the cursor of the next page on meta if there is one
+func findSyllabi(query *gorm.DB, page Page, meta *PageMeta) ([]Syllabus, error) {
+	syllabi := make([]Syllabus, 0)
+	result := query.Preload("User").Preload("Institutions").Preload("Attachments").Find(&syllabi)
+	if result.Error != nil {
+		return syllabi, result.Error
 	}
 
-	// todo: isn't this slow af?
-	var fields []string
-	for _, f := range rawFields {
-		// cleanup string into slice
-		f = f[1 : len(f)-1]
-		fs := strings.Split(f, ",")
+	fetched := len(syllabi)
+	if page.Size > 0 && fetched > page.Size {
+		syllabi = syllabi[:page.Size]
+		last := syllabi[len(syllabi)-1]
+		meta.nextCursor(fetched, sortValue(page.Sort, last.CreatedAt, last.UpdatedAt, last.Title, last.Rank), last.ID)
+	}
 
-		// append if not present
-		for _, s := range fs {
-			isPresent := false
-			for _, field := range fields {
-				if s == field {
-					isPresent = true
-				}
-			}
+	return syllabi, nil
+}
 
-			if !isPresent {
-				fields = append(fields, s)
-			}
-		}
+// UpdateSyllabus sets the fields given in syll on the syllabus, and stores the result as a revision authored by the user.
+// Fields left empty are left as they are.
+func UpdateSyllabus(uuid uuid.UUID, syll *Syllabus, author_uuid uuid.UUID) (Syllabus, error) {
+	var existing Syllabus
+	result := db.Where("uuid = ?", uuid).First(&existing)
+	if result.Error != nil {
+		return *syll, result.Error
 	}
 
-	var years []string
-	res = db.Model(Institution{}).Distinct("date_year").Find(&years)
-	if res.Error != nil {
-		return filters, res.Error
+	lift, err := existing.checkModeration(existing.Status, syll.Status, author_uuid)
+	if err != nil {
+		return existing, err
 	}
 
-	filters["total_syllabi"] = total_syllabi
-	filters["total_pages"] = pages
-	filters["languages"] = languages
-	filters["academic_levels"] = levels
-	filters["academic_fields"] = fields
-	filters["academic_years"] = years
+	if syll.License != "" {
+		syll.License, err = existing.checkLicense(db, syll.License)
+		if err != nil {
+			return existing, err
+		}
+	}
 
-	return filters, nil
-}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&existing).Omit(append([]string{clause.Associations, "id", "uuid", "user_uuid", "created_at", "deleted_at", "slug", "derived_from_uuid"}, moderationColumns...)...).Updates(syll).Error
+		if err != nil {
+			return err
+		}
 
-func GetSyllabi(params map[string]any, user_uuid uuid.UUID) ([]Syllabus, error) {
-	var syllabi []Syllabus
+		// -- an admin changing the status of a moderated syllabus lifts its moderation
+		if lift {
+			err = tx.Model(&existing).Select(moderationColumns).Updates(&Syllabus{}).Error
+			if err != nil {
+				return err
+			}
+		}
 
-	page := (params["page"].(int) - 1) * PAGINATION_LIMIT
-	if page < 0 {
-		page = 0
-	}
+		if len(syll.Readings) > 0 {
+			err = indexReadings(tx, existing.ID, syll.Readings, syll.Citations)
+			if err != nil {
+				return err
+			}
+		}
 
-	//-- TODO: we removed server-side pagination for now
-	result := db.Where("language SIMILAR TO ? AND (lower(description) SIMILAR TO ? OR lower(title) SIMILAR TO ? OR lower(ARRAY_TO_STRING(instructors, ' ')) SIMILAR TO ?) AND lower(ARRAY_TO_STRING(tags, ' ')) SIMILAR TO ? AND academic_level::TEXT SIMILAR TO ? AND ARRAY_TO_STRING(academic_fields, ' ') SIMILAR TO ? AND (status = 'listed' OR user_uuid = ?)", params["languages"], params["keywords"], params["keywords"], params["keywords"], params["tags"], params["levels"], params["fields"], user_uuid.String()).Preload("User").Preload("Institutions").Preload("Attachments").Find(&syllabi)
+		if len(syll.TopicOutlines) > 0 {
+			err = seedSessions(tx, existing.ID, syll.TopicOutlines)
+			if err != nil {
+				return err
+			}
+		}
 
-	return syllabi, result.Error
+		if len(syll.Assignments) > 0 {
+			err = seedAssessments(tx, existing.ID, syll.Assignments)
+			if err != nil {
+				return err
+			}
+		}
 
+		return recordRevision(tx, existing.ID, author_uuid, 0)
+	})
+	if err != nil {
+		return existing, err
+	}
+
+	result = db.Where("id = ?", existing.ID).First(&existing)
+	return existing, result.Error
 }
 
-func UpdateSyllabus(uuid uuid.UUID, user_uuid uuid.UUID, syll *Syllabus) (Syllabus, error) {
-	var existing Syllabus
-	result := db.Where("uuid = ? AND user_uuid = ?", uuid, user_uuid).First(&existing)
+// UnlistSyllabus hides a syllabus from everyone but its owner, as a moderator. Only admins can list it again.
+func UnlistSyllabus(uuid uuid.UUID, moderator_uuid uuid.UUID) (Syllabus, error) {
+	var syll Syllabus
+	result := db.Where("uuid = ?", uuid).First(&syll)
 	if result.Error != nil {
-		return *syll, result.Error
+		return syll, result.Error
 	}
 
-	result = db.Model(&existing).Where("uuid = ?", uuid).Updates(&syll)
-	return existing, result.Error
+	syll.Status = "unlisted"
+	syll.moderate(moderator_uuid)
+	result = db.Model(&syll).Select(append([]string{"status"}, moderationColumns...)).Updates(&syll)
+	return syll, result.Error
 }
 
 func AddAttachmentToSyllabus(syll_uuid uuid.UUID, att_uuid uuid.UUID, user_uuid uuid.UUID) (Syllabus, error) {
 	var syll Syllabus
-	result := db.Where("uuid = ? AND user_uuid = ?", syll_uuid, user_uuid).First(&syll)
+	result := db.Where("uuid = ?", syll_uuid).First(&syll)
 	if result.Error != nil {
 		return syll, result.Error
 	}
 
+	// -- the attachment is taken off the syllabus it belongs to, so the user should be able to edit that one too
 	var att Attachment
-	result = db.Where("uuid = ?", att_uuid, user_uuid).First(&att)
+	result = db.Joins("JOIN syllabi ON syllabi.uuid = attachments.syllabus_uuid AND syllabi.deleted_at IS NULL").Where("attachments.uuid = ?", att_uuid).First(&att)
 	if result.Error != nil {
 		return syll, result.Error
 	}
 
-	err := db.Model(&syll).Association("Attachments").Append(&att)
+	allowed, err := canEditSyllabus(att.SyllabusUUID, user_uuid)
+	if err != nil {
+		return syll, err
+	}
+	if !allowed {
+		return syll, ErrAccessDenied
+	}
+
+	err = db.Model(&syll).Association("Attachments").Append(&att)
 	if err != nil {
 		return syll, err
 	}
@@ -276,9 +390,9 @@ func AddAttachmentToSyllabus(syll_uuid uuid.UUID, att_uuid uuid.UUID, user_uuid
 	return updated, err
 }
 
-func RemoveAttachmentFromSyllabus(syll_uuid uuid.UUID, att_uuid uuid.UUID, user_uuid uuid.UUID) (Syllabus, error) {
+func RemoveAttachmentFromSyllabus(syll_uuid uuid.UUID, att_uuid uuid.UUID) (Syllabus, error) {
 	var syll Syllabus
-	result := db.Where("uuid = ? AND user_uuid = ?", syll_uuid, user_uuid).First(&syll)
+	result := db.Where("uuid = ?", syll_uuid).First(&syll)
 	if result.Error != nil {
 		return syll, result.Error
 	}
@@ -294,10 +408,10 @@ func RemoveAttachmentFromSyllabus(syll_uuid uuid.UUID, att_uuid uuid.UUID, user_
 	return syll, nil
 }
 
-func AddInstitutionToSyllabus(syll_uuid uuid.UUID, user_uuid uuid.UUID, inst *Institution) (Institution, error) {
+func AddInstitutionToSyllabus(syll_uuid uuid.UUID, inst *Institution) (Institution, error) {
 	var updated Institution
 	var syll Syllabus
-	result := db.Where("uuid = ? AND user_uuid = ?", syll_uuid, user_uuid).First(&syll)
+	result := db.Where("uuid = ?", syll_uuid).First(&syll)
 	if result.Error != nil {
 		return updated, result.Error
 	}
@@ -313,12 +427,23 @@ func AddInstitutionToSyllabus(syll_uuid uuid.UUID, user_uuid uuid.UUID, inst *In
 
 func EditInstitutionToSyllabus(uuid uuid.UUID, inst_uuid uuid.UUID, updated *Institution) (Institution, error) {
 	var existing Institution
-	result := db.Where("uuid = ?", inst_uuid).First(&existing)
+	var syll Syllabus
+	result := db.Where("uuid = ?", uuid).First(&syll)
 	if result.Error != nil {
 		return existing, result.Error
 	}
 
-	err := db.Model(&existing).Updates(updated).Error
+	// -- only institutions of this syllabus can be edited through it
+	err := db.Model(&syll).Where("uuid = ?", inst_uuid).Association("Institutions").Find(&existing)
+	if err != nil {
+		return existing, err
+	}
+
+	if existing.ID == 0 {
+		return existing, gorm.ErrRecordNotFound
+	}
+
+	err = db.Model(&existing).Updates(updated).Error
 	if err != nil {
 		return existing, err
 	}
@@ -326,9 +451,9 @@ func EditInstitutionToSyllabus(uuid uuid.UUID, inst_uuid uuid.UUID, updated *Ins
 	return existing, err
 }
 
-func RemoveInstitutionFromSyllabus(syll_uuid uuid.UUID, inst_uuid uuid.UUID, user_uuid uuid.UUID) (Syllabus, error) {
+func RemoveInstitutionFromSyllabus(syll_uuid uuid.UUID, inst_uuid uuid.UUID) (Syllabus, error) {
 	var syll Syllabus
-	result := db.Where("uuid = ? AND user_uuid = ?", syll_uuid, user_uuid).First(&syll)
+	result := db.Where("uuid = ?", syll_uuid).First(&syll)
 	if result.Error != nil {
 		return syll, result.Error
 	}
@@ -343,9 +468,76 @@ func RemoveInstitutionFromSyllabus(syll_uuid uuid.UUID, inst_uuid uuid.UUID, use
 	return syll, err
 }
 
-func DeleteSyllabus(uuid uuid.UUID, user_uuid uuid.UUID) (Syllabus, error) {
+// AddCollaboratorToSyllabus lets another user edit the syllabus alongside its owner
+func AddCollaboratorToSyllabus(syll_uuid uuid.UUID, collab_uuid uuid.UUID) (Syllabus, error) {
+	var syll Syllabus
+	result := db.Where("uuid = ?", syll_uuid).First(&syll)
+	if result.Error != nil {
+		return syll, result.Error
+	}
+
+	if syll.UserUUID == collab_uuid {
+		return syll, fmt.Errorf("the owner of the syllabus cannot be a collaborator")
+	}
+
+	var collab User
+	result = db.Where("uuid = ? AND status = ?", collab_uuid, UserConfirmed).First(&collab)
+	if result.Error != nil {
+		return syll, result.Error
+	}
+
+	err := db.Model(&syll).Association("Collaborators").Append(&collab)
+	if err != nil {
+		return syll, err
+	}
+
+	err = db.Model(&syll).Association("Collaborators").Find(&syll.Collaborators)
+	return syll, err
+}
+
+func RemoveCollaboratorFromSyllabus(syll_uuid uuid.UUID, collab_uuid uuid.UUID) (Syllabus, error) {
+	var syll Syllabus
+	result := db.Where("uuid = ?", syll_uuid).First(&syll)
+	if result.Error != nil {
+		return syll, result.Error
+	}
+
+	var collab User
+	result = db.Where("uuid = ?", collab_uuid).First(&collab)
+	if result.Error != nil {
+		return syll, result.Error
+	}
+
+	err := db.Model(&syll).Association("Collaborators").Delete(&collab)
+	if err != nil {
+		return syll, err
+	}
+
+	err = db.Model(&syll).Association("Collaborators").Find(&syll.Collaborators)
+	return syll, err
+}
+
+// checkLicense normalizes a license the syllabus is given, which forks can only set to one their original allows
+func (s Syllabus) checkLicense(tx *gorm.DB, raw string) (string, error) {
+	license, err := NormalizeLicense(raw)
+	if err != nil || s.DerivedFromUUID == nil {
+		return license, err
+	}
+
+	var original Syllabus
+	err = tx.Unscoped().Select("license").Where("uuid = ?", s.DerivedFromUUID).First(&original).Error
+	if err == nil {
+		err = checkDerivedLicense(original.License, license)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return license, err
+	}
+	return license, nil
+}
+
+func DeleteSyllabus(uuid uuid.UUID) (Syllabus, error) {
 	var syll Syllabus
-	result := db.Where("uuid = ? AND user_uuid = ?", uuid, user_uuid).First(&syll)
+	result := db.Where("uuid = ?", uuid).First(&syll)
 	if result.Error != nil {
 		return syll, result.Error
 	}
@@ -1,6 +1,7 @@
 package models_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -12,7 +13,7 @@ import (
 
 var (
 	listedSyllabiCount = 20
-	tagSyllabiCount    = 3
+	tagSyllabiCount    = 1
 	levelsSyllabiCount = 8
 )
 
@@ -21,57 +22,210 @@ func TestSyllabusModel(t *testing.T) {
 	defer teardown(t)
 
 	searchParams := make(map[string]any, 0)
-	searchParams["page"] = 0
-	searchParams["languages"] = "%"
-	searchParams["keywords"] = "%"
-	searchParams["fields"] = "%"
-	searchParams["levels"] = "%"
-	searchParams["tags"] = "%"
+	searchParams["keywords"] = ""
 
 	t.Run("Test get all listed syllabi", func(t *testing.T) {
-		syll, err := models.GetSyllabi(searchParams, userID)
+		syll, _, err := models.GetSyllabi(searchParams, models.Page{}, userID)
 		require.Nil(t, err)
 		assert.Equal(t, listedSyllabiCount, len(syll))
 	})
 
 	t.Run("Test get all listed syllabi written in french", func(t *testing.T) {
-		searchParams["languages"] = "%(de)%"
-		syll, err := models.GetSyllabi(searchParams, userID)
+		searchParams["languages"] = []string{"de"}
+		syll, _, err := models.GetSyllabi(searchParams, models.Page{}, userID)
 		require.Nil(t, err)
 		assert.Equal(t, 2, len(syll))
-		searchParams["languages"] = "%"
+		delete(searchParams, "languages")
 	})
 
 	t.Run("Test get all listed syllabi with keywords search", func(t *testing.T) {
-		searchParams["keywords"] = "%(berlin|architektur)%"
-		syll, err := models.GetSyllabi(searchParams, userID)
+		searchParams["keywords"] = "berlin or architektur"
+		syll, _, err := models.GetSyllabi(searchParams, models.Page{}, userID)
 		require.Nil(t, err)
 		assert.Equal(t, 2, len(syll))
-		searchParams["keywords"] = "%"
+		searchParams["keywords"] = ""
+	})
+
+	t.Run("Test keywords search is stemmed in the language of the syllabus", func(t *testing.T) {
+		searchParams["keywords"] = "Maschine"
+		syll, _, err := models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(syll))
+		assert.Contains(t, syll[0].Snippet, "<mark>Maschinen</mark>")
+		assert.Greater(t, syll[0].Rank, 0.0)
+		searchParams["keywords"] = ""
+	})
+
+	t.Run("Test keywords search ranks title matches first", func(t *testing.T) {
+		searchParams["keywords"] = "architektur"
+		syll, _, err := models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(syll))
+		assert.GreaterOrEqual(t, syll[0].Rank, syll[1].Rank)
+		searchParams["keywords"] = ""
+	})
+
+	t.Run("Test keywords search ignores regex characters", func(t *testing.T) {
+		searchParams["keywords"] = "(architektur|%"
+		syll, _, err := models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		assert.Equal(t, 2, len(syll))
+		searchParams["keywords"] = ""
 	})
 
 	t.Run("Test get all listed syllabi with tag search", func(t *testing.T) {
-		searchParams["tags"] = "%(design)%"
-		syll, err := models.GetSyllabi(searchParams, userID)
+		searchParams["tags"] = []string{"design"}
+		syll, _, err := models.GetSyllabi(searchParams, models.Page{}, userID)
 		require.Nil(t, err)
 		assert.Equal(t, tagSyllabiCount, len(syll))
-		searchParams["tags"] = "%"
+		delete(searchParams, "tags")
 	})
 
 	t.Run("Test get all listed syllabi with levels", func(t *testing.T) {
-		searchParams["levels"] = "%(2)%"
-		syll, err := models.GetSyllabi(searchParams, userID)
+		searchParams["levels"] = []int{2}
+		syll, _, err := models.GetSyllabi(searchParams, models.Page{}, userID)
 		require.Nil(t, err)
 		assert.Equal(t, levelsSyllabiCount, len(syll))
-		searchParams["levels"] = "%"
+		delete(searchParams, "levels")
 	})
 
 	t.Run("Test get all listed syllabi with fields", func(t *testing.T) {
-		searchParams["fields"] = "%(100)%"
-		syll, err := models.GetSyllabi(searchParams, userID)
+		searchParams["fields"] = []int{100}
+		syll, _, err := models.GetSyllabi(searchParams, models.Page{}, userID)
 		require.Nil(t, err)
 		assert.Equal(t, 2, len(syll))
-		searchParams["fields"] = "%"
+
+		// -- narrow fields don't match the detailed fields whose code starts the same
+		searchParams["fields"] = []int{22}
+		syll, _, err = models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		assert.Equal(t, 0, len(syll))
+
+		searchParams["fields"] = models.FieldDescendants(22)
+		syll, _, err = models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		assert.Equal(t, 3, len(syll))
+		delete(searchParams, "fields")
+	})
+
+	t.Run("Test get all listed syllabi with a query", func(t *testing.T) {
+		query, err := models.ParseQuery(`language:de -tag:design`)
+		require.Nil(t, err)
+		searchParams["query"] = query
+		syll, _, err := models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(syll))
+		assert.Equal(t, syllabusTitle, syll[0].Title)
+
+		query, err = models.ParseQuery(`(tag:architektur OR tag:parametric) level:2`)
+		require.Nil(t, err)
+		searchParams["query"] = query
+		syll, _, err = models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		assert.Equal(t, 1, len(syll))
+
+		query, err = models.ParseQuery(`"Drawing Machines" OR institution:"abu dhabi" year:..2016`)
+		require.Nil(t, err)
+		searchParams["query"] = query
+		_, _, err = models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		delete(searchParams, "query")
+	})
+
+	t.Run("Test get all listed syllabi by institution", func(t *testing.T) {
+		searchParams["years"] = [2]int{2021, 2021}
+		searchParams["terms"] = []string{"fall"}
+		searchParams["countries"] = []int{12}
+		syll, _, err := models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		assert.Equal(t, 3, len(syll))
+
+		searchParams["countries"] = []int{250}
+		syll, _, err = models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		assert.Equal(t, 0, len(syll))
+		delete(searchParams, "countries")
+		delete(searchParams, "terms")
+
+		searchParams["years"] = [2]int{2016, 2017}
+		syll, _, err = models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		assert.Equal(t, 4, len(syll))
+		delete(searchParams, "years")
+
+		searchParams["institutions"] = []string{"NYU Berlin"}
+		syll, _, err = models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(syll))
+
+		// -- the filters hold for the same institution
+		searchParams["years"] = [2]int{2016, 2016}
+		syll, _, err = models.GetSyllabi(searchParams, models.Page{}, userID)
+		require.Nil(t, err)
+		assert.Equal(t, 0, len(syll))
+		delete(searchParams, "years")
+		delete(searchParams, "institutions")
+	})
+
+	t.Run("Test facets count the visible syllabi", func(t *testing.T) {
+		facets, err := models.GetSyllabiFacets(searchParams, uuid.Nil)
+		require.Nil(t, err)
+		assert.Equal(t, int64(1), facetCount(facets["languages"], "de"))
+
+		facets, err = models.GetSyllabiFacets(searchParams, userID)
+		require.Nil(t, err)
+		assert.Equal(t, int64(2), facetCount(facets["languages"], "de"))
+		assert.Equal(t, int64(levelsSyllabiCount), facetCount(facets["academic_levels"], "2"))
+		assert.Equal(t, int64(2), facetCount(facets["academic_fields"], "100"))
+		assert.NotEmpty(t, facets["academic_years"])
+		assert.NotEmpty(t, facets["countries"])
+		assert.NotEmpty(t, facets["institutions"])
+		assert.NotEmpty(t, facets["tags"])
+	})
+
+	t.Run("Test facets follow the current filters", func(t *testing.T) {
+		searchParams["fields"] = []int{100}
+		facets, err := models.GetSyllabiFacets(searchParams, userID)
+		require.Nil(t, err)
+		var total int64
+		for _, f := range facets["languages"] {
+			total += f.Count
+		}
+		assert.Equal(t, int64(2), total)
+		delete(searchParams, "fields")
+
+		searchParams["keywords"] = "architektur"
+		facets, err = models.GetSyllabiFacets(searchParams, userID)
+		require.Nil(t, err)
+		assert.Equal(t, int64(2), facetCount(facets["languages"], "de"))
+		assert.Zero(t, facetCount(facets["languages"], "en"))
+		searchParams["keywords"] = ""
+	})
+
+	t.Run("Test facets ignore their own filter", func(t *testing.T) {
+		searchParams["levels"] = []int{2}
+		facets, err := models.GetSyllabiFacets(searchParams, userID)
+		require.Nil(t, err)
+		assert.Equal(t, int64(levelsSyllabiCount), facetCount(facets["academic_levels"], "2"))
+		assert.NotZero(t, facetCount(facets["academic_levels"], "1"))
+		delete(searchParams, "levels")
+	})
+
+	t.Run("Test institution facets only count the institutions matching the filters", func(t *testing.T) {
+		// -- a syllabus taught both in the filtered country and in another one
+		syll_uuid := uuid.MustParse("46de6a2b-aacb-4c24-b1e1-6665821f846a")
+		inst, err := models.AddInstitutionToSyllabus(syll_uuid, &models.Institution{Name: "Facet Uni", Country: 250, Date: models.Date{Term: "fall", Year: 1999}})
+		require.Nil(t, err)
+		defer models.RemoveInstitutionFromSyllabus(syll_uuid, inst.UUID)
+
+		searchParams["countries"] = []int{12}
+		facets, err := models.GetSyllabiFacets(searchParams, userID)
+		require.Nil(t, err)
+		assert.NotZero(t, facetCount(facets["academic_years"], "2016"))
+		assert.Zero(t, facetCount(facets["academic_years"], "1999"))
+		assert.Zero(t, facetCount(facets["institutions"], "Facet Uni"))
+		assert.NotZero(t, facetCount(facets["countries"], "250"))
+		delete(searchParams, "countries")
 	})
 
 	t.Run("Test create bare syllabus", func(t *testing.T) {
@@ -98,6 +252,36 @@ func TestSyllabusModel(t *testing.T) {
 		assert.NotNil(t, err)
 	})
 
+	t.Run("Test get similar syllabi", func(t *testing.T) {
+		similar, err := models.GetSimilarSyllabi(syllabusID, models.DEFAULT_SIMILAR_LIMIT, userID)
+		require.Nil(t, err)
+		require.NotEmpty(t, similar)
+		assert.LessOrEqual(t, len(similar), models.DEFAULT_SIMILAR_LIMIT)
+		assert.Equal(t, "Politics of Code", similar[0].Syllabus.Title)
+		for i := 1; i < len(similar); i++ {
+			assert.GreaterOrEqual(t, similar[i-1].Score, similar[i].Score)
+		}
+
+		_, err = models.GetSimilarSyllabi(syllabusUnknownID, models.DEFAULT_SIMILAR_LIMIT, userID)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test similar syllabi do not match on the generic field", func(t *testing.T) {
+		// -- a syllabus created without fields is in the generic one, like one of the fixtures
+		generic, err := models.CreateSyllabus(&models.Syllabus{UUID: uuid.New(), Title: "Generic", Description: "A syllabus without a field"}, userID)
+		require.Nil(t, err)
+		defer models.DeleteSyllabus(generic.UUID)
+		require.Equal(t, 1, len(generic.AcademicFields))
+
+		similar, err := models.GetSimilarSyllabi(generic.UUID, models.MAX_SIMILAR_LIMIT, userID)
+		require.Nil(t, err)
+		for _, s := range similar {
+			for _, reason := range s.Reasons {
+				assert.NotContains(t, reason, models.ACADEMIC_FIELDS[0])
+			}
+		}
+	})
+
 	t.Run("Test get syllabus by slug", func(t *testing.T) {
 		syll, err := models.GetSyllabusBySlug(syllabusSlug, userID)
 		require.Nil(t, err)
@@ -107,6 +291,24 @@ func TestSyllabusModel(t *testing.T) {
 		assert.Equal(t, 1, len(syll.Institutions))
 	})
 
+	t.Run("Test get unlisted syllabus by slug as a collaborator", func(t *testing.T) {
+		unlisted, err := models.GetSyllabus(syllabusUnlistedID, userID)
+		require.Nil(t, err)
+
+		_, err = models.GetSyllabusBySlug(unlisted.Slug, userDeleteID)
+		assert.NotNil(t, err)
+
+		_, err = models.AddCollaboratorToSyllabus(syllabusUnlistedID, userDeleteID)
+		require.Nil(t, err)
+
+		syll, err := models.GetSyllabusBySlug(unlisted.Slug, userDeleteID)
+		require.Nil(t, err)
+		assert.Equal(t, syllabusUnlistedID, syll.UUID)
+
+		_, err = models.RemoveCollaboratorFromSyllabus(syllabusUnlistedID, userDeleteID)
+		require.Nil(t, err)
+	})
+
 	t.Run("Test get non-existing syllabus", func(t *testing.T) {
 		syll, err := models.GetSyllabus(syllabusUnknownID, userID)
 		assert.NotNil(t, err)
@@ -117,7 +319,7 @@ func TestSyllabusModel(t *testing.T) {
 		var syll models.Syllabus
 		updatedTitle := fmt.Sprintf("%s (updated)", syllabusTitle)
 		syll.Title = updatedTitle
-		updated, err := models.UpdateSyllabus(syllabusID, userID, &syll)
+		updated, err := models.UpdateSyllabus(syllabusID, &syll, userID)
 
 		require.Nil(t, err)
 		require.NotZero(t, updated.CreatedAt)
@@ -125,20 +327,11 @@ func TestSyllabusModel(t *testing.T) {
 		assert.Equal(t, updatedTitle, syll.Title)
 	})
 
-	t.Run("Test update syllabus wrong user", func(t *testing.T) {
-		var syll models.Syllabus
-		updatedTitle := fmt.Sprintf("%s (updated by someone else)", syllabusTitle)
-		syll.Title = updatedTitle
-		_, err := models.UpdateSyllabus(syllabusID, userUnknownID, &syll)
-
-		require.NotNil(t, err)
-	})
-
 	t.Run("Test update non-existing syllabus", func(t *testing.T) {
 		syll := models.Syllabus{
 			Title: "Test Title 1 (updated)",
 		}
-		updated, err := models.UpdateSyllabus(syllabusUnknownID, userID, &syll)
+		updated, err := models.UpdateSyllabus(syllabusUnknownID, &syll, userID)
 		assert.NotNil(t, err)
 		assert.Zero(t, updated.CreatedAt)
 	})
@@ -160,38 +353,87 @@ func TestSyllabusModel(t *testing.T) {
 			},
 		}
 
-		updated, err := models.AddInstitutionToSyllabus(syllabusID, userID, &inst)
+		updated, err := models.AddInstitutionToSyllabus(syllabusID, &inst)
 		assert.Nil(t, err)
 		assert.NotNil(t, updated.UUID)
 		newInstID = updated.UUID
 	})
 
 	t.Run("Test remove institution from syllabus", func(t *testing.T) {
-		updated, err := models.RemoveInstitutionFromSyllabus(syllabusID, newInstID, userID)
+		updated, err := models.RemoveInstitutionFromSyllabus(syllabusID, newInstID)
 		assert.Nil(t, err)
 		assert.Equal(t, syllabusID, updated.UUID)
 		assert.Equal(t, 0, len(updated.Institutions))
 	})
 
 	t.Run("Test remove institution from syllabus wrong syll ID", func(t *testing.T) {
-		_, err := models.RemoveInstitutionFromSyllabus(syllabusUnknownID, newInstID, userID)
+		_, err := models.RemoveInstitutionFromSyllabus(syllabusUnknownID, newInstID)
 		assert.NotNil(t, err)
 	})
 
 	t.Run("Test remove institution from syllabus wrong inst ID", func(t *testing.T) {
-		_, err := models.RemoveInstitutionFromSyllabus(syllabusID, instID, userID)
+		_, err := models.RemoveInstitutionFromSyllabus(syllabusID, instID)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test unlist syllabus", func(t *testing.T) {
+		syll, err := models.UnlistSyllabus(syllabusDeleteID, adminID)
+		require.Nil(t, err)
+		assert.Equal(t, "unlisted", syll.Status)
+		assert.NotNil(t, syll.ModeratedAt)
+	})
+
+	t.Run("Test admin listing includes moderated syllabi", func(t *testing.T) {
+		syllabi, _, err := models.GetAdminSyllabi(models.Page{})
+		require.Nil(t, err)
+
+		found := false
+		for _, s := range syllabi {
+			if s.UUID == syllabusDeleteID {
+				found = true
+				assert.NotNil(t, s.ModeratedAt)
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("Test unlisted syllabus can only be listed by an admin", func(t *testing.T) {
+		_, err := models.UpdateSyllabus(syllabusDeleteID, &models.Syllabus{Status: "listed"}, userID)
+		assert.True(t, errors.Is(err, models.ErrModerated))
+
+		updated, err := models.UpdateSyllabus(syllabusDeleteID, &models.Syllabus{Title: "Still unlisted"}, userID)
+		require.Nil(t, err)
+		assert.Equal(t, "unlisted", updated.Status)
+
+		updated, err = models.UpdateSyllabus(syllabusDeleteID, &models.Syllabus{Status: "listed"}, adminID)
+		require.Nil(t, err)
+		assert.Equal(t, "listed", updated.Status)
+		assert.Nil(t, updated.ModeratedAt)
+	})
+
+	t.Run("Test unlist non-existing syllabus", func(t *testing.T) {
+		_, err := models.UnlistSyllabus(syllabusUnknownID, adminID)
 		assert.NotNil(t, err)
 	})
 
 	t.Run("Test delete syllabus", func(t *testing.T) {
-		syll, err := models.DeleteSyllabus(syllabusDeleteID, userID)
+		syll, err := models.DeleteSyllabus(syllabusDeleteID)
 		assert.NotNil(t, syll)
 		assert.Nil(t, err)
 	})
 
 	t.Run("Test delete wrong syllabus", func(t *testing.T) {
-		syll, err := models.DeleteSyllabus(syllabusUnknownID, userID)
+		syll, err := models.DeleteSyllabus(syllabusUnknownID)
 		assert.NotNil(t, err)
 		assert.Zero(t, syll)
 	})
 }
+
+func facetCount(counts []models.FacetCount, value string) int64 {
+	for _, c := range counts {
+		if c.Value == value {
+			return c.Count
+		}
+	}
+	return 0
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"sort"
+)
+
+const (
+	FieldBroad    string = "broad"
+	FieldNarrow   string = "narrow"
+	FieldDetailed string = "detailed"
+)
+
+// Field is a field of the ISCED-F 2013 taxonomy, along with the fields it is divided into
+type Field struct {
+	Code     int     `json:"code"`
+	Name     string  `json:"name"`
+	Level    string  `json:"level"`
+	Children []Field `json:"children"`
+}
+
+// -- ACADEMIC_FIELDS writes the codes of ISCED-F without their leading zeroes, and broad fields with two trailing ones so that
+// they don't clash with the narrow fields of education: 01 is 100, 011 is 11 and 0111 is 111, while 10 is 1000, 101 is 101 and 1011 is 1011.
+var fieldChildren = indexFieldChildren()
+
+func indexFieldChildren() map[int][]int {
+	children := make(map[int][]int)
+	for code := range ACADEMIC_FIELDS {
+		if parent, found := FieldParent(code); found {
+			children[parent] = append(children[parent], code)
+		}
+	}
+	for _, c := range children {
+		sort.Ints(c)
+	}
+	return children
+}
+
+// FieldLevel tells whether a code is a broad, narrow or detailed field
+func FieldLevel(code int) string {
+	switch {
+	case code == 0 || (code >= 100 && code%100 == 0):
+		return FieldBroad
+	case code < 110:
+		return FieldNarrow
+	default:
+		return FieldDetailed
+	}
+}
+
+// FieldParent returns the field a narrow or detailed field belongs to. Detailed fields whose narrow field is not listed
+// belong to their broad field.
+func FieldParent(code int) (int, bool) {
+	if _, found := ACADEMIC_FIELDS[code]; !found {
+		return 0, false
+	}
+
+	broad := code / 100 * 100
+	switch FieldLevel(code) {
+	case FieldNarrow:
+		if code >= 100 {
+			return 1000, true
+		}
+		return code / 10 * 100, true
+	case FieldDetailed:
+		if _, found := ACADEMIC_FIELDS[code/10]; found {
+			return code / 10, true
+		}
+		return broad, true
+	default:
+		return 0, false
+	}
+}
+
+// FieldChildren lists the codes of the fields a field is divided into
+func FieldChildren(code int) []int {
+	return fieldChildren[code]
+}
+
+// FieldDescendants lists the code of a field along with the codes of all the fields it is divided into
+func FieldDescendants(code int) []int {
+	codes := []int{code}
+	for _, child := range fieldChildren[code] {
+		codes = append(codes, FieldDescendants(child)...)
+	}
+	return codes
+}
+
+// GetFieldsTaxonomy returns the tree of the ISCED-F 2013 fields, from the broad fields down to the detailed ones
+func GetFieldsTaxonomy() []Field {
+	roots := make([]int, 0)
+	for code := range ACADEMIC_FIELDS {
+		if FieldLevel(code) == FieldBroad {
+			roots = append(roots, code)
+		}
+	}
+	sort.Ints(roots)
+
+	return fieldsTree(roots)
+}
+
+func fieldsTree(codes []int) []Field {
+	fields := make([]Field, 0, len(codes))
+	for _, code := range codes {
+		fields = append(fields, Field{
+			Code:     code,
+			Name:     ACADEMIC_FIELDS[code],
+			Level:    FieldLevel(code),
+			Children: fieldsTree(fieldChildren[code]),
+		})
+	}
+	return fields
+}
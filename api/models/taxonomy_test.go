@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaxonomy(t *testing.T) {
+	t.Run("Test field levels", func(t *testing.T) {
+		for code, level := range map[int]string{0: models.FieldBroad, 500: models.FieldBroad, 1000: models.FieldBroad, 1: models.FieldNarrow, 54: models.FieldNarrow, 101: models.FieldNarrow, 541: models.FieldDetailed, 1011: models.FieldDetailed} {
+			assert.Equal(t, level, models.FieldLevel(code), code)
+		}
+	})
+
+	t.Run("Test field parents", func(t *testing.T) {
+		for code, parent := range map[int]int{1: 0, 11: 100, 111: 11, 54: 500, 541: 54, 231: 23, 101: 1000, 1011: 101} {
+			p, found := models.FieldParent(code)
+			assert.True(t, found, code)
+			assert.Equal(t, parent, p, code)
+		}
+
+		for _, code := range []int{0, 100, 1000, 666} {
+			_, found := models.FieldParent(code)
+			assert.False(t, found, code)
+		}
+	})
+
+	t.Run("Test field descendants", func(t *testing.T) {
+		assert.Equal(t, []int{54, 541, 542}, models.FieldDescendants(54))
+		assert.Equal(t, []int{541}, models.FieldDescendants(541))
+		assert.Equal(t, []int{51, 52, 53, 54}, models.FieldChildren(500))
+		assert.Len(t, models.FieldDescendants(500), 14)
+	})
+
+	t.Run("Test fields taxonomy lists every field once", func(t *testing.T) {
+		seen := make(map[int]int)
+		var walk func(fields []models.Field)
+		walk = func(fields []models.Field) {
+			for _, f := range fields {
+				seen[f.Code]++
+				assert.Equal(t, models.ACADEMIC_FIELDS[f.Code], f.Name)
+				walk(f.Children)
+			}
+		}
+		walk(models.GetFieldsTaxonomy())
+
+		assert.Len(t, seen, len(models.ACADEMIC_FIELDS))
+		for code, n := range seen {
+			assert.Equal(t, 1, n, code)
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	zero "github.com/commonsyllabi/explorer/api/logger"
@@ -8,31 +10,66 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	TokenConfirmation string = "confirmation"
+	TokenRecovery     string = "recovery"
+)
+
+// TOKEN_TTL is how long each kind of token can be redeemed after it has been sent
+var TOKEN_TTL = map[string]time.Duration{
+	TokenConfirmation: 7 * 24 * time.Hour,
+	TokenRecovery:     time.Hour,
+}
+
+var ErrTokenInvalid = errors.New("the token is invalid, expired or already used")
+
+// Token is a single-use secret sent by email to confirm an account or recover a password.
+// Only the hash of the secret is stored, and a token can only be redeemed for its own kind.
 type Token struct {
-	ID        uint           `gorm:"primaryKey"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
-	UUID      uuid.UUID      `gorm:"uniqueIndex;type:uuid;primaryKey;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
-	UserID    uuid.UUID      `gorm:"type:uuid" yaml:"user_id" json:"user_id"`
+	ID        uint       `gorm:"primaryKey"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	UUID      uuid.UUID  `gorm:"uniqueIndex;type:uuid;primaryKey;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
+	UserID    uuid.UUID  `gorm:"type:uuid" yaml:"user_id" json:"user_id"`
+	Kind      string     `gorm:"not null;default:confirmation;index" json:"kind"`
+	Hash      string     `gorm:"uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;default:now()" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
 }
 
-func CreateToken(token_uuid uuid.UUID) (Token, error) {
-	hash := uuid.New()
-	token := Token{UUID: hash, UserID: token_uuid}
+// CreateToken creates a token of the given kind for a user, and returns the secret to send along with the stored token
+func CreateToken(user_uuid uuid.UUID, kind string) (string, Token, error) {
+	var token Token
+	ttl, found := TOKEN_TTL[kind]
+	if !found {
+		return "", token, fmt.Errorf("unknown token kind: %s", kind)
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return "", token, err
+	}
+
+	token = Token{
+		UUID:      uuid.New(),
+		UserID:    user_uuid,
+		Kind:      kind,
+		Hash:      hashSecret(secret),
+		ExpiresAt: time.Now().Add(ttl),
+	}
 	result := db.Create(&token)
-	return token, result.Error
+	return secret, token, result.Error
 }
 
-// GetTokenUser takes a token and returns the full user associated with it
-func GetTokenUser(token_uuid uuid.UUID) (User, error) {
+// GetTokenUser takes a secret and returns the full user associated with it, if the token can still be redeemed
+func GetTokenUser(secret string, kind string) (User, error) {
 	var user User
 	var token Token
 
-	result := db.Where("uuid = ?", token_uuid).First(&token)
+	result := db.Where("hash = ? AND kind = ? AND used_at IS NULL AND expires_at > ?", hashSecret(secret), kind, time.Now()).First(&token)
 	if result.Error != nil {
 		zero.Error(result.Error.Error())
-		return user, result.Error
+		return user, ErrTokenInvalid
 	}
 
 	user, err := GetUser(token.UserID, uuid.Nil)
@@ -44,33 +81,49 @@ func GetTokenUser(token_uuid uuid.UUID) (User, error) {
 	return user, err
 }
 
-// GetDeletedTokenUser checks for any deleted token and returns the user
-func GetDeletedTokenUser(token_uuid uuid.UUID) (User, error) {
+// GetRedeemedTokenUser returns the user of a token of the given kind which has already been redeemed.
+// It allows a confirmation link to be followed more than once.
+func GetRedeemedTokenUser(secret string, kind string) (User, error) {
 	var user User
 	var token Token
 
-	result := db.Unscoped().Where("uuid = ?", token_uuid).First(&token)
+	result := db.Where("hash = ? AND kind = ? AND used_at IS NOT NULL", hashSecret(secret), kind).First(&token)
 	if result.Error != nil {
-		zero.Error(result.Error.Error())
-		return user, result.Error
+		return user, ErrTokenInvalid
 	}
 
-	user, err := GetUser(token.UserID, uuid.Nil)
+	return GetUser(token.UserID, uuid.Nil)
+}
+
+// RedeemToken marks the token as used and returns its user. The check and the update happen in a single statement,
+// so that a token can't be redeemed twice by concurrent requests.
+func RedeemToken(secret string, kind string) (User, error) {
+	token, err := redeemToken(db, secret, kind)
 	if err != nil {
-		zero.Error(err.Error())
-		return user, err
+		return User{}, err
 	}
 
-	return user, err
+	return GetUser(token.UserID, uuid.Nil)
 }
 
-func DeleteToken(token_uuid uuid.UUID) error {
+func redeemToken(tx *gorm.DB, secret string, kind string) (Token, error) {
 	var token Token
-	result := db.Where("uuid = ?", token_uuid).First(&token)
+
+	result := tx.Raw("UPDATE tokens SET used_at = ?, updated_at = ? WHERE hash = ? AND kind = ? AND used_at IS NULL AND expires_at > ? RETURNING *",
+		time.Now(), time.Now(), hashSecret(secret), kind, time.Now()).Scan(&token)
 	if result.Error != nil {
-		return result.Error
+		return token, result.Error
+	}
+
+	if result.RowsAffected == 0 || token.UserID == uuid.Nil {
+		return token, ErrTokenInvalid
 	}
 
-	result = db.Where("uuid = ?", token_uuid).Delete(&token)
-	return result.Error
+	return token, nil
+}
+
+// PurgeExpiredTokens deletes all tokens past their expiry date, whether they have been used or not
+func PurgeExpiredTokens() (int64, error) {
+	result := db.Where("expires_at < ?", time.Now()).Delete(&Token{})
+	return result.RowsAffected, result.Error
 }
@@ -0,0 +1,92 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/commonsyllabi/explorer/api/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenModel(t *testing.T) {
+	teardown := setup(t)
+	defer teardown(t)
+
+	t.Run("Test create token", func(t *testing.T) {
+		secret, token, err := models.CreateToken(userID, models.TokenRecovery)
+		require.Nil(t, err)
+		assert.NotEmpty(t, secret)
+		assert.Equal(t, models.TokenRecovery, token.Kind)
+		assert.NotEqual(t, secret, token.Hash)
+		assert.True(t, token.ExpiresAt.After(token.CreatedAt))
+	})
+
+	t.Run("Test create token of unknown kind", func(t *testing.T) {
+		_, _, err := models.CreateToken(userID, "unknown")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test get token user", func(t *testing.T) {
+		secret, _, err := models.CreateToken(userID, models.TokenRecovery)
+		require.Nil(t, err)
+
+		user, err := models.GetTokenUser(secret, models.TokenRecovery)
+		require.Nil(t, err)
+		assert.Equal(t, userID, user.UUID)
+	})
+
+	t.Run("Test redeem token", func(t *testing.T) {
+		secret, _, err := models.CreateToken(userID, models.TokenRecovery)
+		require.Nil(t, err)
+
+		user, err := models.RedeemToken(secret, models.TokenRecovery)
+		require.Nil(t, err)
+		assert.Equal(t, userID, user.UUID)
+
+		_, err = models.RedeemToken(secret, models.TokenRecovery)
+		assert.ErrorIs(t, err, models.ErrTokenInvalid)
+
+		_, err = models.GetTokenUser(secret, models.TokenRecovery)
+		assert.NotNil(t, err)
+
+		redeemed, err := models.GetRedeemedTokenUser(secret, models.TokenRecovery)
+		require.Nil(t, err)
+		assert.Equal(t, userID, redeemed.UUID)
+	})
+
+	t.Run("Test redeem token of another kind", func(t *testing.T) {
+		secret, _, err := models.CreateToken(userID, models.TokenRecovery)
+		require.Nil(t, err)
+
+		_, err = models.RedeemToken(secret, models.TokenConfirmation)
+		assert.ErrorIs(t, err, models.ErrTokenInvalid)
+
+		_, err = models.GetTokenUser(secret, models.TokenConfirmation)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Test confirm user", func(t *testing.T) {
+		pendingID := uuid.MustParse("e7b74bcd-c864-41ee-b5a7-d3031f76c800")
+		secret, _, err := models.CreateToken(pendingID, models.TokenConfirmation)
+		require.Nil(t, err)
+
+		user, err := models.ConfirmUser(secret)
+		require.Nil(t, err)
+		assert.Equal(t, pendingID, user.UUID)
+		assert.Equal(t, models.UserConfirmed, user.Status)
+
+		_, err = models.ConfirmUser(secret)
+		assert.ErrorIs(t, err, models.ErrTokenInvalid)
+	})
+
+	t.Run("Test redeem unknown token", func(t *testing.T) {
+		_, err := models.RedeemToken("not-a-token", models.TokenConfirmation)
+		assert.ErrorIs(t, err, models.ErrTokenInvalid)
+	})
+
+	t.Run("Test purge expired tokens", func(t *testing.T) {
+		_, err := models.PurgeExpiredTokens()
+		assert.Nil(t, err)
+	})
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount is how many recovery codes are handed out when two-factor authentication is enabled
+const recoveryCodeCount = 10
+
+// recoveryAlphabet leaves out the characters which are easily mistaken for one another
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+var (
+	ErrTOTPReplayed         = errors.New("the authentication code has already been used")
+	ErrRecoveryCodeInvalid  = errors.New("the recovery code is invalid or already used")
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication has not been set up")
+)
+
+// RecoveryCode is a single-use code which replaces the authenticator app, for users who lost it.
+// Like other secrets, only its hash is stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey"`
+	CreatedAt time.Time  `json:"created_at"`
+	UUID      uuid.UUID  `gorm:"uniqueIndex;type:uuid;primaryKey;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
+	UserUUID  uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_uuid"`
+	Hash      string     `gorm:"not null;index" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// SetTOTPSecret starts the enrollment of a user, whose second factor is only enabled once they have proven they can generate codes
+func SetTOTPSecret(user_uuid uuid.UUID, secret string) error {
+	result := db.Model(&User{}).Where("uuid = ? AND two_factor_enabled = ?", user_uuid, false).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// EnableTwoFactor enables the second factor of a user, whose code was valid for the given step. It returns new recovery codes in clear,
+// and revokes the existing sessions since they were opened without the second factor.
+func EnableTwoFactor(user_uuid uuid.UUID, step int64) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("uuid = ? AND totp_secret <> ''", user_uuid).Updates(map[string]interface{}{
+			"two_factor_enabled": true,
+			"totp_last_step":     step,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrTwoFactorNotEnrolled
+		}
+
+		err := tx.Where("user_uuid = ?", user_uuid).Delete(&RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < recoveryCodeCount; i++ {
+			code, err := generateRecoveryCode()
+			if err != nil {
+				return err
+			}
+
+			err = tx.Create(&RecoveryCode{UUID: uuid.New(), UserUUID: user_uuid, Hash: hashSecret(normalizeRecoveryCode(code))}).Error
+			if err != nil {
+				return err
+			}
+			codes = append(codes, code)
+		}
+
+		return revokeSessions(tx, user_uuid)
+	})
+
+	return codes, err
+}
+
+// DisableTwoFactor removes the second factor of a user, along with their recovery codes
+func DisableTwoFactor(user_uuid uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).Where("uuid = ?", user_uuid).Updates(map[string]interface{}{
+			"totp_secret":        "",
+			"totp_last_step":     0,
+			"two_factor_enabled": false,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("user_uuid = ?", user_uuid).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// UseTOTPStep records the step of a valid code as used. Codes of this step or of an earlier one are rejected afterwards.
+func UseTOTPStep(user_uuid uuid.UUID, step int64) error {
+	result := db.Model(&User{}).Where("uuid = ? AND totp_last_step < ?", user_uuid, step).Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrTOTPReplayed
+	}
+	return nil
+}
+
+// RedeemRecoveryCode marks the recovery code of the user as used, in a single statement so that it can't be redeemed twice
+func RedeemRecoveryCode(user_uuid uuid.UUID, code string) error {
+	result := db.Model(&RecoveryCode{}).Where("user_uuid = ? AND hash = ? AND used_at IS NULL", user_uuid, hashSecret(normalizeRecoveryCode(code))).Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrRecoveryCodeInvalid
+	}
+	return nil
+}
+
+// SetTwoFactorRequired lets administrators require a user to set up a second factor before being able to log in again.
+// Requiring it logs the user out everywhere, since their current sessions were not opened with a second factor.
+func SetTwoFactorRequired(user_uuid uuid.UUID, required bool) (User, error) {
+	var user User
+	result := db.Where("uuid = ?", user_uuid).First(&user)
+	if result.Error != nil {
+		return user, result.Error
+	}
+
+	newly_required := required && !user.TwoFactorRequired
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&user).Update("two_factor_required", required).Error
+		if err != nil || !newly_required {
+			return err
+		}
+
+		return revokeSessions(tx, user.UUID)
+	})
+	return user, err
+}
+
+// generateRecoveryCode returns a code such as abcde-23456, easy enough to type from a printout
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	var code strings.Builder
+	for i, c := range b {
+		if i == 5 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(recoveryAlphabet[int(c)%len(recoveryAlphabet)])
+	}
+	return code.String(), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
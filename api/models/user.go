@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strings"
@@ -16,9 +17,35 @@ import (
 const (
 	UserPending   string = "pending"
 	UserConfirmed string = "confirmed"
+	UserSuspended string = "suspended"
 	UserDeleted   string = "deleted"
 )
 
+const (
+	RoleUser      string = "user"
+	RoleModerator string = "moderator"
+	RoleAdmin     string = "admin"
+)
+
+var ErrInvalidAccount = errors.New("invalid account update")
+
+// ROLES ranks each role, a role being granted all the permissions of the roles below it
+var ROLES = map[string]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+// HasRole checks whether the role is at least as high as the required one
+func HasRole(role string, required string) bool {
+	r, found := ROLES[role]
+	if !found {
+		return false
+	}
+
+	return r >= ROLES[required]
+}
+
 type User struct {
 	ID        uint           `gorm:"primaryKey"`
 	CreatedAt time.Time      `json:"created_at"`
@@ -26,20 +53,33 @@ type User struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 	UUID      uuid.UUID      `gorm:"uniqueIndex;type:uuid;primaryKey;default:uuid_generate_v4()" json:"uuid" yaml:"uuid"`
 	Status    string         `gorm:"default:pending" json:"status"`
+	Role      string         `gorm:"not null;default:user" json:"role"`
 
 	Bio       string         `json:"bio" form:"bio"`
 	Education pq.StringArray `gorm:"type:text[]" json:"education" form:"education[]"`
 	Email     string         `gorm:"unique;not null" json:"email" form:"email"`
 	Name      string         `gorm:"default:Anonymous User;not null" json:"name" form:"name"`
 	Slug      string         `gorm:"" json:"slug"`
-	Password  []byte         `gorm:"not null" json:"password"`
+	Password  []byte         `gorm:"not null" json:"-"`
 	URLs      pq.StringArray `gorm:"type:text[]" json:"urls" form:"urls[]"`
 
+	SessionVersion int `gorm:"not null;default:0" json:"-"`
+
+	TOTPSecret        string `json:"-"`
+	TOTPLastStep      int64  `gorm:"not null;default:0" json:"-"`
+	TwoFactorEnabled  bool   `gorm:"not null;default:false" json:"two_factor_enabled"`
+	TwoFactorRequired bool   `gorm:"not null;default:false" json:"two_factor_required"`
+
 	Institutions []Institution `gorm:"many2many:inst_users;" json:"institutions"`
 
 	Collections []Collection `gorm:"foreignKey:UserUUID;references:UUID" json:"collections"`
 	Syllabi     []Syllabus   `gorm:"foreignKey:UserUUID;references:UUID" json:"syllabi"`
 
+	Identities    []UserIdentity `gorm:"foreignKey:UserUUID;references:UUID" json:"-"`
+	RecoveryCodes []RecoveryCode `gorm:"foreignKey:UserUUID;references:UUID" json:"-"`
+	APITokens     []APIToken     `gorm:"foreignKey:UserUUID;references:UUID" json:"-"`
+	SavedSearches []SavedSearch  `gorm:"foreignKey:UserUUID;references:UUID" json:"-"`
+
 	IsNewsletterSubscribed bool `gorm:"default:false" json:"is_newsletter_subscribed" form:"is_newsletter_subscribed"`
 }
 
@@ -163,27 +203,120 @@ func GetUserBySlug(slug string, user_uuid uuid.UUID) (User, error) {
 	return user, err
 }
 
-func GetAllUsers() ([]User, error) {
+// GetAllUsers returns a page of all accounts
+func GetAllUsers(page Page) ([]User, PageMeta, error) {
 	users := make([]User, 0)
-	result := db.Find(&users)
-	return users, result.Error
+	if page.Sort == "" {
+		page.Sort = SortCreated
+	}
+
+	query, meta, err := paginate(db.Model(&User{}), "users", page, USER_SORTS, nil)
+	if err != nil {
+		return users, meta, err
+	}
+
+	result := query.Find(&users)
+	if result.Error != nil {
+		return users, meta, result.Error
+	}
+
+	fetched := len(users)
+	if page.Size > 0 && fetched > page.Size {
+		users = users[:page.Size]
+		last := users[len(users)-1]
+		meta.nextCursor(fetched, sortValue(page.Sort, last.CreatedAt, last.UpdatedAt, last.Name, 0), last.ID)
+	}
+
+	return users, meta, nil
 }
 
-func UpdateUser(uuid uuid.UUID, user_uuid uuid.UUID, user *User) (User, error) {
+func UpdateUser(uuid uuid.UUID, user *User) (User, error) {
 	var existing User
-	result := db.Where("uuid = ? AND uuid = ?", uuid, user_uuid).First(&existing)
+	result := db.Where("uuid = ?", uuid).First(&existing)
 	if result.Error != nil {
 		return *user, result.Error
 	}
 
-	result = db.Model(&existing).Where("uuid = ?", uuid).Updates(user)
+	// -- the role, the status, the sessions and the second factor can only be changed through their own functions
+	result = db.Model(&existing).Where("uuid = ?", uuid).Omit("id", "uuid", "created_at", "deleted_at", "status", "role", "password", "session_version", "totp_secret", "totp_last_step", "two_factor_enabled", "two_factor_required").Updates(user)
 
 	return existing, result.Error
 }
 
-func AddInstitutionToUser(uuid uuid.UUID, user_uuid uuid.UUID, inst *Institution) (User, error) {
+// UpdateUserAccount sets the role and status of a user, as an administrator. Any change revokes the user's sessions,
+// so that tokens carrying the previous role can't be used anymore.
+func UpdateUserAccount(uuid uuid.UUID, role string, status string) (User, error) {
+	var existing User
+	result := db.Where("uuid = ?", uuid).First(&existing)
+	if result.Error != nil {
+		return existing, result.Error
+	}
+
+	if _, found := ROLES[role]; role != "" && !found {
+		return existing, fmt.Errorf("%w: unknown role %s", ErrInvalidAccount, role)
+	}
+
+	if status != "" && status != UserPending && status != UserConfirmed && status != UserSuspended {
+		return existing, fmt.Errorf("%w: unknown status %s", ErrInvalidAccount, status)
+	}
+
+	result = db.Model(&existing).Updates(User{Role: role, Status: status})
+	if result.Error != nil {
+		return existing, result.Error
+	}
+
+	err := RevokeSessions(uuid)
+	return existing, err
+}
+
+// ConfirmUser redeems a confirmation token and marks the pending account of its user as confirmed. Either both happen or
+// neither does, so that a token is never used up on an account left pending. Suspended accounts stay suspended.
+func ConfirmUser(secret string) (User, error) {
+	var token Token
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		token, err = redeemToken(tx, secret, TokenConfirmation)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&User{}).Where("uuid = ? AND status = ?", token.UserID, UserPending).Update("status", UserConfirmed).Error
+	})
+	if err != nil {
+		return User{}, err
+	}
+
+	return GetUser(token.UserID, uuid.Nil)
+}
+
+// SetUserPassword redeems a recovery token and sets the hashed password of its user. It revokes all the sessions of the
+// user, and either every step succeeds or nothing is changed.
+func SetUserPassword(secret string, hashed []byte) (User, error) {
+	var token Token
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		token, err = redeemToken(tx, secret, TokenRecovery)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&User{}).Where("uuid = ?", token.UserID).Update("password", hashed).Error
+		if err != nil {
+			return err
+		}
+
+		return revokeSessions(tx, token.UserID)
+	})
+	if err != nil {
+		return User{}, err
+	}
+
+	return GetUser(token.UserID, uuid.Nil)
+}
+
+func AddInstitutionToUser(uuid uuid.UUID, inst *Institution) (User, error) {
 	var user User
-	result := db.Where("uuid = ? AND uuid = ?", uuid, user_uuid).Preload("Institutions").First(&user)
+	result := db.Where("uuid = ?", uuid).Preload("Institutions").First(&user)
 	if result.Error != nil {
 		return user, result.Error
 	}
@@ -193,18 +326,29 @@ func AddInstitutionToUser(uuid uuid.UUID, user_uuid uuid.UUID, inst *Institution
 		return user, err
 	}
 
-	updated, err := GetUser(user_uuid, user_uuid)
+	updated, err := GetUser(uuid, uuid)
 	return updated, err
 }
 
 func EditInstitutionToUser(uuid uuid.UUID, inst_uuid uuid.UUID, inst *Institution) (User, error) {
-	var existing Institution
-	result := db.Where("uuid = ?", inst_uuid).First(&existing)
+	var user User
+	result := db.Where("uuid = ?", uuid).First(&user)
 	if result.Error != nil {
-		return User{}, result.Error
+		return user, result.Error
 	}
 
-	err := db.Model(&existing).Updates(inst).Error
+	// -- only institutions of this user can be edited through them
+	var existing Institution
+	err := db.Model(&user).Where("uuid = ?", inst_uuid).Association("Institutions").Find(&existing)
+	if err != nil {
+		return User{}, err
+	}
+
+	if existing.ID == 0 {
+		return User{}, gorm.ErrRecordNotFound
+	}
+
+	err = db.Model(&existing).Updates(inst).Error
 	if err != nil {
 		return User{}, err
 	}
@@ -213,9 +357,9 @@ func EditInstitutionToUser(uuid uuid.UUID, inst_uuid uuid.UUID, inst *Institutio
 	return updated, err
 }
 
-func RemoveInstitutionFromUser(uuid uuid.UUID, inst_uuid uuid.UUID, user_uuid uuid.UUID) (User, error) {
+func RemoveInstitutionFromUser(uuid uuid.UUID, inst_uuid uuid.UUID) (User, error) {
 	var user User
-	result := db.Where("uuid = ? AND uuid = ?", uuid, user_uuid).First(&user)
+	result := db.Where("uuid = ?", uuid).First(&user)
 	if result.Error != nil {
 		return user, result.Error
 	}
@@ -231,16 +375,22 @@ func RemoveInstitutionFromUser(uuid uuid.UUID, inst_uuid uuid.UUID, user_uuid uu
 		return user, err
 	}
 
-	updated, err := GetUser(user_uuid, user_uuid)
+	updated, err := GetUser(uuid, uuid)
 	return updated, err
 }
 
-func DeleteUser(uuid uuid.UUID, user_uuid uuid.UUID) (User, error) {
+func DeleteUser(uuid uuid.UUID) (User, error) {
 	var user User
-	result := db.Where("uuid = ? AND uuid = ?", uuid, user_uuid).First(&user)
+	result := db.Where("uuid = ?", uuid).First(&user)
 	if result.Error != nil {
 		return user, result.Error
 	}
+
+	err := RevokeSessions(uuid)
+	if err != nil {
+		return user, err
+	}
+
 	result = db.Select(clause.Associations).Where("uuid = ?", uuid).Delete(&user)
 
 	return user, result.Error
@@ -19,7 +19,7 @@ func TestUserModel(t *testing.T) {
 	defer teardown(t)
 
 	t.Run("Test get all users", func(t *testing.T) {
-		users, err := models.GetAllUsers()
+		users, _, err := models.GetAllUsers(models.Page{})
 		require.Nil(t, err)
 		assert.Equal(t, userCount, len(users))
 	})
@@ -85,7 +85,7 @@ func TestUserModel(t *testing.T) {
 		require.Nil(t, err)
 
 		user.Email = "test@updated.com"
-		updated, err := models.UpdateUser(userID, userID, &user)
+		updated, err := models.UpdateUser(userID, &user)
 
 		require.Nil(t, err)
 		require.False(t, updated.CreatedAt.IsZero())
@@ -93,25 +93,91 @@ func TestUserModel(t *testing.T) {
 		assert.Equal(t, user.Name, userName)
 	})
 
-	t.Run("Test update user non-owner", func(t *testing.T) {
-		user, err := models.GetUser(userID, userUnknownID)
-		require.Nil(t, err)
-
-		user.Email = "test@updated.com"
-		_, err = models.UpdateUser(userID, userUnknownID, &user)
-
-		require.NotNil(t, err)
-	})
-
 	t.Run("Test update non-existing user", func(t *testing.T) {
 		user := models.User{
 			Email: "test@user-non-existing.updated",
 		}
-		updated, err := models.UpdateUser(userUnknownID, userID, &user)
+		updated, err := models.UpdateUser(userUnknownID, &user)
 		assert.NotNil(t, err)
 		assert.Equal(t, uuid.Nil, updated.UUID)
 	})
 
+	t.Run("Test update user cannot change role", func(t *testing.T) {
+		user := models.User{Role: models.RoleAdmin}
+		_, err := models.UpdateUser(userID, &user)
+		require.Nil(t, err)
+
+		updated, err := models.GetUser(userID, userID)
+		require.Nil(t, err)
+		assert.Equal(t, models.RoleUser, updated.Role)
+	})
+
+	t.Run("Test update user cannot change status", func(t *testing.T) {
+		_, err := models.UpdateUserAccount(userID, "", models.UserSuspended)
+		require.Nil(t, err)
+
+		_, err = models.UpdateUser(userID, &models.User{Status: models.UserConfirmed})
+		require.Nil(t, err)
+
+		updated, err := models.GetUser(userID, userID)
+		require.Nil(t, err)
+		assert.Equal(t, models.UserSuspended, updated.Status)
+
+		_, err = models.UpdateUserAccount(userID, "", models.UserConfirmed)
+		require.Nil(t, err)
+	})
+
+	t.Run("Test update user account", func(t *testing.T) {
+		version, err := models.GetSessionVersion(userID)
+		require.Nil(t, err)
+
+		updated, err := models.UpdateUserAccount(userID, models.RoleModerator, "")
+		require.Nil(t, err)
+		assert.Equal(t, models.RoleModerator, updated.Role)
+
+		next, err := models.GetSessionVersion(userID)
+		require.Nil(t, err)
+		assert.Greater(t, next, version)
+
+		_, err = models.UpdateUserAccount(userID, models.RoleUser, "")
+		require.Nil(t, err)
+	})
+
+	t.Run("Test requiring two-factor authentication revokes sessions", func(t *testing.T) {
+		version, err := models.GetSessionVersion(userID)
+		require.Nil(t, err)
+
+		updated, err := models.SetTwoFactorRequired(userID, true)
+		require.Nil(t, err)
+		assert.True(t, updated.TwoFactorRequired)
+
+		next, err := models.GetSessionVersion(userID)
+		require.Nil(t, err)
+		assert.Greater(t, next, version)
+
+		_, err = models.SetTwoFactorRequired(userID, false)
+		require.Nil(t, err)
+
+		last, err := models.GetSessionVersion(userID)
+		require.Nil(t, err)
+		assert.Equal(t, next, last)
+	})
+
+	t.Run("Test update user account with invalid role", func(t *testing.T) {
+		_, err := models.UpdateUserAccount(userID, "superuser", "")
+		assert.ErrorIs(t, err, models.ErrInvalidAccount)
+
+		_, err = models.UpdateUserAccount(userID, "", "banished")
+		assert.ErrorIs(t, err, models.ErrInvalidAccount)
+	})
+
+	t.Run("Test role hierarchy", func(t *testing.T) {
+		assert.True(t, models.HasRole(models.RoleAdmin, models.RoleModerator))
+		assert.True(t, models.HasRole(models.RoleModerator, models.RoleModerator))
+		assert.False(t, models.HasRole(models.RoleUser, models.RoleModerator))
+		assert.False(t, models.HasRole("", models.RoleUser))
+	})
+
 	var newInstID uuid.UUID
 	t.Run("Test add institution to user", func(t *testing.T) {
 		inst := models.Institution{
@@ -120,7 +186,7 @@ func TestUserModel(t *testing.T) {
 			Position: "lector",
 		}
 
-		updated, err := models.AddInstitutionToUser(userID, userID, &inst)
+		updated, err := models.AddInstitutionToUser(userID, &inst)
 		require.Nil(t, err)
 		assert.Equal(t, userID, updated.UUID)
 		assert.Equal(t, 2, len(updated.Institutions))
@@ -128,19 +194,19 @@ func TestUserModel(t *testing.T) {
 	})
 
 	t.Run("Test remove institution from user", func(t *testing.T) {
-		updated, err := models.RemoveInstitutionFromUser(userID, newInstID, userID)
+		updated, err := models.RemoveInstitutionFromUser(userID, newInstID)
 		require.Nil(t, err)
 		assert.Equal(t, userID, updated.UUID)
 	})
 
 	t.Run("Test delete user", func(t *testing.T) {
-		user, err := models.DeleteUser(userDeleteID, userDeleteID)
+		user, err := models.DeleteUser(userDeleteID)
 		assert.Nil(t, err)
 		assert.Equal(t, user.Name, userDeleteName)
 	})
 
 	t.Run("Test delete wrong user", func(t *testing.T) {
-		user, err := models.DeleteUser(userUnknownID, userID)
+		user, err := models.DeleteUser(userUnknownID)
 		assert.Zero(t, user)
 		assert.NotNil(t, err)
 	})
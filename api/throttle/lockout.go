@@ -0,0 +1,57 @@
+package throttle
+
+import (
+	"math"
+	"time"
+)
+
+// Lockout slows down guessing the credentials of an account. Once DelayAfter attempts have failed within the window,
+// each new attempt has to wait twice as long as the previous one, up to MaxDelay. Once LockAfter attempts have failed,
+// the account is locked until the window is over.
+type Lockout struct {
+	Store      Store
+	Window     time.Duration
+	DelayAfter int
+	LockAfter  int
+	MaxDelay   time.Duration
+}
+
+// Check returns whether a new attempt can be made on the key, and otherwise when it can be
+func (l Lockout) Check(key string) (bool, time.Time, error) {
+	counter, err := l.Store.Peek(key)
+	if err != nil {
+		return true, time.Time{}, err
+	}
+
+	if counter.Count >= l.LockAfter {
+		return false, counter.ResetAt, nil
+	}
+
+	if counter.Count >= l.DelayAfter {
+		next := counter.Last.Add(l.delay(counter.Count))
+		if time.Now().Before(next) {
+			return false, next, nil
+		}
+	}
+
+	return true, time.Time{}, nil
+}
+
+// Fail counts a failed attempt on the key
+func (l Lockout) Fail(key string) error {
+	_, err := l.Store.Hit(key, l.Window)
+	return err
+}
+
+// Succeed forgets the failed attempts on the key
+func (l Lockout) Succeed(key string) error {
+	return l.Store.Reset(key)
+}
+
+func (l Lockout) delay(failures int) time.Duration {
+	d := time.Duration(math.Pow(2, float64(failures-l.DelayAfter))) * time.Second
+	if d > l.MaxDelay {
+		return l.MaxDelay
+	}
+	return d
+}
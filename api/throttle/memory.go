@@ -0,0 +1,69 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many hits the memory store takes between two sweeps of its expired counters
+const sweepEvery = 1000
+
+// MemoryStore keeps counters in the memory of the process
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]Counter
+	hits     int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]Counter)}
+}
+
+func (s *MemoryStore) Hit(key string, window time.Duration) (Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.hits++
+	if s.hits%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	counter, found := s.counters[key]
+	if !found || !now.Before(counter.ResetAt) {
+		counter = Counter{ResetAt: now.Add(window)}
+	}
+
+	counter.Count++
+	counter.Last = now
+	s.counters[key] = counter
+
+	return counter, nil
+}
+
+func (s *MemoryStore) Peek(key string) (Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, found := s.counters[key]
+	if !found || !time.Now().Before(counter.ResetAt) {
+		return Counter{}, nil
+	}
+	return counter, nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.counters, key)
+	return nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, counter := range s.counters {
+		if !now.Before(counter.ResetAt) {
+			delete(s.counters, key)
+		}
+	}
+}
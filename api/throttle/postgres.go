@@ -0,0 +1,24 @@
+package throttle
+
+import (
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/models"
+)
+
+// PostgresStore keeps counters in the database, so that they are shared by every instance of the API
+type PostgresStore struct{}
+
+func (PostgresStore) Hit(key string, window time.Duration) (Counter, error) {
+	limit, err := models.HitRateLimit(key, window)
+	return Counter{Count: limit.Count, Last: limit.LastAt, ResetAt: limit.ResetAt}, err
+}
+
+func (PostgresStore) Peek(key string) (Counter, error) {
+	limit, err := models.GetRateLimit(key)
+	return Counter{Count: limit.Count, Last: limit.LastAt, ResetAt: limit.ResetAt}, err
+}
+
+func (PostgresStore) Reset(key string) error {
+	return models.ResetRateLimit(key)
+}
@@ -0,0 +1,100 @@
+package throttle
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	zero "github.com/commonsyllabi/explorer/api/logger"
+	"github.com/labstack/echo/v4"
+)
+
+// Counter is the number of hits on a key within its current window, along with the time of the last one
+type Counter struct {
+	Count   int
+	Last    time.Time
+	ResetAt time.Time
+}
+
+// Store keeps counters across requests. The memory store only counts the requests of a single instance of the API,
+// while the Postgres store shares its counters between all of them.
+type Store interface {
+	// Hit counts a new hit on the key, starting a new window if the previous one is over
+	Hit(key string, window time.Duration) (Counter, error)
+	// Peek returns the counter of the key without counting a hit, and an empty counter if its window is over
+	Peek(key string) (Counter, error)
+	// Reset forgets all hits on the key
+	Reset(key string) error
+}
+
+// Rule allows a number of requests per window
+type Rule struct {
+	Requests int
+	Window   time.Duration
+}
+
+// NewStore returns the store of the given kind, either memory or postgres
+func NewStore(kind string) (Store, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "postgres":
+		return PostgresStore{}, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store: %s", kind)
+	}
+}
+
+// IPExtractor tells clients by the address they connect from. Behind proxies, the address is taken from the X-Forwarded-For header,
+// trusting only the hops within the given ranges, so that clients can't pick another IP to get around the limits.
+func IPExtractor(trusted_proxies []string) (echo.IPExtractor, error) {
+	if len(trusted_proxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, p := range trusted_proxies {
+		_, ip_range, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("malformed trusted proxy range %q: %w", p, err)
+		}
+		options = append(options, echo.TrustIPRange(ip_range))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// Limit rejects the requests of a client once it has gone over the rule, until the window is over.
+// Clients are told by their IP, as given by the IPExtractor of the router. The limit fails open: requests go through when the store can't be reached, since locking everyone out is worse.
+func Limit(store Store, name string, rule Rule) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			counter, err := store.Hit(fmt.Sprintf("%s:ip:%s", name, c.RealIP()), rule.Window)
+			if err != nil {
+				zero.Errorf("error counting requests for %s: %v", name, err)
+				return next(c)
+			}
+
+			if counter.Count > rule.Requests {
+				zero.Warnf("rate limit of %s reached by %s", name, c.RealIP())
+				return TooManyRequests(c, counter.ResetAt)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// TooManyRequests answers with a 429 status, and the number of seconds to wait before trying again in the Retry-After header
+func TooManyRequests(c echo.Context, until time.Time) error {
+	seconds := int(math.Ceil(time.Until(until).Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return c.String(http.StatusTooManyRequests, "Too many attempts, please try again later.")
+}
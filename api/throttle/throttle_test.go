@@ -0,0 +1,192 @@
+package throttle_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/commonsyllabi/explorer/api/throttle"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	t.Run("Test hits are counted within the window", func(t *testing.T) {
+		store := throttle.NewMemoryStore()
+
+		for i := 1; i <= 3; i++ {
+			counter, err := store.Hit("key", time.Minute)
+			require.Nil(t, err)
+			assert.Equal(t, i, counter.Count)
+		}
+
+		counter, err := store.Peek("key")
+		require.Nil(t, err)
+		assert.Equal(t, 3, counter.Count)
+	})
+
+	t.Run("Test window expires", func(t *testing.T) {
+		store := throttle.NewMemoryStore()
+
+		store.Hit("key", 10*time.Millisecond)
+		store.Hit("key", 10*time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+
+		counter, err := store.Peek("key")
+		require.Nil(t, err)
+		assert.Equal(t, 0, counter.Count)
+
+		counter, err = store.Hit("key", 10*time.Millisecond)
+		require.Nil(t, err)
+		assert.Equal(t, 1, counter.Count)
+	})
+
+	t.Run("Test reset", func(t *testing.T) {
+		store := throttle.NewMemoryStore()
+
+		store.Hit("key", time.Minute)
+		err := store.Reset("key")
+		require.Nil(t, err)
+
+		counter, _ := store.Peek("key")
+		assert.Equal(t, 0, counter.Count)
+	})
+
+	t.Run("Test unknown store", func(t *testing.T) {
+		_, err := throttle.NewStore("redis")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestLimit(t *testing.T) {
+	e := echo.New()
+	e.POST("/login", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}, throttle.Limit(throttle.NewMemoryStore(), "login", throttle.Rule{Requests: 2, Window: time.Minute}))
+
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = ip + ":1234"
+		res := httptest.NewRecorder()
+		e.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("Test requests under the limit", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("192.0.2.10").Code)
+		assert.Equal(t, http.StatusOK, request("192.0.2.10").Code)
+	})
+
+	t.Run("Test requests over the limit", func(t *testing.T) {
+		res := request("192.0.2.10")
+		assert.Equal(t, http.StatusTooManyRequests, res.Code)
+		assert.Equal(t, "60", res.Header().Get("Retry-After"))
+	})
+
+	t.Run("Test other clients are not limited", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("192.0.2.11").Code)
+	})
+}
+
+func TestIPExtractor(t *testing.T) {
+	limited := func(extractor echo.IPExtractor) func(ip string, forwarded string) int {
+		e := echo.New()
+		e.IPExtractor = extractor
+		e.POST("/login", func(c echo.Context) error {
+			return c.String(http.StatusOK, "ok")
+		}, throttle.Limit(throttle.NewMemoryStore(), "login", throttle.Rule{Requests: 1, Window: time.Minute}))
+
+		return func(ip string, forwarded string) int {
+			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			req.RemoteAddr = ip + ":1234"
+			req.Header.Set(echo.HeaderXForwardedFor, forwarded)
+			res := httptest.NewRecorder()
+			e.ServeHTTP(res, req)
+			return res.Code
+		}
+	}
+
+	t.Run("Test spoofed forwarded addresses do not reset the counter", func(t *testing.T) {
+		extractor, err := throttle.IPExtractor(nil)
+		require.Nil(t, err)
+		request := limited(extractor)
+
+		assert.Equal(t, http.StatusOK, request("192.0.2.10", "203.0.113.1"))
+		assert.Equal(t, http.StatusTooManyRequests, request("192.0.2.10", "203.0.113.2"))
+	})
+
+	t.Run("Test forwarded addresses from untrusted proxies are ignored", func(t *testing.T) {
+		extractor, err := throttle.IPExtractor([]string{"10.0.0.0/8"})
+		require.Nil(t, err)
+		request := limited(extractor)
+
+		assert.Equal(t, http.StatusOK, request("192.0.2.10", "203.0.113.1"))
+		assert.Equal(t, http.StatusTooManyRequests, request("192.0.2.10", "203.0.113.2"))
+	})
+
+	t.Run("Test forwarded addresses from trusted proxies are used", func(t *testing.T) {
+		extractor, err := throttle.IPExtractor([]string{"10.0.0.0/8"})
+		require.Nil(t, err)
+		request := limited(extractor)
+
+		assert.Equal(t, http.StatusOK, request("10.0.0.2", "203.0.113.1"))
+		assert.Equal(t, http.StatusOK, request("10.0.0.2", "203.0.113.2"))
+		assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.2", "203.0.113.2"))
+	})
+
+	t.Run("Test malformed proxy ranges", func(t *testing.T) {
+		_, err := throttle.IPExtractor([]string{"10.0.0.0"})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestLockout(t *testing.T) {
+	lockout := throttle.Lockout{
+		Store:      throttle.NewMemoryStore(),
+		Window:     time.Minute,
+		DelayAfter: 2,
+		LockAfter:  4,
+		MaxDelay:   10 * time.Second,
+	}
+
+	t.Run("Test attempts before the delay", func(t *testing.T) {
+		lockout.Fail("account")
+
+		ok, _, err := lockout.Check("account")
+		require.Nil(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("Test progressive delay", func(t *testing.T) {
+		lockout.Fail("account")
+
+		ok, until, err := lockout.Check("account")
+		require.Nil(t, err)
+		assert.False(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Second), until, 100*time.Millisecond)
+
+		lockout.Fail("account")
+		ok, until, _ = lockout.Check("account")
+		assert.False(t, ok)
+		assert.WithinDuration(t, time.Now().Add(2*time.Second), until, 100*time.Millisecond)
+	})
+
+	t.Run("Test lock until the window is over", func(t *testing.T) {
+		lockout.Fail("account")
+
+		ok, until, err := lockout.Check("account")
+		require.Nil(t, err)
+		assert.False(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), until, time.Second)
+	})
+
+	t.Run("Test success resets the failures", func(t *testing.T) {
+		err := lockout.Succeed("account")
+		require.Nil(t, err)
+
+		ok, _, _ := lockout.Check("account")
+		assert.True(t, ok)
+	})
+}
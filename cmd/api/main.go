@@ -25,7 +25,9 @@ func main() {
 	}
 
 	var conf config.Config
-	conf.DefaultConf()
+	if err := conf.DefaultConf(); err != nil {
+		zero.Log.Fatal().Msgf("error loading the config: %v", err)
+	}
 
 	url := os.Getenv("DATABASE_URL")
 	if url == "" {
@@ -59,6 +59,47 @@ func (c DeletionPayload) Data() interface{} {
 	return c
 }
 
+// DigestPayload lists the syllabi newly listed which match a saved search. More is the number of those which did not fit in the digest.
+type DigestPayload struct {
+	Name        string
+	Host        string
+	Search      string
+	Syllabi     []DigestSyllabus
+	More        int
+	Unsubscribe string
+}
+
+type DigestSyllabus struct {
+	UUID        string
+	Title       string
+	Description string
+}
+
+func (c DigestPayload) Check() error {
+	var err error
+	if c.Name == "" || c.Host == "" || c.Search == "" || c.Unsubscribe == "" || len(c.Syllabi) == 0 {
+		err = fmt.Errorf("the payload should not be empty")
+	}
+	return err
+}
+
+func (c DigestPayload) Data() interface{} {
+	return c
+}
+
+// Headers lets mail clients offer to unsubscribe from the digest in one click
+func (c DigestPayload) Headers() map[string]string {
+	return map[string]string{
+		"List-Unsubscribe":      fmt.Sprintf("<%s>", c.Unsubscribe),
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
+
+// Headers is implemented by the payloads which need extra headers on their message
+type Headers interface {
+	Headers() map[string]string
+}
+
 func loadTemplate(_name string, _data interface{}) (string, error) {
 	p := filepath.Join(Basepath, "../api/templates", fmt.Sprintf("%s.tmpl", _name))
 	t, err := template.ParseFiles(p)
@@ -96,6 +137,12 @@ func SendMail(_dest string, _subject string, _template string, _data Payload) er
 	}
 	message.SetHtml(body)
 
+	if h, ok := _data.(Headers); ok {
+		for k, v := range h.Headers() {
+			message.AddHeader(k, v)
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 
@@ -41,4 +41,30 @@ func TestMailer(t *testing.T) {
 
 		assert.NotNil(t, err)
 	})
+
+	t.Run("Testing digest send", func(t *testing.T) {
+		body := DigestPayload{
+			Name:        "Pierre",
+			Host:        "localhost",
+			Search:      "Media theory",
+			Syllabi:     []DigestSyllabus{{UUID: "46de6a2b-aacb-4c24-b1e1-3495821f846a", Title: "Ungewohnt", Description: "A course."}},
+			Unsubscribe: "localhost/searches/unsubscribe?token=ttttt",
+		}
+
+		err := SendMail("pierre.depaz@gmail.com", "test subject", "search_digest", body)
+		assert.Nil(t, err)
+
+		html, err := loadTemplate("search_digest", body.Data())
+		assert.Nil(t, err)
+		assert.Contains(t, html, "localhost/syllabus/46de6a2b-aacb-4c24-b1e1-3495821f846a")
+		assert.Contains(t, html, "localhost/searches/unsubscribe?token=ttttt")
+		assert.Equal(t, "<localhost/searches/unsubscribe?token=ttttt>", body.Headers()["List-Unsubscribe"])
+	})
+
+	t.Run("Testing empty digest", func(t *testing.T) {
+		body := DigestPayload{Name: "Pierre", Host: "localhost", Search: "Media theory", Unsubscribe: "localhost"}
+
+		err := SendMail("pierre.depaz@gmail.com", "test subject", "search_digest", body)
+		assert.NotNil(t, err)
+	})
 }